@@ -8,14 +8,17 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"auth/internal/auth"
+	"auth/internal/config"
 	"auth/internal/server"
 )
 
@@ -44,20 +47,43 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 }
 
 /*
+Charge et valide la configuration (voir internal/config) ; le service ne démarre pas si elle est invalide.
 Initialise le module d’authentification,
 Crée une instance du serveur HTTP configuré
 Lance une goroutine qui surveille les signaux d’arrêt du système.
+Avec -migrate, gère seulement le schéma (voir migrate.go) puis s’arrête.
+Avec -print-config, affiche la configuration effective (secrets masqués) puis s’arrête.
 */
 
 func main() {
-	auth.NewAuth()
-	server := server.NewServer()
+	migrate := flag.String("migrate", "", "schema command: up, down, status or baseline")
+	configFile := flag.String("config", "", "YAML or TOML configuration file (default: CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted, then exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	if *printConfig {
+		cfg.Dump(os.Stdout)
+		return
+	}
+
+	if *migrate != "" {
+		runMigrateCommand(cfg, *migrate)
+		return
+	}
+
+	auth.NewAuth(cfg)
+	server := server.NewServer(cfg)
 
 	done := make(chan bool, 1)
 
 	go gracefulShutdown(server, done)
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		panic(fmt.Sprintf("http server error: %s", err))
 	}
@@ -0,0 +1,72 @@
+/*
+Commande de gestion du schéma, sans démarrer le serveur :
+
+	./main -migrate up        applique les migrations en attente (fait aussi au démarrage)
+	./main -migrate down      annule la dernière migration appliquée
+	./main -migrate status    affiche l’état de chaque migration
+	./main -migrate baseline  marque le schéma initial (0001) comme appliqué, pour une base créée avec l’ancien SQL_DB.sql ;
+	                          refusé si une de ses tables manque ou si la base a déjà été mise à jour à la main
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"auth/internal/config"
+	"auth/internal/database"
+	"auth/internal/database/migrations"
+)
+
+func runMigrateCommand(cfg *config.Config, command string) {
+	db := database.Connect(cfg.Database)
+	defer db.Close()
+
+	migrator, err := migrations.New(db.DB, db.Dialect)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		fmt.Printf("%d migration(s) applied\n", count)
+
+	case "down":
+		migration, err := migrator.Down(ctx)
+		if err != nil {
+			log.Fatal("Rollback failed: ", err)
+		}
+		fmt.Printf("Rolled back %d_%s\n", migration.Version, migration.Name)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal("Failed to read migration status: ", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Dirty:
+				state = "DIRTY"
+			case status.Applied:
+				state = "applied " + status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, state)
+		}
+
+	case "baseline":
+		if err := migrator.Baseline(ctx, migrations.InitialVersion); err != nil {
+			log.Fatal("Baseline failed: ", err)
+		}
+		fmt.Println("Existing schema marked as migration", migrations.InitialVersion)
+
+	default:
+		log.Fatalf("Unknown -migrate command %q (expected up, down, status or baseline)", command)
+	}
+}
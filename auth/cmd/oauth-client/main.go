@@ -0,0 +1,81 @@
+/*
+Enregistre un client OpenID Connect dans la table oauth_clients.
+
+	go run ./cmd/oauth-client -name "My App" -redirect-uri https://app.example.com/callback
+	go run ./cmd/oauth-client -name "My SPA" -redirect-uri http://localhost:5173/callback -public
+
+Plusieurs redirect URIs peuvent être séparées par des virgules.
+Le secret (clients confidentiels) n’est affiché qu’une seule fois : seule son empreinte est stockée.
+*/
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"auth/internal/auth"
+	"auth/internal/config"
+	"auth/internal/database"
+)
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func main() {
+	name := flag.String("name", "", "application name shown on the consent screen")
+	redirectURIs := flag.String("redirect-uri", "", "allowed redirect URIs, comma separated")
+	scopes := flag.String("scopes", strings.Join(auth.SupportedScopes, " "), "scopes the client may request")
+	public := flag.Bool("public", false, "public client (no secret, PKCE only), e.g. a SPA or mobile app")
+	configFile := flag.String("config", "", "YAML or TOML configuration file (default: CONFIG_FILE)")
+	flag.Parse()
+
+	if *name == "" || *redirectURIs == "" {
+		flag.Usage()
+		log.Fatal("-name and -redirect-uri are required")
+	}
+
+	client := database.OAuthClient{
+		ID:     randomHex(16),
+		Name:   *name,
+		Scopes: strings.Join(auth.ParseScopes(*scopes), " "),
+	}
+	for _, uri := range strings.Split(*redirectURIs, ",") {
+		uri = strings.TrimSpace(uri)
+		if u, err := url.Parse(uri); err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+			log.Fatalf("invalid redirect URI %q", uri)
+		}
+		client.RedirectURIs = append(client.RedirectURIs, uri)
+	}
+
+	var secret string
+	if !*public {
+		secret = randomHex(32)
+		client.SecretHash = sql.NullString{String: database.HashToken(secret), Valid: true}
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	db := database.New(cfg)
+	defer db.Close()
+
+	if err := db.CreateOAuthClient(client); err != nil {
+		log.Fatalf("failed to register client: %v", err)
+	}
+
+	fmt.Printf("client_id:     %s\n", client.ID)
+	if secret != "" {
+		fmt.Printf("client_secret: %s\n", secret)
+	}
+}
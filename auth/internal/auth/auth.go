@@ -1,71 +1,190 @@
 /*
-Ce fichier gère la configuration et l’initialisation du système d’authentification OAuth2 via Google.
+Ce fichier gère la configuration et l’initialisation des fournisseurs OAuth2 / OpenID Connect (via goth).
+
+Les fournisseurs sont activés par configuration (voir le paquet config) :
+
+	Google     : GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET
+	GitHub     : GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET
+	Microsoft  : MICROSOFT_CLIENT_ID, MICROSOFT_CLIENT_SECRET, MICROSOFT_TENANT (défaut : common)
+	GitLab     : GITLAB_CLIENT_ID, GITLAB_CLIENT_SECRET, GITLAB_URL (instance auto-hébergée, optionnel)
+	OIDC       : OIDC_PROVIDERS=keycloak,okta puis, pour chaque nom :
+	             OIDC_KEYCLOAK_CLIENT_ID, OIDC_KEYCLOAK_CLIENT_SECRET, OIDC_KEYCLOAK_DISCOVERY_URL,
+	             OIDC_KEYCLOAK_DISPLAY_NAME et OIDC_KEYCLOAK_SCOPES (optionnels)
+
+Chaque fournisseur est accessible sur /auth/{name} et rappelle /auth/{name}/callback via le gateway.
 */
 
 package auth
 
 import (
+	"fmt"
 	"log"
-	"net/http"
-	"os"
+	"regexp"
+	"strings"
 
-	"github.com/gorilla/sessions"
-	"github.com/joho/godotenv"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
+	"github.com/markbates/goth/providers/azureadv2"
+	"github.com/markbates/goth/providers/github"
+	"github.com/markbates/goth/providers/gitlab"
 	"github.com/markbates/goth/providers/google"
-)
+	"github.com/markbates/goth/providers/openidConnect"
 
-const (
-	key    = "infernodragon@megaknight" //clé secrete
-	MaxAge = 86400 * 7                  // durée max du cookie
-	IsProd = false                      // en dévéloppement et non production
+	"auth/internal/config"
 )
 
+// Fournisseur OAuth activé, tel qu’exposé au frontend par GET /auth/providers.
+type Provider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// Fournisseurs intégrés : build retourne nil tant que le client n’est pas configuré.
+var builtinProviders = []struct {
+	name        string
+	displayName string
+	build       func(cfg config.OAuth, callbackURL string) goth.Provider
+}{
+	{"google", "Google", func(cfg config.OAuth, callbackURL string) goth.Provider {
+		if !cfg.Google.Enabled() {
+			return nil
+		}
+		return google.New(cfg.Google.ClientID, cfg.Google.ClientSecret, callbackURL, "email", "profile")
+	}},
+	{"github", "GitHub", func(cfg config.OAuth, callbackURL string) goth.Provider {
+		if !cfg.GitHub.Enabled() {
+			return nil
+		}
+		return github.New(cfg.GitHub.ClientID, cfg.GitHub.ClientSecret, callbackURL, "read:user", "user:email")
+	}},
+	{"microsoft", "Microsoft", func(cfg config.OAuth, callbackURL string) goth.Provider {
+		if !cfg.Microsoft.Enabled() {
+			return nil
+		}
+		p := azureadv2.New(cfg.Microsoft.ClientID, cfg.Microsoft.ClientSecret, callbackURL, azureadv2.ProviderOptions{
+			Tenant: azureadv2.TenantType(cfg.Microsoft.Tenant),
+		})
+		p.SetName("microsoft")
+		return p
+	}},
+	{"gitlab", "GitLab", func(cfg config.OAuth, callbackURL string) goth.Provider {
+		if !cfg.GitLab.Enabled() {
+			return nil
+		}
+		clientID, clientSecret := cfg.GitLab.ClientID, cfg.GitLab.ClientSecret
+		if baseURL := strings.TrimSuffix(cfg.GitLab.URL, "/"); baseURL != "" {
+			return gitlab.NewCustomisedURL(clientID, clientSecret, callbackURL,
+				baseURL+"/oauth/authorize", baseURL+"/oauth/token", baseURL+"/api/v4/user", "read_user")
+		}
+		return gitlab.New(clientID, clientSecret, callbackURL, "read_user")
+	}},
+}
+
+// Noms déjà utilisés par d’autres routes /auth/... du service.
+var reservedProviderNames = map[string]bool{
+	"callback": true, "forgot-password": true, "login": true, "logout": true, "providers": true,
+	"register": true, "resend-code": true, "reset-password": true, "siwe": true, "token": true,
+	"verify": true, "webauthn": true,
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+var enabledProviders []Provider
+
+// Fournisseurs OAuth activés, dans l’ordre de configuration.
+func Providers() []Provider {
+	return enabledProviders
+}
+
+// Nom affiché d’un fournisseur activé (le nom technique s’il est inconnu).
+func ProviderDisplayName(name string) string {
+	for _, p := range enabledProviders {
+		if p.Name == name {
+			return p.DisplayName
+		}
+	}
+	return name
+}
+
+/*
+Indique si le fournisseur garantit que l’utilisateur possède son adresse email,
+condition pour fusionner l’identité avec un compte existant portant la même adresse.
+GitHub ne renvoie qu’un email primaire vérifié ; Google et les fournisseurs OIDC l’indiquent explicitement.
+Microsoft et GitLab ne donnent aucune garantie : leurs identités ne sont jamais fusionnées automatiquement.
+*/
+func ProviderEmailVerified(provider string, rawData map[string]interface{}) bool {
+	switch provider {
+	case "github":
+		return true
+	case "microsoft", "gitlab":
+		return false
+	}
+	for _, claim := range []string{"email_verified", "verified_email"} {
+		switch v := rawData[claim].(type) {
+		case bool:
+			return v
+		case string:
+			return v == "true"
+		}
+	}
+	return false
+}
+
 /*
-Tente de charger les variables depuis le fichier .env
-Récupère le Client ID et le Client Secret du compte OAuth2 Google.
-Récupère l’URL du gateway
-Crée un cookie store sécurisé pour conserver les sessions des utilisateurs authentifiés.
-Construit l’URL de rappel complète que Google utilisera après l’authentification.
-Les scopes "email" et "profile" permettent de récupérer l’adresse e-mail et le nom de l’utilisateur.
+Crée le cookie store qui conserve l’état des flux OAuth (trousseau de clés, voir oauth_state.go).
+Enregistre auprès de goth tous les fournisseurs configurés ;
+aucun n’est obligatoire (la connexion email/mot de passe, passkey et SIWE reste possible).
 */
+func NewAuth(cfg *config.Config) {
+	gothic.Store = NewOAuthStateStore(cfg.Cookies)
 
-func NewAuth() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	callbackURL := func(name string) string {
+		return cfg.Server.GatewayURL + "/auth/" + name + "/callback"
 	}
 
-	googleClientId := os.Getenv("GOOGLE_CLIENT_ID")
-	googleClientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
+	var providers []goth.Provider
+	enabledProviders = nil
 
-	if googleClientId == "" || googleClientSecret == "" {
-		log.Fatal("GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET must be set")
+	for _, p := range builtinProviders {
+		provider := p.build(cfg.OAuth, callbackURL(p.name))
+		if provider == nil {
+			continue
+		}
+		providers = append(providers, provider)
+		enabledProviders = append(enabledProviders, Provider{Name: p.name, DisplayName: p.displayName})
 	}
 
-	gatewayURL := os.Getenv("GATEWAY_URL")
-	if gatewayURL == "" {
-		gatewayURL = "http://localhost:8000"
+	for _, p := range cfg.OAuth.OIDC {
+		provider, err := newOIDCProvider(p, callbackURL(p.Name))
+		if err != nil {
+			log.Printf("OIDC provider %q disabled: %v", p.Name, err)
+			continue
+		}
+		providers = append(providers, provider)
+		enabledProviders = append(enabledProviders, Provider{Name: p.Name, DisplayName: p.DisplayName})
 	}
 
-	store := sessions.NewCookieStore([]byte(key))
-	store.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   MaxAge,
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
+	if len(providers) == 0 {
+		log.Println("Warning: no OAuth provider configured")
+		return
 	}
-	gothic.Store = store
-
-	callbackURL := gatewayURL + "/auth/google/callback"
-	goth.UseProviders(
-		google.New(
-			googleClientId,
-			googleClientSecret,
-			callbackURL,
-			"email", "profile",
-		),
-	)
+
+	goth.UseProviders(providers...)
+	for _, p := range enabledProviders {
+		log.Printf("OAuth provider enabled: %s (%s)", p.Name, callbackURL(p.Name))
+	}
+}
+
+// Fournisseur OpenID Connect générique configuré par OIDC_<NAME>_* (découverte automatique).
+func newOIDCProvider(p config.OIDCProvider, callbackURL string) (goth.Provider, error) {
+	if !providerNamePattern.MatchString(p.Name) || reservedProviderNames[p.Name] {
+		return nil, fmt.Errorf("invalid provider name")
+	}
+	for _, builtin := range builtinProviders {
+		if builtin.name == p.Name {
+			return nil, fmt.Errorf("name already used by a built-in provider")
+		}
+	}
+
+	return openidConnect.NewNamed(p.Name, p.ClientID, p.ClientSecret, callbackURL, p.DiscoveryURL, strings.Fields(p.Scopes)...)
 }
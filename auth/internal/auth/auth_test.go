@@ -0,0 +1,21 @@
+package auth
+
+import "testing"
+
+func TestProviderEmailVerified(t *testing.T) {
+	if !ProviderEmailVerified("google", map[string]interface{}{"verified_email": true}) {
+		t.Errorf("expected Google verified_email to be trusted")
+	}
+	if !ProviderEmailVerified("keycloak", map[string]interface{}{"email_verified": "true"}) {
+		t.Errorf("expected OIDC email_verified string claim to be trusted")
+	}
+	if ProviderEmailVerified("keycloak", map[string]interface{}{"email_verified": false}) {
+		t.Errorf("expected unverified OIDC email to be rejected")
+	}
+	if ProviderEmailVerified("microsoft", map[string]interface{}{"email_verified": true}) {
+		t.Errorf("expected Microsoft email never to be trusted")
+	}
+	if ProviderEmailVerified("google", nil) {
+		t.Errorf("expected missing claim to be rejected")
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Ce fichier implémente les jetons d’accès signés (JWT, RFC 7519) et leurs clés publiques (JWK, RFC 7517).
+
+Les jetons sont signés en EdDSA (Ed25519, RFC 8037) et portent l’identité de l’utilisateur :
+le gateway et les backends les vérifient localement grâce à /.well-known/jwks.json,
+sans appeler /api/me à chaque requête.
+La session opaque (cookie session_token) reste la référence pour obtenir un nouveau jeton.
+*/
+
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const AccessTokenAlgorithm = "EdDSA"
+
+// Revendications d’un jeton d’accès.
+type AccessTokenClaims struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	IssuedAt      int64  `json:"iat"`
+	ExpiresAt     int64  `json:"exp"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+
+	// Rôles de l’utilisateur (jetons internes uniquement), pour l’autorisation côté gateway / backends.
+	Roles []string `json:"roles,omitempty"`
+
+	// Session qui a obtenu le jeton (jetons internes uniquement, voir SessionID).
+	SessionID string `json:"sid,omitempty"`
+
+	// Jetons délivrés à un client OpenID Connect : client destinataire et scopes accordés.
+	// Les jetons internes (gateway / backends) n’ont pas d’audience.
+	Audience string `json:"aud,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// Clé de signature identifiée par son kid (publié dans le JWKS).
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+}
+
+// Clé publique au format JWK (OKP / Ed25519).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	X   string `json:"x"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+var (
+	ErrInvalidToken = errors.New("invalid access token")
+	ErrExpiredToken = errors.New("access token expired")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+var b64 = base64.RawURLEncoding
+
+/*
+Génère une nouvelle clé Ed25519.
+Le kid est dérivé de la clé publique (empreinte SHA-256 tronquée) : il est stable et sans secret.
+*/
+func GenerateSigningKey() (SigningKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	sum := sha256.Sum256(pub)
+	return SigningKey{ID: b64.EncodeToString(sum[:12]), PrivateKey: priv}, nil
+}
+
+// Clé publique JWK correspondant à une clé de signature.
+func (k SigningKey) JWK() JWK {
+	return JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		Kid: k.ID,
+		Use: "sig",
+		Alg: AccessTokenAlgorithm,
+		X:   b64.EncodeToString(k.PrivateKey.Public().(ed25519.PublicKey)),
+	}
+}
+
+// Clé publique Ed25519 décrite par un JWK.
+func (j JWK) PublicKey() (ed25519.PublicKey, error) {
+	if j.Kty != "OKP" || j.Crv != "Ed25519" {
+		return nil, errors.New("unsupported JWK type")
+	}
+	x, err := b64.DecodeString(j.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 JWK")
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// Signe les revendications avec la clé donnée (JWS compact : header.payload.signature).
+func SignAccessToken(key SigningKey, claims AccessTokenClaims) (string, error) {
+	return signJWT(key, claims)
+}
+
+func signJWT(key SigningKey, claims interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: AccessTokenAlgorithm, Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	signature := ed25519.Sign(key.PrivateKey, []byte(signingInput))
+	return signingInput + "." + b64.EncodeToString(signature), nil
+}
+
+/*
+Vérifie un jeton d’accès : algorithme, signature avec la clé désignée par le kid,
+émetteur et expiration. keys associe chaque kid publié à sa clé publique.
+*/
+func VerifyAccessToken(token string, keys map[string]ed25519.PublicKey, issuer string, now time.Time) (*AccessTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	rawHeader, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil || header.Alg != AccessTokenAlgorithm {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := keys[header.Kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	signature, err := b64.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	rawPayload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims AccessTokenClaims
+	if err := json.Unmarshal(rawPayload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Issuer != issuer || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("GenerateSigningKey error: %v", err)
+	}
+
+	pub, err := key.JWK().PublicKey()
+	if err != nil {
+		t.Fatalf("JWK.PublicKey error: %v", err)
+	}
+	keys := map[string]ed25519.PublicKey{key.ID: pub}
+
+	now := time.Unix(1767261600, 0)
+	token, err := SignAccessToken(key, AccessTokenClaims{
+		Issuer:        "http://localhost:8000",
+		Subject:       "42",
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     now.Add(10 * time.Minute).Unix(),
+		Email:         "user@example.com",
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("SignAccessToken error: %v", err)
+	}
+
+	claims, err := VerifyAccessToken(token, keys, "http://localhost:8000", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("VerifyAccessToken error: %v", err)
+	}
+	if claims.Subject != "42" || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	if _, err := VerifyAccessToken(token, keys, "http://localhost:8000", now.Add(time.Hour)); err != ErrExpiredToken {
+		t.Errorf("expected expired token, got %v", err)
+	}
+	if _, err := VerifyAccessToken(token, keys, "http://evil.example", now); err != ErrInvalidToken {
+		t.Errorf("expected issuer mismatch, got %v", err)
+	}
+
+	// Une autre clé (rotation) ne doit pas valider ce jeton
+	other, _ := GenerateSigningKey()
+	otherPub, _ := other.JWK().PublicKey()
+	if _, err := VerifyAccessToken(token, map[string]ed25519.PublicKey{other.ID: otherPub}, "http://localhost:8000", now); err != ErrUnknownKey {
+		t.Errorf("expected unknown key, got %v", err)
+	}
+
+	// Charge utile modifiée
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + b64.EncodeToString([]byte(`{"iss":"http://localhost:8000","sub":"1","exp":9999999999}`)) + "." + parts[2]
+	if _, err := VerifyAccessToken(forged, keys, "http://localhost:8000", now); err != ErrInvalidToken {
+		t.Errorf("expected forged token to be rejected, got %v", err)
+	}
+}
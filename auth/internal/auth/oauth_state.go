@@ -0,0 +1,67 @@
+/*
+Ce fichier crée le cookie store de gothic, qui conserve l’état d’un flux OAuth
+entre /auth/{provider} et /auth/{provider}/callback.
+
+Le cookie est signé (HMAC-SHA256) et chiffré (AES) avec un trousseau de paires de clés
+(OAUTH_STATE_SIGNING_KEYS et OAUTH_STATE_ENCRYPTION_KEYS, appariées par position) :
+la première paire protège les nouveaux cookies, les suivantes ne servent qu’à relire
+les cookies émis avant une rotation.
+
+Rotation sans interruption (plusieurs instances) :
+
+ 1. ajouter la nouvelle paire en dernière position et déployer : toutes les instances savent la lire ;
+ 2. la déplacer en première position et déployer : les nouveaux cookies l’utilisent ;
+ 3. après OAUTH_STATE_TTL (durée de vie du cookie), retirer l’ancienne paire.
+*/
+
+package auth
+
+import (
+	"crypto/rand"
+	"log"
+
+	"github.com/gorilla/sessions"
+
+	"auth/internal/config"
+)
+
+/*
+Cookie store des flux OAuth : Secure (COOKIE_SECURE), SameSite (OAUTH_STATE_SAMESITE)
+et durée de vie (OAUTH_STATE_TTL) viennent de la configuration.
+Sans trousseau (hors production), une paire aléatoire est générée :
+les flux en cours ne survivent pas à un redémarrage.
+*/
+func NewOAuthStateStore(cfg config.Cookies) *sessions.CookieStore {
+	pairs, err := cfg.OAuthState.KeyPairs()
+	if err != nil {
+		log.Fatal("Invalid OAuth state keys:", err)
+	}
+	if len(pairs) == 0 {
+		log.Println("Warning: OAUTH_STATE_SIGNING_KEYS and OAUTH_STATE_ENCRYPTION_KEYS are not set, using random keys (OAuth flows in progress are lost on restart)")
+		pairs = []config.CookieKeyPair{{Signing: randomKey(64), Encryption: randomKey(32)}}
+	}
+
+	keys := make([][]byte, 0, 2*len(pairs))
+	for _, pair := range pairs {
+		keys = append(keys, pair.Signing, pair.Encryption)
+	}
+
+	store := sessions.NewCookieStore(keys...)
+	store.Options = &sessions.Options{
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: cfg.OAuthState.SameSiteMode(),
+	}
+	// Expiration du cookie et des valeurs signées
+	store.MaxAge(int(cfg.OAuthState.TTL.Seconds()))
+	return store
+}
+
+func randomKey(n int) []byte {
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal("Failed to generate OAuth state key:", err)
+	}
+	return key
+}
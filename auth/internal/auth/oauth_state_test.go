@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"auth/internal/config"
+)
+
+func stateCookies(keys ...string) config.Cookies {
+	cfg := config.Defaults().Cookies
+	for _, key := range keys {
+		cfg.OAuthState.SigningKeys = append(cfg.OAuthState.SigningKeys, base64.StdEncoding.EncodeToString([]byte(strings.Repeat(key, 64))))
+		cfg.OAuthState.EncryptionKeys = append(cfg.OAuthState.EncryptionKeys, base64.StdEncoding.EncodeToString([]byte(strings.Repeat(key, 32))))
+	}
+	return cfg
+}
+
+// Enregistre une valeur avec store et retourne le cookie émis.
+func saveState(t *testing.T, store *sessions.CookieStore) *http.Cookie {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/google", nil)
+	rec := httptest.NewRecorder()
+	session, _ := store.Get(req, "_gothic_session")
+	session.Values["google"] = "state"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Result().Cookies()[0]
+}
+
+func readState(store *sessions.CookieStore, cookie *http.Cookie) (interface{}, error) {
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback", nil)
+	req.AddCookie(cookie)
+	session, err := store.Get(req, "_gothic_session")
+	return session.Values["google"], err
+}
+
+func TestOAuthStateStoreRotation(t *testing.T) {
+	oldCookie := saveState(t, NewOAuthStateStore(stateCookies("a")))
+
+	// Nouvelle paire en première position : les anciens cookies restent lisibles
+	rotated := NewOAuthStateStore(stateCookies("b", "a"))
+	if value, err := readState(rotated, oldCookie); err != nil || value != "state" {
+		t.Fatalf("cookie signed with the previous key should still be valid, got %v, %v", value, err)
+	}
+
+	// Les nouveaux cookies utilisent la nouvelle paire
+	newCookie := saveState(t, rotated)
+	if _, err := readState(NewOAuthStateStore(stateCookies("a")), newCookie); err == nil {
+		t.Error("cookie should be protected by the new key")
+	}
+	if value, err := readState(NewOAuthStateStore(stateCookies("b")), newCookie); err != nil || value != "state" {
+		t.Errorf("cookie should be readable once the old key is removed, got %v, %v", value, err)
+	}
+
+	// Ancienne paire retirée
+	if _, err := readState(NewOAuthStateStore(stateCookies("b")), oldCookie); err == nil {
+		t.Error("cookie signed with a removed key should be rejected")
+	}
+}
+
+func TestOAuthStateStoreOptions(t *testing.T) {
+	cfg := stateCookies("a")
+	cfg.Secure = true
+	cfg.OAuthState.SameSite = "none"
+	cfg.OAuthState.TTL = 10 * time.Minute
+
+	cookie := saveState(t, NewOAuthStateStore(cfg))
+	if !cookie.Secure || cookie.SameSite != http.SameSiteNoneMode || !cookie.HttpOnly || cookie.MaxAge != 600 {
+		t.Errorf("unexpected cookie attributes: %+v", cookie)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Ce fichier contient les éléments OpenID Connect indépendants du stockage :
+jeton d’identité (ID token), vérification PKCE (RFC 7636) et gestion des scopes.
+*/
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"strings"
+)
+
+// Scopes reconnus par le fournisseur OpenID Connect.
+var SupportedScopes = []string{"openid", "profile", "email"}
+
+// Revendications d’un jeton d’identité OpenID Connect.
+type IDTokenClaims struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	Audience      string `json:"aud"`
+	IssuedAt      int64  `json:"iat"`
+	ExpiresAt     int64  `json:"exp"`
+	AuthTime      int64  `json:"auth_time,omitempty"`
+	Nonce         string `json:"nonce,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+}
+
+// Signe un jeton d’identité avec la clé courante (même JWKS que les jetons d’accès).
+func SignIDToken(key SigningKey, claims IDTokenClaims) (string, error) {
+	return signJWT(key, claims)
+}
+
+/*
+Vérifie le code_verifier PKCE contre le code_challenge reçu à l’autorisation.
+Seule la méthode S256 est acceptée : BASE64URL(SHA256(verifier)) == challenge.
+*/
+func VerifyPKCE(verifier, challenge string) bool {
+	// RFC 7636 : 43 à 128 caractères
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(b64.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// Découpe une liste de scopes séparés par des espaces, sans doublons.
+func ParseScopes(scope string) []string {
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, s := range strings.Fields(scope) {
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// Indique si scope (liste séparée par des espaces) contient wanted.
+func HasScope(scope, wanted string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == wanted {
+			return true
+		}
+	}
+	return false
+}
+
+// Indique si tous les scopes de requested figurent dans granted.
+func ScopesCovered(requested, granted string) bool {
+	for _, s := range strings.Fields(requested) {
+		if !HasScope(granted, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package auth
+
+import "testing"
+
+func TestVerifyPKCE(t *testing.T) {
+	// Exemple de l’annexe B de la RFC 7636
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if !VerifyPKCE(verifier, challenge) {
+		t.Fatalf("expected RFC 7636 verifier to match")
+	}
+	if VerifyPKCE(verifier+"x", challenge) {
+		t.Errorf("expected modified verifier to be rejected")
+	}
+	if VerifyPKCE("short", challenge) {
+		t.Errorf("expected short verifier to be rejected")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	if got := ParseScopes("openid  email openid"); len(got) != 2 || got[0] != "openid" || got[1] != "email" {
+		t.Fatalf("unexpected scopes %v", got)
+	}
+	if !ScopesCovered("openid email", "email profile openid") {
+		t.Errorf("expected scopes to be covered")
+	}
+	if ScopesCovered("openid email", "openid") {
+		t.Errorf("expected email scope to be missing")
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Ce fichier définit le format des jetons de session (cookie session_token) :
+
+	se_sess_<64 caractères hex aléatoires>_<CRC32 hex sur 8 caractères>
+
+Le préfixe rend le jeton reconnaissable (logs, scanners de secrets) et le CRC32 permet
+de rejeter un jeton mal formé ou tronqué sans interroger la base.
+Seule l’empreinte SHA-256 du jeton est stockée (voir database.HashToken).
+*/
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+const SessionTokenPrefix = "se_sess_"
+
+const (
+	sessionSecretLength   = 64
+	sessionChecksumLength = 8
+	sessionTokenLength    = len(SessionTokenPrefix) + sessionSecretLength + 1 + sessionChecksumLength
+)
+
+// Génère un nouveau jeton de session (32 octets aléatoires + somme de contrôle).
+func NewSessionToken() (string, error) {
+	b := make([]byte, sessionSecretLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %v", err)
+	}
+	body := SessionTokenPrefix + hex.EncodeToString(b)
+	return body + "_" + sessionChecksum(body), nil
+}
+
+// Indique si token a le format d’un jeton de session et une somme de contrôle correcte.
+func ValidSessionToken(token string) bool {
+	if len(token) != sessionTokenLength || !strings.HasPrefix(token, SessionTokenPrefix) {
+		return false
+	}
+	body, checksum := token[:len(token)-sessionChecksumLength-1], token[len(token)-sessionChecksumLength:]
+	if token[len(body)] != '_' {
+		return false
+	}
+	if _, err := hex.DecodeString(body[len(SessionTokenPrefix):]); err != nil {
+		return false
+	}
+	return checksum == sessionChecksum(body)
+}
+
+/*
+Identifiant de session porté par les jetons d’accès internes (revendication sid).
+Le gateway le recalcule à partir du cookie session_token pour ne réutiliser un jeton
+qu’avec la session qui l’a obtenu. Le préfixe le distingue de database.HashToken :
+les backends ne voient jamais la clé de la table sessions.
+*/
+func SessionID(token string) string {
+	sum := sha256.Sum256([]byte("sid:" + token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func sessionChecksum(body string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(body)))
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionTokenFormat(t *testing.T) {
+	token, err := NewSessionToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, SessionTokenPrefix) {
+		t.Fatalf("token %q does not start with %q", token, SessionTokenPrefix)
+	}
+	if !ValidSessionToken(token) {
+		t.Fatalf("freshly issued token %q is not valid", token)
+	}
+
+	other, _ := NewSessionToken()
+	if token == other {
+		t.Fatal("two tokens are identical")
+	}
+}
+
+func TestValidSessionTokenRejectsMalformedTokens(t *testing.T) {
+	token, _ := NewSessionToken()
+
+	tampered := []byte(token)
+	if tampered[20] == 'a' {
+		tampered[20] = 'b'
+	} else {
+		tampered[20] = 'a'
+	}
+
+	cases := map[string]string{
+		"empty":     "",
+		"legacy":    strings.Repeat("ab", 32),
+		"truncated": token[:len(token)-1],
+		"tampered":  string(tampered),
+		"prefix":    "se_xxxx_" + token[len(SessionTokenPrefix):],
+	}
+	for name, value := range cases {
+		if ValidSessionToken(value) {
+			t.Errorf("%s token %q should be rejected", name, value)
+		}
+	}
+}
+
+// Même vecteur que sessionID dans gateway/cmd/api : les deux calculs doivent rester identiques.
+func TestSessionID(t *testing.T) {
+	if got := SessionID("se_sess_test"); got != "JeCYtWuls21OWJ4zcRVCLIbW4Hc9e3A2GWhrdjD_fAw" {
+		t.Fatalf("unexpected session id %q", got)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Ce fichier implémente Sign-In with Ethereum (EIP-4361).
+
+Le wallet signe un message texte standardisé (personal_sign, EIP-191) contenant
+le domaine, l’adresse, un nonce fourni par le serveur et des dates de validité.
+Le serveur retrouve l’adresse du signataire à partir de la signature secp256k1
+et la compare à celle annoncée dans le message.
+*/
+
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// Champs d’un message SIWE (EIP-4361).
+type SIWEMessage struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+/*
+Analyse un message SIWE.
+Format :
+
+	{domain} wants you to sign in with your Ethereum account:
+	{address}
+
+	{statement}
+
+	URI: {uri}
+	Version: 1
+	Chain ID: {chain-id}
+	Nonce: {nonce}
+	Issued At: {issued-at}
+	Expiration Time: {expiration-time}   (optionnel)
+	Not Before: {not-before}             (optionnel)
+	Request ID: {request-id}             (optionnel)
+	Resources:                           (optionnel)
+	- {resource}
+*/
+func ParseSIWEMessage(message string) (*SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], siweHeaderSuffix) {
+		return nil, errors.New("invalid SIWE header")
+	}
+
+	msg := &SIWEMessage{
+		Domain:  strings.TrimSuffix(lines[0], siweHeaderSuffix),
+		Address: strings.TrimSpace(lines[1]),
+	}
+	if !IsEthereumAddress(msg.Address) {
+		return nil, errors.New("invalid SIWE address")
+	}
+
+	i := 2
+	// Déclaration optionnelle entre l’adresse et les champs
+	var statement []string
+	for ; i < len(lines) && !strings.HasPrefix(lines[i], "URI: "); i++ {
+		if lines[i] != "" {
+			statement = append(statement, lines[i])
+		}
+	}
+	msg.Statement = strings.Join(statement, "\n")
+
+	inResources := false
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if inResources {
+			if !strings.HasPrefix(line, "- ") {
+				return nil, fmt.Errorf("invalid SIWE resource line %q", line)
+			}
+			msg.Resources = append(msg.Resources, strings.TrimPrefix(line, "- "))
+			continue
+		}
+		if line == "Resources:" {
+			inResources = true
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("invalid SIWE line %q", line)
+		}
+
+		var err error
+		switch key {
+		case "URI":
+			msg.URI = value
+		case "Version":
+			msg.Version = value
+		case "Chain ID":
+			msg.ChainID, err = strconv.ParseInt(value, 10, 64)
+		case "Nonce":
+			msg.Nonce = value
+		case "Issued At":
+			msg.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case "Expiration Time":
+			var t time.Time
+			t, err = time.Parse(time.RFC3339, value)
+			msg.ExpirationTime = &t
+		case "Not Before":
+			var t time.Time
+			t, err = time.Parse(time.RFC3339, value)
+			msg.NotBefore = &t
+		case "Request ID":
+			msg.RequestID = value
+		default:
+			return nil, fmt.Errorf("unknown SIWE field %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SIWE field %q: %v", key, err)
+		}
+	}
+
+	if msg.URI == "" || msg.Version == "" || msg.ChainID == 0 || msg.Nonce == "" || msg.IssuedAt.IsZero() {
+		return nil, errors.New("missing required SIWE field")
+	}
+	if msg.Version != "1" {
+		return nil, errors.New("unsupported SIWE version")
+	}
+
+	return msg, nil
+}
+
+/*
+Vérifie les champs du message indépendamment de la signature :
+le domaine attendu et la fenêtre de validité (Expiration Time / Not Before).
+*/
+func (m *SIWEMessage) Validate(domain string, now time.Time) error {
+	if m.Domain != domain {
+		return fmt.Errorf("SIWE domain mismatch: %s", m.Domain)
+	}
+	if m.ExpirationTime != nil && now.After(*m.ExpirationTime) {
+		return errors.New("SIWE message expired")
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return errors.New("SIWE message not yet valid")
+	}
+	return nil
+}
+
+/*
+Retrouve l’adresse qui a signé message avec personal_sign.
+La signature fait 65 octets r || s || v, v valant 27/28 (ou 0/1 selon les wallets).
+*/
+func RecoverPersonalSignAddress(message, signatureHex string) (string, error) {
+	signature, err := hex.DecodeString(strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil || len(signature) != 65 {
+		return "", errors.New("invalid signature")
+	}
+
+	v := signature[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return "", errors.New("invalid signature recovery id")
+	}
+
+	// Format compact attendu par secp256k1 : <27 + v> || r || s (clé non compressée)
+	compact := make([]byte, 65)
+	compact[0] = 27 + v
+	copy(compact[1:], signature[:64])
+
+	pub, _, err := ecdsa.RecoverCompact(compact, personalSignHash(message))
+	if err != nil {
+		return "", err
+	}
+
+	// Adresse = 20 derniers octets du keccak256 de la clé publique non compressée (sans le préfixe 0x04)
+	addressHash := keccak256(pub.SerializeUncompressed()[1:])
+	return ChecksumAddress("0x" + hex.EncodeToString(addressHash[12:])), nil
+}
+
+// Hash EIP-191 : keccak256("\x19Ethereum Signed Message:\n" + len(message) + message)
+func personalSignHash(message string) []byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))
+	return keccak256([]byte(prefix + message))
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// Indique si value est une adresse Ethereum hexadécimale (0x + 40 caractères).
+func IsEthereumAddress(value string) bool {
+	if len(value) != 42 || !strings.HasPrefix(value, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(value[2:])
+	return err == nil
+}
+
+// Formate une adresse avec la casse de contrôle EIP-55.
+func ChecksumAddress(address string) string {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	hash := hex.EncodeToString(keccak256([]byte(lower)))
+
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			out[i] = c - 32
+		}
+	}
+	return "0x" + string(out)
+}
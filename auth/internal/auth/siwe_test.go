@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Signe comme personal_sign : r || s || v avec v = 27/28.
+func personalSign(t *testing.T, key *secp256k1.PrivateKey, message string) string {
+	t.Helper()
+	compact := ecdsa.SignCompact(key, personalSignHash(message), false)
+	sig := append(compact[1:], compact[0])
+	return "0x" + hex.EncodeToString(sig)
+}
+
+func TestRecoverPersonalSignAddress(t *testing.T) {
+	// La clé privée 1 correspond à l’adresse bien connue 0x7E5F...5Bdf
+	var one [32]byte
+	one[31] = 1
+	key := secp256k1.PrivKeyFromBytes(one[:])
+
+	message := "hello SmartEther"
+	address, err := RecoverPersonalSignAddress(message, personalSign(t, key, message))
+	if err != nil {
+		t.Fatalf("RecoverPersonalSignAddress error: %v", err)
+	}
+	if address != "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf" {
+		t.Fatalf("unexpected address %s", address)
+	}
+
+	other, err := RecoverPersonalSignAddress("another message", personalSign(t, key, message))
+	if err == nil && other == address {
+		t.Fatalf("signature must not validate another message")
+	}
+}
+
+func TestChecksumAddress(t *testing.T) {
+	// Vecteurs de l’EIP-55
+	for _, want := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+	} {
+		if got := ChecksumAddress(want); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestParseSIWEMessage(t *testing.T) {
+	message := "localhost:3000 wants you to sign in with your Ethereum account:\n" +
+		"0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf\n" +
+		"\n" +
+		"Sign in to SmartEther\n" +
+		"\n" +
+		"URI: http://localhost:3000\n" +
+		"Version: 1\n" +
+		"Chain ID: 31337\n" +
+		"Nonce: abcdef1234567890\n" +
+		"Issued At: 2026-01-01T10:00:00Z\n" +
+		"Expiration Time: 2026-01-01T10:10:00Z\n" +
+		"Resources:\n" +
+		"- https://smartether.app/terms"
+
+	msg, err := ParseSIWEMessage(message)
+	if err != nil {
+		t.Fatalf("ParseSIWEMessage error: %v", err)
+	}
+	if msg.Domain != "localhost:3000" || msg.Statement != "Sign in to SmartEther" || msg.ChainID != 31337 ||
+		msg.Nonce != "abcdef1234567890" || len(msg.Resources) != 1 {
+		t.Fatalf("unexpected parsed message: %+v", msg)
+	}
+
+	issued := time.Date(2026, 1, 1, 10, 5, 0, 0, time.UTC)
+	if err := msg.Validate("localhost:3000", issued); err != nil {
+		t.Errorf("expected message to be valid: %v", err)
+	}
+	if err := msg.Validate("evil.example", issued); err == nil {
+		t.Errorf("expected domain mismatch")
+	}
+	if err := msg.Validate("localhost:3000", issued.Add(time.Hour)); err == nil {
+		t.Errorf("expected expired message")
+	}
+
+	// Sans déclaration
+	noStatement := "localhost:3000 wants you to sign in with your Ethereum account:\n" +
+		"0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf\n\n\n" +
+		"URI: http://localhost:3000\nVersion: 1\nChain ID: 1\nNonce: abcdef1234567890\nIssued At: 2026-01-01T10:00:00Z"
+	if msg, err := ParseSIWEMessage(noStatement); err != nil || msg.Statement != "" {
+		t.Errorf("expected message without statement to parse: %v", err)
+	}
+}
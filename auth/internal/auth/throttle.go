@@ -0,0 +1,76 @@
+/*
+Ce fichier définit la protection contre la force brute (connexion, vérification d’email).
+
+Chaque compteur (par compte ou par adresse IP) compte les échecs récents :
+
+	au-delà de FreeFailures échecs, chaque nouvelle tentative doit attendre un délai
+	qui double à chaque échec (BaseDelay, 2×BaseDelay… plafonné à MaxDelay) ;
+	à partir de LockoutFailures échecs, le compteur est verrouillé pendant LockoutDuration.
+
+Les échecs plus anciens que Window sont oubliés.
+*/
+
+package auth
+
+import "time"
+
+type ThrottlePolicy struct {
+	FreeFailures    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutFailures int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// Compteur par compte : quelques erreurs de frappe, puis ralentissement et verrouillage.
+var AccountThrottlePolicy = ThrottlePolicy{
+	FreeFailures:    3,
+	BaseDelay:       2 * time.Second,
+	MaxDelay:        time.Minute,
+	LockoutFailures: 10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// Compteur par adresse IP : plus tolérant (NAT, réseaux partagés), il vise les attaques sur de nombreux comptes.
+var IPThrottlePolicy = ThrottlePolicy{
+	FreeFailures:    20,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutFailures: 100,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// Délai imposé après failures échecs consécutifs.
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	if failures >= p.LockoutFailures {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeFailures {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeFailures + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+/*
+Temps restant avant la prochaine tentative autorisée (0 : tentative autorisée),
+pour un compteur de failures échecs dont le dernier date de lastFailure.
+*/
+func (p ThrottlePolicy) RetryAfter(failures int, lastFailure, now time.Time) time.Duration {
+	if failures == 0 || now.Sub(lastFailure) >= p.Window {
+		return 0
+	}
+	if wait := lastFailure.Add(p.Delay(failures)).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
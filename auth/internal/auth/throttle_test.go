@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyDelay(t *testing.T) {
+	p := ThrottlePolicy{
+		FreeFailures:    3,
+		BaseDelay:       2 * time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutFailures: 8,
+		LockoutDuration: 15 * time.Minute,
+		Window:          15 * time.Minute,
+	}
+
+	cases := map[int]time.Duration{
+		0: 0,
+		3: 0,
+		4: 2 * time.Second,
+		5: 4 * time.Second,
+		6: 8 * time.Second,
+		7: 10 * time.Second,
+		8: 15 * time.Minute,
+		9: 15 * time.Minute,
+	}
+	for failures, want := range cases {
+		if got := p.Delay(failures); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestThrottlePolicyRetryAfter(t *testing.T) {
+	p := AccountThrottlePolicy
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := p.RetryAfter(p.FreeFailures, now, now); got != 0 {
+		t.Errorf("expected no delay within the free failures, got %v", got)
+	}
+	if got := p.RetryAfter(p.FreeFailures+1, now.Add(-time.Second), now); got != p.BaseDelay-time.Second {
+		t.Errorf("expected the remaining progressive delay, got %v", got)
+	}
+	if got := p.RetryAfter(p.LockoutFailures, now.Add(-time.Minute), now); got != p.LockoutDuration-time.Minute {
+		t.Errorf("expected the remaining lockout, got %v", got)
+	}
+	if got := p.RetryAfter(p.LockoutFailures, now.Add(-p.Window), now); got != 0 {
+		t.Errorf("expected failures outside the window to be forgotten, got %v", got)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Ce fichier implémente les mots de passe à usage unique basés sur le temps (TOTP, RFC 6238)
+utilisés pour l’authentification à deux facteurs.
+
+Paramètres compatibles avec Google Authenticator, Authy, 1Password... :
+HMAC-SHA1, codes à 6 chiffres, pas de 30 secondes.
+*/
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 // secondes
+	TOTPIssuer = "SmartEther"
+
+	// Nombre de pas acceptés avant/après le pas courant (décalage d’horloge du téléphone)
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Génère un secret aléatoire de 160 bits encodé en base32 (taille recommandée par la RFC 4226).
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+/*
+Construit l’URI otpauth:// à afficher en QR code dans l’application d’authentification.
+Format : otpauth://totp/Issuer:compte?secret=...&issuer=...&algorithm=SHA1&digits=6&period=30
+*/
+func TOTPURI(secret, account string) string {
+	label := url.PathEscape(TOTPIssuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", TOTPIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", TOTPDigits))
+	params.Set("period", fmt.Sprintf("%d", TOTPPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+/*
+Vérifie un code TOTP à l’instant now.
+Retourne le pas (time step) qui a validé le code : l’appelant le stocke pour refuser
+qu’un même code soit rejoué, en n’acceptant que des pas strictement supérieurs à lastStep.
+*/
+func ValidateTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / TOTPPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected := hotp(key, uint64(step), TOTPDigits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// HOTP (RFC 4226) : HMAC-SHA1 du compteur puis troncature dynamique.
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+/*
+Génère n codes de récupération au format xxxxx-xxxxx.
+Ils permettent de se connecter si le téléphone est perdu ; chacun n’est utilisable qu’une fois.
+*/
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+	}
+	return codes, nil
+}
+
+// Normalise un code de récupération saisi par l’utilisateur (casse, espaces, tiret).
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Vecteurs de test SHA1 de l’annexe B de la RFC 6238 (codes à 8 chiffres).
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, v := range vectors {
+		if got := hotp(key, uint64(v.unix/TOTPPeriod), 8); got != v.code {
+			t.Errorf("time %d: expected %s, got %s", v.unix, v.code, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	code := hotp([]byte("12345678901234567890"), uint64(now.Unix()/TOTPPeriod), TOTPDigits)
+
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	if !ok {
+		t.Fatalf("expected code %s to be valid", code)
+	}
+
+	// Le même code ne doit pas être accepté deux fois
+	if _, ok := ValidateTOTP(secret, code, now, step); ok {
+		t.Fatalf("expected replayed code to be rejected")
+	}
+
+	// Un pas de décalage est toléré, pas deux
+	if _, ok := ValidateTOTP(secret, code, now.Add(TOTPPeriod*time.Second), 0); !ok {
+		t.Errorf("expected code to be valid one step later")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*TOTPPeriod*time.Second), 0); ok {
+		t.Errorf("expected code to be rejected two steps later")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes error: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+		if NormalizeRecoveryCode(" "+code+" ") != code[:5]+code[6:] {
+			t.Errorf("unexpected normalization for %q", code)
+		}
+	}
+}
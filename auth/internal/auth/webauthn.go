@@ -0,0 +1,35 @@
+/*
+Ce fichier configure WebAuthn (passkeys), troisième méthode de connexion
+à côté de Google OAuth et de l’email/mot de passe.
+
+L’identifiant du Relying Party (RP ID) est le domaine du site, sans schéma ni port ;
+les origines autorisées sont celles des pages qui appellent navigator.credentials (le frontend).
+*/
+
+package auth
+
+import (
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"auth/internal/config"
+)
+
+/*
+Crée l’instance WebAuthn à partir de la configuration :
+WEBAUTHN_RP_ID (défaut localhost) et WEBAUTHN_RP_ORIGINS (liste séparée par des virgules,
+défaut FRONTEND_URL).
+Les passkeys sont des credentials découvrables : la connexion ne demande pas d’email.
+*/
+func NewWebAuthn(cfg config.WebAuthn) (*webauthn.WebAuthn, error) {
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: "SmartEther",
+		RPOrigins:     cfg.RPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		},
+	})
+}
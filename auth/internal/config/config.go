@@ -0,0 +1,478 @@
+/*
+Ce paquet regroupe la configuration du service auth dans une structure typée,
+chargée une seule fois au démarrage puis passée aux autres paquets.
+
+Ordre de priorité (du plus faible au plus fort) :
+
+	valeurs par défaut (tags default)
+	fichier YAML ou TOML optionnel (-config ou CONFIG_FILE)
+	variables d’environnement (et .env en développement)
+	NAME_FILE : secret lu depuis un fichier (Docker/Kubernetes secrets)
+
+La configuration est validée avant le démarrage : toutes les erreurs sont signalées ensemble.
+Dump affiche la configuration effective avec les secrets masqués (-print-config).
+*/
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"auth/internal/database/dialect"
+)
+
+// Longueur minimale des clés secrètes (VERIFICATION_CODE_KEY, clés de signature des cookies).
+const MinKeyLength = 32
+
+type Config struct {
+	// production active les contrôles stricts (cookies Secure, clés obligatoires)
+	Env string `env:"APP_ENV" key:"env" default:"development"`
+
+	Server       Server       `env:"" key:"server"`
+	Database     Database     `env:"" key:"database"`
+	Cookies      Cookies      `env:"" key:"cookies"`
+	Sessions     Sessions     `env:"" key:"sessions"`
+	AccessTokens AccessTokens `env:"" key:"access_tokens"`
+	Email        Email        `env:"" key:"email"`
+	WebAuthn     WebAuthn     `env:"" key:"webauthn"`
+	OAuth        OAuth        `env:"" key:"oauth"`
+
+	// Clé HMAC des codes de vérification d’email
+	VerificationCodeKey string `env:"VERIFICATION_CODE_KEY" key:"verification_code_key" secret:"true"`
+	// Compte promu administrateur tant qu’aucun administrateur n’existe
+	AdminBootstrapEmail string `env:"ADMIN_BOOTSTRAP_EMAIL" key:"admin_bootstrap_email"`
+	// Domaine attendu dans les messages Sign-In with Ethereum (défaut : hôte du frontend)
+	SIWEDomain string `env:"SIWE_DOMAIN" key:"siwe_domain"`
+}
+
+type Server struct {
+	Port int `env:"PORT" key:"port" default:"3060"`
+	// URL publique du gateway : callbacks OAuth, redirections après connexion, CORS
+	GatewayURL  string `env:"GATEWAY_URL" key:"gateway_url" default:"http://localhost:8000"`
+	FrontendURL string `env:"FRONTEND_URL" key:"frontend_url" default:"http://localhost:3000"`
+	// Proxys (adresses ou plages CIDR) dont l’en-tête X-Forwarded-For est pris en compte, en général le gateway.
+	// Obligatoire dès que le service est joint via un proxy hors de localhost (conteneur du gateway…) :
+	// sinon tous les clients partagent l’adresse du proxy dans le compteur par IP, et un seul attaquant
+	// qui atteint le verrouillage par IP bloque tout le monde.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" key:"trusted_proxies" default:"127.0.0.1/8,::1/128"`
+}
+
+// Port et utilisateur vides : valeurs par défaut du driver (3306/root ou 5432/postgres).
+type Database struct {
+	Driver   string `env:"BLUEPRINT_DB_DRIVER" key:"driver" default:"mysql"`
+	Host     string `env:"BLUEPRINT_DB_HOST" key:"host" default:"127.0.0.1"`
+	Port     string `env:"BLUEPRINT_DB_PORT" key:"port"`
+	Username string `env:"BLUEPRINT_DB_USERNAME" key:"username"`
+	Password string `env:"BLUEPRINT_DB_PASSWORD" key:"password" secret:"true"`
+	Name     string `env:"BLUEPRINT_DB_DATABASE" key:"name" default:"miniprojet"`
+	SSLMode  string `env:"BLUEPRINT_DB_SSLMODE" key:"sslmode" default:"disable"`
+}
+
+type Cookies struct {
+	// Cookies réservés à HTTPS ; obligatoire en production
+	Secure     bool             `env:"COOKIE_SECURE" key:"secure"`
+	OAuthState OAuthStateCookie `env:"OAUTH_STATE_" key:"oauth_state"`
+}
+
+/*
+Cookie d’état des flux OAuth (gothic), signé et chiffré.
+Trousseau : clés de signature (HMAC-SHA256) et de chiffrement (AES) en base64, appariées par position.
+La première paire protège les nouveaux cookies, toutes les paires sont acceptées en lecture.
+Sans clé hors production, une paire aléatoire est générée au démarrage.
+*/
+type OAuthStateCookie struct {
+	SigningKeys    []string      `env:"SIGNING_KEYS" key:"signing_keys" secret:"true"`
+	EncryptionKeys []string      `env:"ENCRYPTION_KEYS" key:"encryption_keys" secret:"true"`
+	SameSite       string        `env:"SAMESITE" key:"samesite" default:"lax"`
+	TTL            time.Duration `env:"TTL" key:"ttl" default:"15m"`
+}
+
+// Une paire du trousseau, décodée.
+type CookieKeyPair struct {
+	Signing    []byte
+	Encryption []byte
+}
+
+type Sessions struct {
+	AbsoluteTTL           time.Duration `env:"SESSION_TTL" key:"ttl" default:"24h"`
+	IdleTimeout           time.Duration `env:"SESSION_IDLE_TIMEOUT" key:"idle_timeout" default:"2h"`
+	RememberMeTTL         time.Duration `env:"SESSION_REMEMBER_ME_TTL" key:"remember_me_ttl" default:"720h"`
+	RememberMeIdleTimeout time.Duration `env:"SESSION_REMEMBER_ME_IDLE_TIMEOUT" key:"remember_me_idle_timeout" default:"168h"`
+}
+
+type AccessTokens struct {
+	TTL         time.Duration `env:"ACCESS_TOKEN_TTL" key:"ttl" default:"10m"`
+	KeyRotation time.Duration `env:"ACCESS_TOKEN_KEY_ROTATION" key:"key_rotation" default:"168h"`
+	// Émetteur (iss) vérifié par le gateway et les backends (défaut : GATEWAY_URL)
+	Issuer string `env:"ACCESS_TOKEN_ISSUER" key:"issuer"`
+}
+
+type Email struct {
+	// resend, smtp ou file (développement uniquement) ; obligatoire, sans valeur par défaut
+	Backend      string `env:"EMAIL_BACKEND" key:"backend"`
+	From         string `env:"EMAIL_FROM" key:"from" default:"SmartEther <no-reply@smartether.app>"`
+	ResendAPIKey string `env:"ResendAPI" key:"resend_api_key" secret:"true"`
+	SMTP         SMTP   `env:"SMTP_" key:"smtp"`
+	MailboxDir   string `env:"MAILBOX_DIR" key:"mailbox_dir" default:"tmp/mailbox"`
+	// Routes /dev/emails même hors backend fichier (refusé en production)
+	Preview     bool `env:"EMAIL_PREVIEW" key:"preview"`
+	MaxAttempts int  `env:"EMAIL_MAX_ATTEMPTS" key:"max_attempts" default:"8"`
+
+	// Destinataires des signalements ; aucun e-mail n’est envoyé si la liste est vide
+	ReportRecipients []string `env:"REPORT_RECIPIENTS" key:"report_recipients"`
+	ReportFrom       string   `env:"REPORT_FROM" key:"report_from" default:"SmartEther Reports <reports@smartether.app>"`
+}
+
+type SMTP struct {
+	Host     string `env:"HOST" key:"host"`
+	Port     int    `env:"PORT" key:"port" default:"587"`
+	Username string `env:"USERNAME" key:"username"`
+	Password string `env:"PASSWORD" key:"password" secret:"true"`
+}
+
+type WebAuthn struct {
+	RPID string `env:"WEBAUTHN_RP_ID" key:"rp_id" default:"localhost"`
+	// Origines autorisées (défaut : FRONTEND_URL)
+	RPOrigins []string `env:"WEBAUTHN_RP_ORIGINS" key:"rp_origins"`
+}
+
+// Un fournisseur intégré est activé dès que son client est configuré.
+type OAuth struct {
+	Google    OAuthClient     `env:"GOOGLE_" key:"google"`
+	GitHub    OAuthClient     `env:"GITHUB_" key:"github"`
+	Microsoft MicrosoftClient `env:"MICROSOFT_" key:"microsoft"`
+	GitLab    GitLabClient    `env:"GITLAB_" key:"gitlab"`
+	// OIDC_PROVIDERS=keycloak,okta puis OIDC_KEYCLOAK_CLIENT_ID…
+	OIDC []OIDCProvider `env:"OIDC_PROVIDERS" prefix:"OIDC_" key:"oidc"`
+}
+
+type OAuthClient struct {
+	ClientID     string `env:"CLIENT_ID" key:"client_id"`
+	ClientSecret string `env:"CLIENT_SECRET" key:"client_secret" secret:"true"`
+}
+
+type MicrosoftClient struct {
+	ClientID     string `env:"CLIENT_ID" key:"client_id"`
+	ClientSecret string `env:"CLIENT_SECRET" key:"client_secret" secret:"true"`
+	Tenant       string `env:"TENANT" key:"tenant" default:"common"`
+}
+
+type GitLabClient struct {
+	ClientID     string `env:"CLIENT_ID" key:"client_id"`
+	ClientSecret string `env:"CLIENT_SECRET" key:"client_secret" secret:"true"`
+	// Instance auto-hébergée (défaut : gitlab.com)
+	URL string `env:"URL" key:"url"`
+}
+
+type OIDCProvider struct {
+	Name         string `key:"name"`
+	ClientID     string `env:"CLIENT_ID" key:"client_id"`
+	ClientSecret string `env:"CLIENT_SECRET" key:"client_secret" secret:"true"`
+	DiscoveryURL string `env:"DISCOVERY_URL" key:"discovery_url"`
+	DisplayName  string `env:"DISPLAY_NAME" key:"display_name"`
+	// Séparés par des espaces
+	Scopes string `env:"SCOPES" key:"scopes" default:"openid email profile"`
+}
+
+func (c OAuthClient) Enabled() bool     { return c.ClientID != "" }
+func (c MicrosoftClient) Enabled() bool { return c.ClientID != "" }
+func (c GitLabClient) Enabled() bool    { return c.ClientID != "" }
+
+/*
+Charge la configuration (voir l’en-tête du paquet) et la valide.
+path peut être vide : CONFIG_FILE est alors utilisé s’il est défini.
+*/
+func Load(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookup lookupFunc) (*Config, error) {
+	cfg := &Config{}
+	v := reflect.ValueOf(cfg).Elem()
+	setDefaults(v)
+
+	var errs []error
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config file: %v", err)
+		}
+		errs = append(errs, applyFile(v, values, "")...)
+	}
+	errs = append(errs, applyEnv(v, "", lookup)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	cfg.resolve()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Configuration par défaut, sans fichier ni environnement (utilisée aussi par les tests).
+func Defaults() *Config {
+	cfg := &Config{}
+	setDefaults(reflect.ValueOf(cfg).Elem())
+	cfg.resolve()
+	return cfg
+}
+
+func (c *Config) Production() bool {
+	return c.Env == "production"
+}
+
+// Valeurs déduites d’autres réglages quand elles ne sont pas fixées.
+func (c *Config) resolve() {
+	c.Server.GatewayURL = strings.TrimSuffix(c.Server.GatewayURL, "/")
+	c.Server.FrontendURL = strings.TrimSuffix(c.Server.FrontendURL, "/")
+
+	if c.AccessTokens.Issuer == "" {
+		c.AccessTokens.Issuer = c.Server.GatewayURL
+	}
+	if len(c.WebAuthn.RPOrigins) == 0 {
+		c.WebAuthn.RPOrigins = []string{c.Server.FrontendURL}
+	}
+	if c.SIWEDomain == "" {
+		if u, err := url.Parse(c.Server.FrontendURL); err == nil {
+			c.SIWEDomain = u.Host
+		}
+	}
+	c.Email.Backend = strings.ToLower(c.Email.Backend)
+	c.Cookies.OAuthState.SameSite = strings.ToLower(c.Cookies.OAuthState.SameSite)
+	for i := range c.OAuth.OIDC {
+		c.OAuth.OIDC[i].Name = strings.ToLower(c.OAuth.OIDC[i].Name)
+		if c.OAuth.OIDC[i].DisplayName == "" {
+			c.OAuth.OIDC[i].DisplayName = c.OAuth.OIDC[i].Name
+		}
+	}
+}
+
+// Vérifie la cohérence de la configuration ; les messages citent les variables d’environnement.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "PORT must be between 1 and 65535, got %d", c.Server.Port)
+	check(isHTTPURL(c.Server.GatewayURL), "GATEWAY_URL must be an absolute http(s) URL, got %q", c.Server.GatewayURL)
+	check(isHTTPURL(c.Server.FrontendURL), "FRONTEND_URL must be an absolute http(s) URL, got %q", c.Server.FrontendURL)
+	if _, err := c.Server.TrustedProxyPrefixes(); err != nil {
+		errs = append(errs, err)
+	}
+
+	_, err := dialect.Parse(c.Database.Driver)
+	check(err == nil, "BLUEPRINT_DB_DRIVER: %v", err)
+	check(c.Database.Host != "", "BLUEPRINT_DB_HOST must be set")
+	check(c.Database.Name != "", "BLUEPRINT_DB_DATABASE must be set")
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"SESSION_TTL", c.Sessions.AbsoluteTTL},
+		{"SESSION_IDLE_TIMEOUT", c.Sessions.IdleTimeout},
+		{"SESSION_REMEMBER_ME_TTL", c.Sessions.RememberMeTTL},
+		{"SESSION_REMEMBER_ME_IDLE_TIMEOUT", c.Sessions.RememberMeIdleTimeout},
+		{"ACCESS_TOKEN_TTL", c.AccessTokens.TTL},
+		{"ACCESS_TOKEN_KEY_ROTATION", c.AccessTokens.KeyRotation},
+		{"OAUTH_STATE_TTL", c.Cookies.OAuthState.TTL},
+	} {
+		check(d.value > 0, "%s must be a positive duration, got %s", d.name, d.value)
+	}
+	check(c.AccessTokens.Issuer != "", "ACCESS_TOKEN_ISSUER must be set")
+
+	switch c.Email.Backend {
+	case "resend":
+		check(c.Email.ResendAPIKey != "", "ResendAPI must be set for the resend email backend")
+	case "smtp":
+		check(c.Email.SMTP.Host != "", "SMTP_HOST must be set for the smtp email backend")
+		check(c.Email.SMTP.Port > 0 && c.Email.SMTP.Port < 65536, "SMTP_PORT must be between 1 and 65535, got %d", c.Email.SMTP.Port)
+	case "file":
+		check(!c.Production(), "EMAIL_BACKEND=file is not allowed when APP_ENV=production")
+		check(c.Email.MailboxDir != "", "MAILBOX_DIR must be set for the file email backend")
+	case "":
+		errs = append(errs, fmt.Errorf("EMAIL_BACKEND must be set to resend, smtp or file"))
+	default:
+		errs = append(errs, fmt.Errorf("EMAIL_BACKEND must be resend, smtp or file, got %q", c.Email.Backend))
+	}
+	check(isAddress(c.Email.From), "EMAIL_FROM is not a valid address: %q", c.Email.From)
+	check(isAddress(c.Email.ReportFrom), "REPORT_FROM is not a valid address: %q", c.Email.ReportFrom)
+	for _, recipient := range c.Email.ReportRecipients {
+		check(isAddress(recipient), "REPORT_RECIPIENTS: %q is not a valid address", recipient)
+	}
+	if c.Production() {
+		check(!c.Email.Preview, "EMAIL_PREVIEW is not allowed when APP_ENV=production")
+	}
+	check(c.Email.MaxAttempts > 0, "EMAIL_MAX_ATTEMPTS must be positive, got %d", c.Email.MaxAttempts)
+	if c.AdminBootstrapEmail != "" {
+		check(isAddress(c.AdminBootstrapEmail), "ADMIN_BOOTSTRAP_EMAIL is not a valid address: %q", c.AdminBootstrapEmail)
+	}
+
+	check(c.WebAuthn.RPID != "", "WEBAUTHN_RP_ID must be set")
+	for _, origin := range c.WebAuthn.RPOrigins {
+		check(isHTTPURL(origin), "WEBAUTHN_RP_ORIGINS: %q is not an absolute http(s) URL", origin)
+	}
+	check(c.SIWEDomain != "", "SIWE_DOMAIN must be set")
+
+	errs = append(errs, checkClient("GOOGLE", c.OAuth.Google.ClientID, c.OAuth.Google.ClientSecret)...)
+	errs = append(errs, checkClient("GITHUB", c.OAuth.GitHub.ClientID, c.OAuth.GitHub.ClientSecret)...)
+	errs = append(errs, checkClient("MICROSOFT", c.OAuth.Microsoft.ClientID, c.OAuth.Microsoft.ClientSecret)...)
+	errs = append(errs, checkClient("GITLAB", c.OAuth.GitLab.ClientID, c.OAuth.GitLab.ClientSecret)...)
+	if c.OAuth.GitLab.URL != "" {
+		check(isHTTPURL(c.OAuth.GitLab.URL), "GITLAB_URL must be an absolute http(s) URL, got %q", c.OAuth.GitLab.URL)
+	}
+	for _, p := range c.OAuth.OIDC {
+		prefix := "OIDC_" + envName(p.Name) + "_"
+		check(p.Name != "", "OIDC providers must have a name")
+		check(p.ClientID != "" && p.ClientSecret != "", "%sCLIENT_ID and %sCLIENT_SECRET must be set", prefix, prefix)
+		check(isHTTPURL(p.DiscoveryURL), "%sDISCOVERY_URL must be an absolute http(s) URL, got %q", prefix, p.DiscoveryURL)
+	}
+
+	keys, err := c.Cookies.OAuthState.KeyPairs()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if c.Production() {
+		check(len(keys) > 0 || err != nil, "OAUTH_STATE_SIGNING_KEYS and OAUTH_STATE_ENCRYPTION_KEYS must be set when APP_ENV=production")
+	}
+	switch c.Cookies.OAuthState.SameSite {
+	case "lax":
+	case "none":
+		check(c.Cookies.Secure, "OAUTH_STATE_SAMESITE=none requires COOKIE_SECURE=true")
+	case "strict":
+		errs = append(errs, fmt.Errorf("OAUTH_STATE_SAMESITE=strict is not supported: the cookie would not be sent back on the provider redirect"))
+	default:
+		errs = append(errs, fmt.Errorf("OAUTH_STATE_SAMESITE must be lax or none, got %q", c.Cookies.OAuthState.SameSite))
+	}
+
+	errs = append(errs, checkKey("VERIFICATION_CODE_KEY", c.VerificationCodeKey, c.Production())...)
+	if c.Production() {
+		check(c.Cookies.Secure, "COOKIE_SECURE must be true when APP_ENV=production")
+	}
+
+	return errors.Join(errs...)
+}
+
+/*
+Écrit la configuration effective au format NAME=valeur ;
+les secrets définis sont remplacés par [redacted].
+*/
+func (c *Config) Dump(w io.Writer) {
+	dump(w, reflect.ValueOf(c).Elem(), "")
+}
+
+/*
+Décode le trousseau, clé courante en premier.
+Les clés de signature font au moins 32 octets, les clés de chiffrement 16, 24 ou 32 (AES).
+*/
+func (c OAuthStateCookie) KeyPairs() ([]CookieKeyPair, error) {
+	if len(c.SigningKeys) != len(c.EncryptionKeys) {
+		return nil, fmt.Errorf("OAUTH_STATE_SIGNING_KEYS and OAUTH_STATE_ENCRYPTION_KEYS must have the same number of keys (%d and %d)",
+			len(c.SigningKeys), len(c.EncryptionKeys))
+	}
+
+	var errs []error
+	pairs := make([]CookieKeyPair, len(c.SigningKeys))
+	for i := range pairs {
+		signing, err := base64.StdEncoding.DecodeString(c.SigningKeys[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OAUTH_STATE_SIGNING_KEYS[%d] is not valid base64", i))
+		} else if len(signing) < MinKeyLength {
+			errs = append(errs, fmt.Errorf("OAUTH_STATE_SIGNING_KEYS[%d] must decode to at least %d bytes, got %d", i, MinKeyLength, len(signing)))
+		}
+
+		encryption, err := base64.StdEncoding.DecodeString(c.EncryptionKeys[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OAUTH_STATE_ENCRYPTION_KEYS[%d] is not valid base64", i))
+		} else if n := len(encryption); n != 16 && n != 24 && n != 32 {
+			errs = append(errs, fmt.Errorf("OAUTH_STATE_ENCRYPTION_KEYS[%d] must decode to 16, 24 or 32 bytes, got %d", i, n))
+		}
+
+		pairs[i] = CookieKeyPair{Signing: signing, Encryption: encryption}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return pairs, nil
+}
+
+// Plages de TRUSTED_PROXIES ; une adresse seule vaut une plage /32 (ou /128).
+func (s Server) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	var errs []error
+	prefixes := make([]netip.Prefix, 0, len(s.TrustedProxies))
+	for _, entry := range s.TrustedProxies {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or CIDR range", entry))
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, errors.Join(errs...)
+}
+
+// Attribut SameSite du cookie d’état (valeur validée : lax ou none).
+func (c OAuthStateCookie) SameSiteMode() http.SameSite {
+	if c.SameSite == "none" {
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+// Un client OAuth a besoin de son identifiant et de son secret, ou d’aucun des deux.
+func checkClient(prefix, clientID, clientSecret string) []error {
+	if (clientID == "") != (clientSecret == "") {
+		return []error{fmt.Errorf("%s_CLIENT_ID and %s_CLIENT_SECRET must be set together", prefix, prefix)}
+	}
+	return nil
+}
+
+// Une clé trop courte est refusée ; en production, la clé est obligatoire.
+func checkKey(name, key string, required bool) []error {
+	switch {
+	case key == "" && required:
+		return []error{fmt.Errorf("%s must be set when APP_ENV=production", name)}
+	case key != "" && len(key) < MinKeyLength:
+		return []error{fmt.Errorf("%s must be at least %d characters long", name, MinKeyLength)}
+	}
+	return nil
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isAddress(value string) bool {
+	_, err := mail.ParseAddress(value)
+	return err == nil
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Environnement de test ; EMAIL_BACKEND, obligatoire, vaut file sauf s’il est fourni.
+func env(values map[string]string) lookupFunc {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		if !ok && name == "EMAIL_BACKEND" {
+			return "file", true
+		}
+		return value, ok
+	}
+}
+
+var (
+	key64 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("s", 64)))
+	key32 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("e", 32)))
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	cfg := Defaults()
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "EMAIL_BACKEND must be set") {
+		t.Fatalf("EMAIL_BACKEND has no default, got %v", err)
+	}
+	cfg.Email.Backend = "file"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default configuration should be valid: %v", err)
+	}
+	if cfg.AccessTokens.Issuer != "http://localhost:8000" || cfg.SIWEDomain != "localhost:3000" {
+		t.Errorf("unexpected derived defaults: %+v", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "auth.yaml", `
+server:
+  port: 4000
+  gateway_url: https://api.example.com/
+sessions:
+  ttl: 12h
+email:
+  report_recipients: [mod@example.com, admin@example.com]
+`)
+	secret := writeFile(t, "code_key", strings.Repeat("k", 32)+"\n")
+
+	cfg, err := load(file, env(map[string]string{
+		"PORT":                       "5000",
+		"VERIFICATION_CODE_KEY_FILE": secret,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Port != 5000 {
+		t.Errorf("environment should override the file, got port %d", cfg.Server.Port)
+	}
+	if cfg.Server.GatewayURL != "https://api.example.com" || cfg.AccessTokens.Issuer != "https://api.example.com" {
+		t.Errorf("gateway URL and issuer should come from the file, got %q and %q", cfg.Server.GatewayURL, cfg.AccessTokens.Issuer)
+	}
+	if cfg.Sessions.AbsoluteTTL != 12*time.Hour || cfg.Sessions.IdleTimeout != 2*time.Hour {
+		t.Errorf("unexpected session durations: %+v", cfg.Sessions)
+	}
+	if len(cfg.Email.ReportRecipients) != 2 {
+		t.Errorf("expected two report recipients, got %v", cfg.Email.ReportRecipients)
+	}
+	if cfg.VerificationCodeKey != strings.Repeat("k", 32) {
+		t.Errorf("VERIFICATION_CODE_KEY_FILE should be read without the trailing newline, got %q", cfg.VerificationCodeKey)
+	}
+}
+
+func TestLoadOIDCProvidersFromTOML(t *testing.T) {
+	file := writeFile(t, "auth.toml", `
+[[oauth.oidc]]
+name = "keycloak"
+client_id = "auth-service"
+discovery_url = "https://sso.example.com/.well-known/openid-configuration"
+`)
+
+	cfg, err := load(file, env(map[string]string{"OIDC_KEYCLOAK_CLIENT_SECRET": "s3cret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.OAuth.OIDC) != 1 {
+		t.Fatalf("expected one OIDC provider, got %+v", cfg.OAuth.OIDC)
+	}
+	p := cfg.OAuth.OIDC[0]
+	if p.ClientSecret != "s3cret" || p.Scopes != "openid email profile" || p.DisplayName != "keycloak" {
+		t.Errorf("unexpected provider: %+v", p)
+	}
+
+	// OIDC_PROVIDERS remplace la liste du fichier
+	cfg, err = load(file, env(map[string]string{
+		"OIDC_PROVIDERS":              "okta",
+		"OIDC_OKTA_CLIENT_ID":         "id",
+		"OIDC_OKTA_CLIENT_SECRET":     "secret",
+		"OIDC_OKTA_DISCOVERY_URL":     "https://okta.example.com/.well-known/openid-configuration",
+		"OIDC_KEYCLOAK_CLIENT_SECRET": "s3cret",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.OAuth.OIDC) != 1 || cfg.OAuth.OIDC[0].Name != "okta" {
+		t.Errorf("expected only okta, got %+v", cfg.OAuth.OIDC)
+	}
+}
+
+func TestOAuthStateKeyPairs(t *testing.T) {
+	cfg, err := load("", env(map[string]string{
+		"OAUTH_STATE_SIGNING_KEYS":    key64 + ", " + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32))),
+		"OAUTH_STATE_ENCRYPTION_KEYS": key32 + ", " + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("p", 16))),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, err := cfg.Cookies.OAuthState.KeyPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 || string(pairs[0].Signing) != strings.Repeat("s", 64) || len(pairs[1].Encryption) != 16 {
+		t.Errorf("unexpected key pairs: %+v", pairs)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "unknown file key",
+			file: "server:\n  prot: 4000\n",
+			want: []string{`unknown key "server.prot"`},
+		},
+		{
+			name: "invalid values",
+			env:  map[string]string{"PORT": "http", "SESSION_TTL": "forever"},
+			want: []string{`PORT: invalid value "http"`, `SESSION_TTL: invalid value "forever"`},
+		},
+		{
+			name: "value and file",
+			env:  map[string]string{"ResendAPI": "re_123", "ResendAPI_FILE": "/run/secrets/resend"},
+			want: []string{"ResendAPI and ResendAPI_FILE are both set"},
+		},
+		{
+			name: "validation",
+			env: map[string]string{
+				"GATEWAY_URL":           "localhost:8000",
+				"EMAIL_BACKEND":         "smtp",
+				"GOOGLE_CLIENT_ID":      "id",
+				"VERIFICATION_CODE_KEY": "short",
+			},
+			want: []string{
+				"GATEWAY_URL must be an absolute http(s) URL",
+				"SMTP_HOST must be set",
+				"GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET must be set together",
+				"VERIFICATION_CODE_KEY must be at least 32 characters long",
+			},
+		},
+		{
+			name: "oauth state cookie",
+			env: map[string]string{
+				"OAUTH_STATE_SIGNING_KEYS":    "c2hvcnQ=," + key64,
+				"OAUTH_STATE_ENCRYPTION_KEYS": "not base64!," + key32,
+				"OAUTH_STATE_SAMESITE":        "none",
+			},
+			want: []string{
+				"OAUTH_STATE_SIGNING_KEYS[0] must decode to at least 32 bytes, got 5",
+				"OAUTH_STATE_ENCRYPTION_KEYS[0] is not valid base64",
+				"OAUTH_STATE_SAMESITE=none requires COOKIE_SECURE=true",
+			},
+		},
+		{
+			name: "oauth state key count",
+			env: map[string]string{
+				"OAUTH_STATE_SIGNING_KEYS":    key64 + "," + key64,
+				"OAUTH_STATE_ENCRYPTION_KEYS": key32,
+			},
+			want: []string{"must have the same number of keys (2 and 1)"},
+		},
+		{
+			name: "production",
+			env:  map[string]string{"APP_ENV": "production", "EMAIL_PREVIEW": "true"},
+			want: []string{
+				"OAUTH_STATE_SIGNING_KEYS and OAUTH_STATE_ENCRYPTION_KEYS must be set",
+				"VERIFICATION_CODE_KEY must be set",
+				"COOKIE_SECURE must be true",
+				"EMAIL_BACKEND=file is not allowed when APP_ENV=production",
+				"EMAIL_PREVIEW is not allowed when APP_ENV=production",
+			},
+		},
+		{
+			name: "trusted proxies",
+			env:  map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,gateway"},
+			want: []string{`TRUSTED_PROXIES: "gateway" is not an IP address or CIDR range`},
+		},
+		{
+			name: "email backend not set",
+			env:  map[string]string{"EMAIL_BACKEND": "", "ResendAPI": "re_test"},
+			want: []string{"EMAIL_BACKEND must be set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			if tt.file != "" {
+				path = writeFile(t, "auth.yaml", tt.file)
+			}
+			_, err := load(path, env(tt.env))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error should mention %q, got:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	cfg, err := load("", env(map[string]string{
+		"BLUEPRINT_DB_PASSWORD":       "db-password",
+		"GITHUB_CLIENT_ID":            "github-id",
+		"GITHUB_CLIENT_SECRET":        "github-secret",
+		"SMTP_PASSWORD":               "smtp-password",
+		"OAUTH_STATE_SIGNING_KEYS":    key64,
+		"OAUTH_STATE_ENCRYPTION_KEYS": key32,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cfg.Dump(&out)
+	dump := out.String()
+
+	for _, secret := range []string{"db-password", "github-secret", "smtp-password", key64, key32} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump should not contain %q", secret)
+		}
+	}
+	for _, line := range []string{
+		"BLUEPRINT_DB_PASSWORD=[redacted]",
+		"GITHUB_CLIENT_ID=github-id",
+		"GITHUB_CLIENT_SECRET=[redacted]",
+		"SMTP_PORT=587",
+		"OAUTH_STATE_SIGNING_KEYS=[redacted]",
+		"VERIFICATION_CODE_KEY=\n",
+		"SESSION_TTL=24h0m0s",
+	} {
+		if !strings.Contains(dump, line) {
+			t.Errorf("dump should contain %q, got:\n%s", line, dump)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+/*
+Chargement générique d’une structure de configuration, piloté par les tags des champs :
+
+	env:"NAME"        variable d’environnement ; sur une structure imbriquée, préfixe de ses champs
+	key:"name"        clé dans le fichier YAML/TOML
+	default:"value"   valeur par défaut (même format que la variable)
+	secret:"true"     masqué par Dump
+	prefix:"OIDC_"    liste de structures : les noms viennent de la variable env,
+	                  chaque élément est lu avec le préfixe OIDC_<NOM>_
+
+Types supportés : string, bool, int, time.Duration, []string (séparé par des virgules)
+et []struct (éléments nommés par leur champ Name).
+
+Chaque variable peut aussi être lue depuis un fichier : NAME_FILE=/run/secrets/name
+(contenu sans les espaces de fin), mais pas les deux à la fois.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Lit une variable d’environnement ; remplacée dans les tests.
+type lookupFunc func(name string) (string, bool)
+
+// Applique les valeurs par défaut des tags.
+func setDefaults(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		switch {
+		case isSection(f.Type):
+			setDefaults(fv)
+		case f.Tag.Get("default") != "":
+			if err := setValue(fv, f.Tag.Get("default")); err != nil {
+				panic(fmt.Sprintf("config: invalid default for %s: %v", f.Name, err))
+			}
+		}
+	}
+}
+
+// Lit un fichier YAML (.yaml, .yml) ou TOML (.toml) sous forme de table.
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q (expected .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return values, nil
+}
+
+// Applique les valeurs du fichier ; une clé inconnue est une erreur (faute de frappe probable).
+func applyFile(v reflect.Value, values map[string]interface{}, path string) []error {
+	fields := map[string]int{}
+	for i := 0; i < v.NumField(); i++ {
+		if key := v.Type().Field(i).Tag.Get("key"); key != "" {
+			fields[key] = i
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		name := path + key
+		i, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file: unknown key %q", name))
+			continue
+		}
+		f, fv, value := v.Type().Field(i), v.Field(i), values[key]
+
+		switch {
+		case isSection(f.Type):
+			table, ok := value.(map[string]interface{})
+			if !ok {
+				errs = append(errs, fmt.Errorf("config file: %s must be a table", name))
+				continue
+			}
+			errs = append(errs, applyFile(fv, table, name+".")...)
+
+		case isList(f.Type):
+			tables, ok := tableList(value)
+			if !ok {
+				errs = append(errs, fmt.Errorf("config file: %s must be a list of tables", name))
+				continue
+			}
+			fv.Set(reflect.MakeSlice(f.Type, 0, len(tables)))
+			for n, table := range tables {
+				elem := reflect.New(f.Type.Elem()).Elem()
+				setDefaults(elem)
+				errs = append(errs, applyFile(elem, table, fmt.Sprintf("%s[%d].", name, n))...)
+				fv.Set(reflect.Append(fv, elem))
+			}
+
+		default:
+			if err := setFileValue(fv, value); err != nil {
+				errs = append(errs, fmt.Errorf("config file: %s: %v", name, err))
+			}
+		}
+	}
+	return errs
+}
+
+/*
+Applique les variables d’environnement (et les NAME_FILE), prioritaires sur le fichier.
+Une variable vide est ignorée, comme si elle n’était pas définie.
+*/
+func applyEnv(v reflect.Value, prefix string, lookup lookupFunc) []error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		env, ok := f.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+
+		switch {
+		case isSection(f.Type):
+			errs = append(errs, applyEnv(fv, prefix+env, lookup)...)
+
+		case isList(f.Type):
+			names, err := envValue(prefix+env, lookup)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if names != "" {
+				fv.Set(namedList(fv, splitList(names)))
+			}
+			for n := 0; n < fv.Len(); n++ {
+				elem := fv.Index(n)
+				errs = append(errs, applyEnv(elem, prefix+f.Tag.Get("prefix")+envName(elemName(elem))+"_", lookup)...)
+			}
+
+		default:
+			value, err := envValue(prefix+env, lookup)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if value == "" {
+				continue
+			}
+			if err := setValue(fv, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid value %q: %v", prefix+env, value, err))
+			}
+		}
+	}
+	return errs
+}
+
+// Valeur de name, ou contenu du fichier désigné par name_FILE.
+func envValue(name string, lookup lookupFunc) (string, error) {
+	value, _ := lookup(name)
+	path, _ := lookup(name + "_FILE")
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("%s and %s_FILE are both set", name, name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %v", name, err)
+	}
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
+
+/*
+Liste dans l’ordre des noms donnés ; les éléments déjà lus dans le fichier sont repris,
+les autres sont créés avec leurs valeurs par défaut.
+*/
+func namedList(list reflect.Value, names []string) reflect.Value {
+	result := reflect.MakeSlice(list.Type(), 0, len(names))
+	for _, name := range names {
+		elem := reflect.New(list.Type().Elem()).Elem()
+		setDefaults(elem)
+		for n := 0; n < list.Len(); n++ {
+			if elemName(list.Index(n)) == name {
+				elem.Set(list.Index(n))
+			}
+		}
+		elem.FieldByName("Name").SetString(name)
+		result = reflect.Append(result, elem)
+	}
+	return result
+}
+
+func setValue(fv reflect.Value, value string) error {
+	switch {
+	case fv.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+	case fv.Kind() == reflect.String:
+		fv.SetString(value)
+	case fv.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		fv.SetBool(b)
+	case fv.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		fv.SetInt(int64(n))
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		fv.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// Valeur lue dans le fichier : une liste YAML/TOML ou un scalaire au format des variables.
+func setFileValue(fv reflect.Value, value interface{}) error {
+	if items, ok := value.([]interface{}); ok && fv.Kind() == reflect.Slice {
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			list = append(list, fmt.Sprint(item))
+		}
+		fv.Set(reflect.ValueOf(list))
+		return nil
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}, []map[string]interface{}:
+		return fmt.Errorf("expected a single value")
+	}
+	return setValue(fv, fmt.Sprint(value))
+}
+
+/*
+Écrit la configuration effective au format NAME=valeur, secrets masqués.
+Les champs sans variable d’environnement ne sont pas affichés.
+*/
+func dump(w io.Writer, v reflect.Value, prefix string) {
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		env, ok := f.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+
+		switch {
+		case isSection(f.Type):
+			dump(w, fv, prefix+env)
+		case isList(f.Type):
+			names := make([]string, fv.Len())
+			for n := range names {
+				names[n] = elemName(fv.Index(n))
+			}
+			fmt.Fprintf(w, "%s=%s\n", prefix+env, strings.Join(names, ","))
+			for n := 0; n < fv.Len(); n++ {
+				dump(w, fv.Index(n), prefix+f.Tag.Get("prefix")+envName(names[n])+"_")
+			}
+		default:
+			value := formatValue(fv)
+			if f.Tag.Get("secret") == "true" && value != "" {
+				value = redacted
+			}
+			fmt.Fprintf(w, "%s=%s\n", prefix+env, value)
+		}
+	}
+}
+
+func formatValue(fv reflect.Value) string {
+	switch {
+	case fv.Type() == durationType:
+		return time.Duration(fv.Int()).String()
+	case fv.Kind() == reflect.Slice:
+		return strings.Join(fv.Interface().([]string), ",")
+	}
+	return fmt.Sprint(fv.Interface())
+}
+
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct
+}
+
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct
+}
+
+func elemName(elem reflect.Value) string {
+	return elem.FieldByName("Name").String()
+}
+
+// Les deux formes de listes de tables (YAML : []interface{}, TOML : []map[string]interface{}).
+func tableList(value interface{}) ([]map[string]interface{}, bool) {
+	if tables, ok := value.([]map[string]interface{}); ok {
+		return tables, true
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	tables := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		table, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		tables = append(tables, table)
+	}
+	return tables, true
+}
+
+// "keycloak-eu" -> "KEYCLOAK_EU"
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
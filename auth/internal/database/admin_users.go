@@ -0,0 +1,189 @@
+/*
+Ce fichier regroupe les opérations d’administration des comptes :
+
+lister et filtrer les utilisateurs,
+
+suspendre jusqu’à une date, bannir définitivement ou rétablir un compte
+(les sessions du compte sont supprimées dans la même transaction),
+
+marquer un email comme vérifié.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+)
+
+// Compte vu par un administrateur.
+type AdminUser struct {
+	ID               int64      `json:"id"`
+	Email            string     `json:"email"`
+	Name             string     `json:"name"`
+	AvatarURL        string     `json:"avatar"`
+	Verified         bool       `json:"verified"`
+	HasPassword      bool       `json:"has_password"`
+	CreatedAt        time.Time  `json:"created_at"`
+	Status           string     `json:"status"`
+	SuspendedUntil   *time.Time `json:"suspended_until"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+}
+
+// Filtres de la liste des utilisateurs (valeurs vides : pas de filtre).
+type UserFilter struct {
+	Query    string // recherche dans l’email et le nom
+	Status   string // active, suspended ou banned
+	Verified *bool
+	Limit    int
+	Offset   int
+}
+
+const adminUserStatus = `CASE
+	WHEN u.banned = TRUE THEN 'banned'
+	WHEN u.suspended_until > NOW() THEN 'suspended'
+	ELSE 'active' END`
+
+const adminUserWhere = `
+	WHERE (? = '' OR LOWER(u.email) LIKE LOWER(?) OR LOWER(u.name) LIKE LOWER(?))
+	  AND (? = '' OR ` + adminUserStatus + ` = ?)
+	  AND (NOT ? OR u.verified = ?)`
+
+// Le filtre verified est passé en deux paramètres typés (actif, valeur) : PostgreSQL ne sait pas typer « ? IS NULL ».
+func (f UserFilter) args() []interface{} {
+	like := "%" + f.Query + "%"
+	verified := f.Verified != nil && *f.Verified
+	return []interface{}{f.Query, like, like, f.Status, f.Status, f.Verified != nil, verified}
+}
+
+// Utilisateurs correspondant au filtre, les plus récents en premier, et leur nombre total.
+func (s Service) ListUsers(filter UserFilter) ([]AdminUser, int, error) {
+	var total int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM users u"+adminUserWhere, filter.args()...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.DB.Query(
+		`SELECT u.id, u.email, u.name, u.picture, u.verified, u.password IS NOT NULL, u.created_at,
+		   `+adminUserStatus+`, u.suspended_until, u.suspension_reason
+		 FROM users u`+adminUserWhere+`
+		 ORDER BY u.created_at DESC, u.id DESC
+		 LIMIT ? OFFSET ?`,
+		append(filter.args(), filter.Limit, filter.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []AdminUser{}
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *user)
+	}
+	return users, total, rows.Err()
+}
+
+func (s Service) GetAdminUser(userID int) (*AdminUser, error) {
+	return scanAdminUser(s.DB.QueryRow(
+		`SELECT u.id, u.email, u.name, u.picture, u.verified, u.password IS NOT NULL, u.created_at,
+		   `+adminUserStatus+`, u.suspended_until, u.suspension_reason
+		 FROM users u WHERE u.id = ?`,
+		userID,
+	))
+}
+
+func scanAdminUser(row interface{ Scan(...interface{}) error }) (*AdminUser, error) {
+	var user AdminUser
+	var email, name, picture, reason sql.NullString
+	var createdAt, suspendedUntil sql.NullTime
+	if err := row.Scan(&user.ID, &email, &name, &picture, &user.Verified, &user.HasPassword, &createdAt,
+		&user.Status, &suspendedUntil, &reason); err != nil {
+		return nil, err
+	}
+	user.Email = email.String
+	user.Name = name.String
+	user.AvatarURL = picture.String
+	user.CreatedAt = createdAt.Time
+	user.SuspensionReason = reason.String
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
+	return &user, nil
+}
+
+/*
+Suspend (until renseigné) ou bannit (banned) un compte et supprime ses sessions.
+Retourne le nombre de sessions supprimées.
+*/
+func (s Service) SuspendUser(userID int, until *time.Time, banned bool, reason string) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE users SET suspended_until = ?, banned = ?, suspension_reason = NULLIF(?, '') WHERE id = ?",
+		until, banned, reason, userID,
+	); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	revoked, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return revoked, tx.Commit()
+}
+
+// Lève une suspension ou un bannissement.
+func (s Service) ReinstateUser(userID int) error {
+	_, err := s.DB.Exec(
+		"UPDATE users SET suspended_until = NULL, banned = FALSE, suspension_reason = NULL WHERE id = ?",
+		userID,
+	)
+	return err
+}
+
+// Déconnecte le compte de tous ses appareils ; retourne le nombre de sessions supprimées.
+func (s Service) RevokeAllUserSessions(userID int) (int64, error) {
+	res, err := s.DB.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Marque l’email d’un compte comme vérifié et invalide ses codes de vérification en attente.
+func (s Service) MarkUserVerifiedByID(userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET verified = TRUE WHERE id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM verification_codes WHERE email = (SELECT email FROM users WHERE id = ?)",
+		userID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
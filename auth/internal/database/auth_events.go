@@ -0,0 +1,170 @@
+/*
+Ce fichier gère le journal d’audit de l’authentification (table auth_events).
+
+Le journal est en ajout seul : ce fichier n’expose ni mise à jour ni suppression,
+et les triggers créés par les migrations refusent UPDATE et DELETE sur la table.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Types d’événements enregistrés.
+const (
+	EventRegister                = "register"
+	EventLogin                   = "login"
+	EventLogout                  = "logout"
+	EventEmailVerify             = "email.verify"
+	EventVerificationResend      = "email.resend_code"
+	EventPasswordResetRequest    = "password.reset_request"
+	EventPasswordReset           = "password.reset"
+	EventPasswordChange          = "password.change"
+	EventTwoFactorEnable         = "2fa.enable"
+	EventTwoFactorDisable        = "2fa.disable"
+	EventRecoveryCodesRegenerate = "2fa.recovery_codes_regenerate"
+	EventSessionRevoke           = "session.revoke"
+	EventIdentityLink            = "identity.link"
+	EventIdentityUnlink          = "identity.unlink"
+	EventPasskeyRegister         = "passkey.register"
+	EventPasskeyDelete           = "passkey.delete"
+	EventWalletLink              = "wallet.link"
+	EventWalletUnlink            = "wallet.unlink"
+	EventOAuthConsentGrant       = "oauth.consent_grant"
+	EventOAuthConsentRevoke      = "oauth.consent_revoke"
+	EventReportSubmit            = "report.submit"
+	EventAdminSuspendUser        = "admin.user_suspend"
+	EventAdminBanUser            = "admin.user_ban"
+	EventAdminReinstateUser      = "admin.user_reinstate"
+	EventAdminLogoutUser         = "admin.user_logout"
+	EventAdminVerifyEmail        = "admin.user_verify_email"
+	EventAdminGrantRole          = "admin.role_grant"
+	EventAdminRevokeRole         = "admin.role_revoke"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+type AuthEvent struct {
+	ID        int64                  `json:"id"`
+	ActorID   *int64                 `json:"actor_id"`
+	UserID    *int64                 `json:"user_id"`
+	Email     string                 `json:"email,omitempty"`
+	Event     string                 `json:"event"`
+	Outcome   string                 `json:"outcome"`
+	IPAddress string                 `json:"ip_address"`
+	UserAgent string                 `json:"user_agent"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Filtres du journal (valeurs vides : pas de filtre).
+type AuthEventFilter struct {
+	UserID    int64
+	ActorID   int64
+	Event     string
+	Outcome   string
+	IPAddress string
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+	Offset    int
+}
+
+func (s Service) RecordAuthEvent(event AuthEvent) error {
+	var metadata interface{}
+	if len(event.Metadata) > 0 {
+		raw, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(raw)
+	}
+	if len(event.UserAgent) > 255 {
+		event.UserAgent = event.UserAgent[:255]
+	}
+	if len(event.Email) > 100 {
+		event.Email = event.Email[:100]
+	}
+
+	_, err := s.DB.Exec(
+		`INSERT INTO auth_events (actor_id, user_id, email, event, outcome, ip_address, user_agent, metadata)
+		 VALUES (?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)`,
+		event.ActorID, event.UserID, event.Email, event.Event, event.Outcome,
+		event.IPAddress, event.UserAgent, metadata,
+	)
+	return err
+}
+
+// Événements correspondant au filtre, les plus récents en premier.
+func (s Service) ListAuthEvents(filter AuthEventFilter) ([]AuthEvent, error) {
+	// Bornes passées en deux paramètres typés (active, date) : PostgreSQL ne sait pas typer « ? IS NULL »
+	var since, until time.Time
+	if filter.Since != nil {
+		since = filter.Since.UTC()
+	}
+	if filter.Until != nil {
+		until = filter.Until.UTC()
+	}
+
+	rows, err := s.DB.Query(
+		`SELECT id, actor_id, user_id, email, event, outcome, ip_address, user_agent, metadata, created_at
+		 FROM auth_events
+		 WHERE (? = 0 OR user_id = ?)
+		   AND (? = 0 OR actor_id = ?)
+		   AND (? = '' OR event = ?)
+		   AND (? = '' OR outcome = ?)
+		   AND (? = '' OR ip_address = ?)
+		   AND (NOT ? OR created_at >= ?)
+		   AND (NOT ? OR created_at < ?)
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT ? OFFSET ?`,
+		filter.UserID, filter.UserID,
+		filter.ActorID, filter.ActorID,
+		filter.Event, filter.Event,
+		filter.Outcome, filter.Outcome,
+		filter.IPAddress, filter.IPAddress,
+		filter.Since != nil, since,
+		filter.Until != nil, until,
+		filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []AuthEvent{}
+	for rows.Next() {
+		var (
+			event            AuthEvent
+			actorID, userID  sql.NullInt64
+			email, ip, agent sql.NullString
+			metadata         sql.NullString
+		)
+		if err := rows.Scan(&event.ID, &actorID, &userID, &email, &event.Event, &event.Outcome,
+			&ip, &agent, &metadata, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if actorID.Valid {
+			event.ActorID = &actorID.Int64
+		}
+		if userID.Valid {
+			event.UserID = &userID.Int64
+		}
+		event.Email = email.String
+		event.IPAddress = ip.String
+		event.UserAgent = agent.String
+		if metadata.Valid {
+			if err := json.Unmarshal([]byte(metadata.String), &event.Metadata); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -2,11 +2,11 @@
 Ce fichier définit la couche d’accès à la base de données pour le microservice auth.
 Il :
 
-Initialise la connexion MySQL.
+Initialise la connexion MySQL ou PostgreSQL (BLUEPRINT_DB_DRIVER) et applique les migrations du schéma (voir migrations/).
 
 Gère toutes les opérations CRUD liées aux utilisateurs, sessions et codes de vérification.
 
-Fournit des fonctions pour l’authentification (Google et email/mot de passe).
+Fournit des fonctions pour l’authentification (fournisseurs OAuth et email/mot de passe).
 */
 
 package database
@@ -16,28 +16,48 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"net/url"
 	"time"
 
+	"auth/internal/config"
+	"auth/internal/database/dialect"
+	"auth/internal/database/migrations"
+	"auth/internal/mailer"
+
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // Structure principale contenant une instance de la base de données.
 type Service struct {
-	DB *sql.DB
+	DB *DB
+
+	// Durées de vie des sessions (absolue, inactivité, remember me)
+	SessionPolicy SessionPolicy
+
+	// Clé HMAC des codes de vérification (VERIFICATION_CODE_KEY)
+	CodeKey []byte
 }
 
 // Représente un utilisateur avec ses données essentielles.
 type User struct {
 	ID         int64
-	GoogleID   sql.NullString
 	Email      string
 	Password   sql.NullString
 	Name       string
 	AvatarURL  string
 	IsVerified bool
+	Locale     string // langue des e-mails ("" : Accept-Language de la requête)
 	CreatedAt  time.Time
+
+	SuspendedUntil *time.Time // suspension temporaire
+	Banned         bool       // bannissement définitif
+}
+
+// Indique si le compte est suspendu ou banni à la date now.
+func (u *User) IsSuspended(now time.Time) bool {
+	return u.Banned || (u.SuspendedUntil != nil && u.SuspendedUntil.After(now))
 }
 
 type PublicUser struct {
@@ -48,34 +68,73 @@ type PublicUser struct {
 }
 
 /*
-Crée une connexion à MySQL et retourne un Service connecté.
+Crée une connexion à la base, applique les migrations en attente
+et retourne un Service connecté.
+*/
+func New(cfg *config.Config) Service {
+	db := Connect(cfg.Database)
+
+	// Schéma à jour avant de servir ; les instances qui démarrent ensemble s’attendent (verrou consultatif)
+	migrator, err := migrations.New(db.DB, db.Dialect)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	return Service{
+		DB:            db,
+		SessionPolicy: NewSessionPolicy(cfg.Sessions),
+		CodeKey:       VerificationCodeKey(cfg.VerificationCodeKey),
+	}
+}
+
+/*
+Ouvre la connexion sans toucher au schéma (utilisé aussi par la commande -migrate).
+BLUEPRINT_DB_DRIVER choisit la base : mysql (par défaut) ou postgres.
 Construction du DSN
 Connexion et vérification :
 */
-func New() Service {
-	dbHost := os.Getenv("BLUEPRINT_DB_HOST")
-	dbPort := os.Getenv("BLUEPRINT_DB_PORT")
-	dbUser := os.Getenv("BLUEPRINT_DB_USERNAME")
-	dbPass := os.Getenv("BLUEPRINT_DB_PASSWORD")
-	dbName := os.Getenv("BLUEPRINT_DB_DATABASE")
-
-	if dbHost == "" {
-		dbHost = "127.0.0.1"
+func Connect(cfg config.Database) *DB {
+	d, err := dialect.Parse(cfg.Driver)
+	if err != nil {
+		log.Fatal(err)
 	}
+
+	dbHost := cfg.Host
+	dbPort := cfg.Port
+	dbUser := cfg.Username
+	dbPass := cfg.Password
+	dbName := cfg.Name
+
 	if dbPort == "" {
 		dbPort = "3306"
+		if d == dialect.Postgres {
+			dbPort = "5432"
+		}
 	}
 	if dbUser == "" {
 		dbUser = "root"
-	}
-	if dbName == "" {
-		dbName = "miniprojet"
+		if d == dialect.Postgres {
+			dbUser = "postgres"
+		}
 	}
 
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
 		dbUser, dbPass, dbHost, dbPort, dbName)
-
-	db, err := sql.Open("mysql", dsn)
+	if d == dialect.Postgres {
+		// sslmode=disable pour une instance locale ; require (ou verify-full) pour une base hébergée
+		dsn = (&url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(dbUser, dbPass),
+			Host:     dbHost + ":" + dbPort,
+			Path:     "/" + dbName,
+			RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+		}).String()
+	}
+
+	db, err := sql.Open(d.DriverName(), dsn)
 	if err != nil {
 		log.Fatal("Failed to connect to DB:", err)
 	}
@@ -83,8 +142,8 @@ func New() Service {
 		log.Fatal("Failed to ping DB:", err)
 	}
 
-	log.Println("Successfully connected to database!")
-	return Service{DB: db}
+	log.Printf("Successfully connected to %s database!", d)
+	return &DB{DB: db, Dialect: d}
 }
 
 /*
@@ -107,33 +166,15 @@ func (s Service) Health() map[string]string {
 	}
 }
 
-// Cherche un utilisateur à partir de son identifiant Google OAuth :
-func (s Service) FindUserByGoogleID(googleID string) (int, error) {
-	var id int
-	err := s.DB.QueryRow("SELECT id FROM users WHERE google_id = ?", googleID).Scan(&id)
-	return id, err
-}
-
-// Insère un utilisateur provenant de Google
-func (s Service) CreateUser(googleID, email, name, picture string) (int, error) {
-	res, err := s.DB.Exec("INSERT INTO users (google_id, email, name, picture) VALUES (?, ?, ?, ?)",
-		googleID, email, name, picture)
-	if err != nil {
-		return 0, err
-	}
-	lastID, _ := res.LastInsertId()
-	fmt.Println("User inserted")
-	return int(lastID), nil
-}
-
 // Récupère un utilisateur via son email (auth locale).
 func (s Service) FindUserByEmail(email string) (*User, error) {
-	query := `SELECT id, email, password, name, picture, google_id, verified FROM users WHERE email = ?`
+	query := `SELECT id, email, password, name, picture, verified, locale, suspended_until, banned FROM users WHERE email = ?`
 	var user User
 	var password sql.NullString
-	var googleID sql.NullString
 	var name sql.NullString
 	var picture sql.NullString
+	var locale sql.NullString
+	var suspendedUntil sql.NullTime
 
 	err := s.DB.QueryRow(query, email).Scan(
 		&user.ID,
@@ -141,8 +182,10 @@ func (s Service) FindUserByEmail(email string) (*User, error) {
 		&password,
 		&name,
 		&picture,
-		&googleID,
 		&user.IsVerified,
+		&locale,
+		&suspendedUntil,
+		&user.Banned,
 	)
 
 	if err != nil {
@@ -151,7 +194,10 @@ func (s Service) FindUserByEmail(email string) (*User, error) {
 	}
 
 	user.Password = password
-	user.GoogleID = googleID
+	user.Locale = locale.String
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
 
 	if name.Valid {
 		user.Name = name.String
@@ -173,7 +219,7 @@ func (s Service) SearchUsers(query string, limit int) ([]PublicUser, error) {
 	rows, err := s.DB.Query(
 		`SELECT id, name, email, picture
 		 FROM users
-		 WHERE name LIKE ? OR email LIKE ?
+		 WHERE LOWER(name) LIKE LOWER(?) OR LOWER(email) LIKE LOWER(?)
 		 ORDER BY name ASC
 		 LIMIT ?`,
 		searchTerm, searchTerm, limit,
@@ -225,22 +271,39 @@ func (s Service) SearchUsers(query string, limit int) ([]PublicUser, error) {
 /*
 Crée un utilisateur classique (email + mot de passe) :
 Hash du mot de passe avec bcrypt.
+Le code de vérification et l’e-mail qui le contient sont enregistrés dans la même transaction :
+un compte n’existe jamais sans son e-mail de vérification en file d’envoi.
 */
-func (s Service) CreateEmailUser(email, password, name string) (int, error) {
+func (s Service) CreateEmailUser(email, password, name, locale, code string, codeExpiresAt time.Time, verificationEmail mailer.Message) (int, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return 0, fmt.Errorf("failed to hash password: %v", err)
 	}
 
-	res, err := s.DB.Exec(
-		"INSERT INTO users (email, password, name) VALUES (?, ?, ?)",
-		email, string(hashedPassword), name,
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	lastID, err := tx.InsertID(
+		"INSERT INTO users (email, password, name, locale) VALUES (?, ?, ?, NULLIF(?, ''))",
+		email, string(hashedPassword), name, locale,
 	)
 	if err != nil {
 		return 0, err
 	}
 
-	lastID, _ := res.LastInsertId()
+	if err := s.saveVerificationCode(tx, email, code, codeExpiresAt); err != nil {
+		return 0, err
+	}
+	if err := enqueueEmail(tx, verificationEmail); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
 	fmt.Println("Email user created:", email)
 	return int(lastID), nil
 }
@@ -271,7 +334,7 @@ func (s Service) VerifyPassword(hashedPassword, password string) bool {
 
 // Marque un utilisateur comme vérifié
 func (s Service) MarkUserAsVerified(email string) error {
-	_, err := s.DB.Exec("UPDATE users SET verified = 1 WHERE email = ?", email)
+	_, err := s.DB.Exec("UPDATE users SET verified = TRUE WHERE email = ?", email)
 	if err != nil {
 		return err
 	}
@@ -280,74 +343,31 @@ func (s Service) MarkUserAsVerified(email string) error {
 }
 
 /*
-Sauvegarde un code temporaire pour vérification
-*/
-func (s Service) SaveVerificationCode(email, code string, expiresAt time.Time) error {
-	_, err := s.DB.Exec(
-		"INSERT INTO verification_codes (email, code, expires_at) VALUES (?, ?, ?)",
-		email, code, expiresAt,
-	)
-	return err
-}
-
-/*
-Sélection du dernier code envoyé (ORDER BY created_at DESC LIMIT 1)
-
-Vérifie :
-
-# S’il existe
+Crée une nouvelle session après authentification.
+Seule l’empreinte SHA-256 du token est stockée.
+Un utilisateur peut avoir plusieurs sessions actives (une par appareil) :
+le user agent et l’adresse IP sont conservés pour la gestion des appareils.
 
-# S’il n’a pas déjà été utilisé
-
-S’il n’est pas expiré
+Les expirations sont calculées par SessionPolicy ; l’expiration absolue est retournée
+pour que le cookie côté gateway expire en même temps que la session.
 */
-func (s Service) VerifyCode(email, code string) (bool, error) {
-	var id int
-	var used bool
-	var expiresAt time.Time
-
-	query := `SELECT id, used, expires_at FROM verification_codes 
-			  WHERE email = ? AND code = ? ORDER BY created_at DESC LIMIT 1`
+func (s Service) CreateSession(userID int, token string, rememberMe bool, userAgent, ipAddress string) (time.Time, error) {
+	now := time.Now().UTC()
+	absoluteExpiresAt := s.SessionPolicy.AbsoluteExpiry(now, rememberMe)
+	expiresAt := s.SessionPolicy.IdleExpiry(now, absoluteExpiresAt, rememberMe)
 
-	err := s.DB.QueryRow(query, email, code).Scan(&id, &used, &expiresAt)
-	if err == sql.ErrNoRows {
-		return false, nil
-	}
-	if err != nil {
-		return false, err
-	}
-
-	if used {
-		return false, nil
-	}
-
-	if time.Now().After(expiresAt) {
-		return false, nil
-	}
-
-	_, err = s.DB.Exec("UPDATE verification_codes SET used = TRUE WHERE id = ?", id)
+	_, err := s.DB.Exec(
+		`INSERT INTO sessions (user_id, token_hash, user_agent, ip_address, remember_me, expires_at, absolute_expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, HashToken(token), truncate(userAgent, 255), truncate(ipAddress, 45), rememberMe, expiresAt, absoluteExpiresAt)
 	if err != nil {
-		return false, err
+		return time.Time{}, err
 	}
-
-	return true, nil
-}
-
-// Nettoie les anciens codes expirés
-func (s Service) DeleteOldVerificationCodes(email string) error {
-	_, err := s.DB.Exec("DELETE FROM verification_codes WHERE email = ? AND expires_at < NOW()", email)
-	return err
-}
-
-// Crée une nouvelle session après authentification
-func (s Service) CreateSession(userID int, token string, expiresAt string) error {
-	_, err := s.DB.Exec("INSERT INTO sessions (user_id, session_token, expires_at) VALUES (?, ?, ?)",
-		userID, token, expiresAt)
 	fmt.Println("session inserted")
-	return err
+	return absoluteExpiresAt, nil
 }
 
-// Supprime toutes les sessions précédentes d’un utilisateur (avant login ou logout)
+// Supprime toutes les sessions d’un utilisateur (réinitialisation du mot de passe, révocation globale)
 func (s Service) DeleteUserSessions(userID int) error {
 	query := `DELETE FROM sessions WHERE user_id = ?`
 	_, err := s.DB.Exec(query, userID)
@@ -358,35 +378,40 @@ func (s Service) DeleteUserSessions(userID int) error {
 	return nil
 }
 
-// Récupère l’utilisateur à partir d’un token de session valide
-// Vérifie que la session n'est pas expirée
+// Récupère l’utilisateur à partir d’un token de session valide (recherché par son empreinte SHA-256)
+// Vérifie que la session n'est pas expirée (inactivité ou expiration absolue)
+// et que le compte n’est ni suspendu ni banni, puis prolonge l’expiration glissante.
 func (s Service) GetUserBySessionToken(token string) (*User, error) {
 	query := `
-		SELECT u.id, u.google_id, u.email, u.name, u.picture
+		SELECT u.id, u.email, u.name, u.picture, u.verified, u.locale, s.remember_me, s.absolute_expires_at
 		FROM users u
 		INNER JOIN sessions s ON u.id = s.user_id
-		WHERE s.session_token = ? AND s.expires_at > NOW()
+		WHERE s.token_hash = ? AND s.expires_at > NOW() AND s.absolute_expires_at > NOW()
+		  AND u.banned = FALSE AND (u.suspended_until IS NULL OR u.suspended_until <= NOW())
 	`
 	var user User
-	var googleID sql.NullString
 	var name sql.NullString
 	var picture sql.NullString
+	var locale sql.NullString
+	var rememberMe bool
+	var absoluteExpiresAt time.Time
 
-	log.Printf("GetUserBySessionToken: Looking for token: %s", token[:10]+"...")
-
-	err := s.DB.QueryRow(query, token).Scan(
+	tokenHash := HashToken(token)
+	err := s.DB.QueryRow(query, tokenHash).Scan(
 		&user.ID,
-		&googleID,
 		&user.Email,
 		&name,
 		&picture,
+		&user.IsVerified,
+		&locale,
+		&rememberMe,
+		&absoluteExpiresAt,
 	)
 	if err != nil {
 		log.Printf("GetUserBySessionToken: Query error: %v", err)
 		return nil, err
 	}
 
-	user.GoogleID = googleID
 	if name.Valid {
 		user.Name = name.String
 	}
@@ -394,32 +419,48 @@ func (s Service) GetUserBySessionToken(token string) (*User, error) {
 		user.AvatarURL = picture.String
 	}
 
+	user.Locale = locale.String
+
+	s.renewSession(tokenHash, s.SessionPolicy.IdleExpiry(time.Now().UTC(), absoluteExpiresAt, rememberMe))
+
 	log.Printf("GetUserBySessionToken: User found: ID=%d, Email=%s", user.ID, user.Email)
 	return &user, nil
 }
 
+// Enregistre la langue des e-mails de l’utilisateur ("" pour suivre Accept-Language).
+func (s Service) SetUserLocale(userID int, locale string) error {
+	_, err := s.DB.Exec("UPDATE users SET locale = NULLIF(?, '') WHERE id = ?", locale, userID)
+	return err
+}
+
 // Récupère un utilisateur par son ID
 func (s Service) GetUserByID(userID int) (*User, error) {
-	query := `SELECT id, google_id, email, name, picture, verified FROM users WHERE id = ?`
+	query := `SELECT id, email, name, picture, verified, locale, suspended_until, banned FROM users WHERE id = ?`
 	var user User
-	var googleID sql.NullString
 	var name sql.NullString
 	var picture sql.NullString
+	var locale sql.NullString
+	var suspendedUntil sql.NullTime
 
 	err := s.DB.QueryRow(query, userID).Scan(
 		&user.ID,
-		&googleID,
 		&user.Email,
 		&name,
 		&picture,
 		&user.IsVerified,
+		&locale,
+		&suspendedUntil,
+		&user.Banned,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	user.GoogleID = googleID
+	user.Locale = locale.String
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
 
 	if name.Valid {
 		user.Name = name.String
@@ -433,8 +474,8 @@ func (s Service) GetUserByID(userID int) (*User, error) {
 
 // Supprime une session spécifique (déconnexion).
 func (s Service) DeleteSession(token string) error {
-	query := `DELETE FROM sessions WHERE session_token = ?`
-	_, err := s.DB.Exec(query, token)
+	query := `DELETE FROM sessions WHERE token_hash = ?`
+	_, err := s.DB.Exec(query, HashToken(token))
 	if err != nil {
 		return err
 	}
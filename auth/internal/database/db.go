@@ -0,0 +1,90 @@
+/*
+Ce fichier enveloppe *sql.DB et *sql.Tx pour que chaque requête soit adaptée au dialecte
+de la connexion (MySQL ou PostgreSQL, voir le paquet dialect) :
+
+Exec, Query, QueryRow et Begin convertissent les marqueurs « ? ».
+
+InsertID remplace LastInsertId, absent de PostgreSQL (RETURNING id).
+
+Les autres méthodes de *sql.DB (ExecContext…) restent accessibles mais ne convertissent rien.
+*/
+
+package database
+
+import (
+	"database/sql"
+
+	"auth/internal/database/dialect"
+)
+
+type DB struct {
+	*sql.DB
+	Dialect dialect.Dialect
+}
+
+type Tx struct {
+	*sql.Tx
+	dialect dialect.Dialect
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: db.Dialect}, nil
+}
+
+// Exécute un INSERT et retourne l’identifiant généré.
+func (db *DB) InsertID(query string, args ...interface{}) (int64, error) {
+	return insertID(db.DB, db.Dialect, query, args)
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) InsertID(query string, args ...interface{}) (int64, error) {
+	return insertID(tx.Tx, tx.dialect, query, args)
+}
+
+// Méthodes communes à *sql.DB et *sql.Tx utilisées par insertID.
+type rawQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func insertID(q rawQuerier, d dialect.Dialect, query string, args []interface{}) (int64, error) {
+	query = d.Rebind(query)
+	if d == dialect.Postgres {
+		var id int64
+		err := q.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
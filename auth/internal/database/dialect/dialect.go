@@ -0,0 +1,95 @@
+/*
+Ce paquet isole les différences entre les bases supportées par le service auth :
+MySQL 8 (par défaut) et PostgreSQL.
+
+Les requêtes du paquet database sont écrites avec des marqueurs « ? » ;
+Rebind les convertit en $1, $2… pour PostgreSQL.
+Les quelques requêtes qui n’ont pas d’équivalent portable (upsert, INSERT IGNORE…)
+existent en deux versions, choisies selon le dialecte de la connexion.
+*/
+
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+)
+
+// Dialecte correspondant à BLUEPRINT_DB_DRIVER ("" : MySQL).
+func Parse(name string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "mysql":
+		return MySQL, nil
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	}
+	return "", fmt.Errorf("unknown database driver %q (expected mysql or postgres)", name)
+}
+
+// Nom du driver database/sql.
+func (d Dialect) DriverName() string {
+	if d == Postgres {
+		return "pgx"
+	}
+	return "mysql"
+}
+
+/*
+Remplace les marqueurs « ? » par $1, $2… (PostgreSQL). Les « ? » situés dans une chaîne
+ou un identifiant entre guillemets sont conservés. Sous MySQL, la requête est inchangée.
+*/
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var (
+		out   strings.Builder
+		quote byte // ', " ou ` en cours, 0 sinon
+		n     int
+	)
+	out.Grow(len(query) + 8)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			n++
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(n))
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// Indique si err est une violation de contrainte unique (MySQL 1062, PostgreSQL 23505).
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package dialect
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM users WHERE email = ? AND name <> '?' AND id IN (?, ?)"
+
+	if got := MySQL.Rebind(query); got != query {
+		t.Errorf("MySQL: query should be unchanged, got %q", got)
+	}
+	want := "SELECT id FROM users WHERE email = $1 AND name <> '?' AND id IN ($2, $3)"
+	if got := Postgres.Rebind(query); got != want {
+		t.Errorf("Postgres: got %q, want %q", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for name, want := range map[string]Dialect{"": MySQL, "mysql": MySQL, "postgres": Postgres, "PostgreSQL": Postgres} {
+		got, err := Parse(name)
+		if err != nil || got != want {
+			t.Errorf("Parse(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := Parse("sqlite"); err == nil {
+		t.Error("expected an error for an unsupported driver")
+	}
+}
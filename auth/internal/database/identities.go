@@ -0,0 +1,177 @@
+/*
+Ce fichier gère les identités externes (table user_identities) :
+un compte peut être relié à plusieurs fournisseurs OAuth (Google, GitHub, Microsoft, GitLab, OIDC…).
+Le couple (provider, provider_user_id) est unique : une identité n’appartient qu’à un seul utilisateur.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Identity struct {
+	ID             int64     `json:"id"`
+	Provider       string    `json:"provider"`
+	ProviderUserID string    `json:"-"`
+	Email          string    `json:"email"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Retrouve l’utilisateur relié à une identité externe (sql.ErrNoRows sinon).
+func (s Service) FindUserByIdentity(provider, providerUserID string) (int, error) {
+	var userID int
+	err := s.DB.QueryRow(
+		"SELECT user_id FROM user_identities WHERE provider = ? AND provider_user_id = ?",
+		provider, providerUserID,
+	).Scan(&userID)
+	return userID, err
+}
+
+/*
+Crée un utilisateur à partir d’un fournisseur OAuth et lui associe l’identité.
+verified indique si le fournisseur garantit la possession de l’adresse email.
+*/
+func (s Service) CreateProviderUser(provider, providerUserID, email, name, picture string, verified bool) (int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	userID, err := tx.InsertID("INSERT INTO users (email, name, picture, verified) VALUES (?, ?, ?, ?)", email, name, picture, verified)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO user_identities (user_id, provider, provider_user_id, email) VALUES (?, ?, ?, ?)",
+		userID, provider, providerUserID, email,
+	); err != nil {
+		return 0, err
+	}
+
+	return int(userID), tx.Commit()
+}
+
+// Liste les identités externes d’un utilisateur.
+func (s Service) ListUserIdentities(userID int) ([]Identity, error) {
+	rows, err := s.DB.Query(
+		"SELECT id, provider, provider_user_id, email, created_at FROM user_identities WHERE user_id = ? ORDER BY created_at ASC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []Identity{}
+	for rows.Next() {
+		var identity Identity
+		var email sql.NullString
+		if err := rows.Scan(&identity.ID, &identity.Provider, &identity.ProviderUserID, &email, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identity.Email = email.String
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// Relie une identité externe à un utilisateur existant.
+func (s Service) LinkIdentity(userID int, provider, providerUserID, email string) error {
+	_, err := s.DB.Exec(
+		"INSERT INTO user_identities (user_id, provider, provider_user_id, email) VALUES (?, ?, ?, ?)",
+		userID, provider, providerUserID, email,
+	)
+	return err
+}
+
+// Supprime une identité externe de l’utilisateur ; retourne false si elle n’existe pas.
+func (s Service) UnlinkIdentity(userID int, id int64) (bool, error) {
+	res, err := s.DB.Exec("DELETE FROM user_identities WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Tables des moyens de connexion, sessions et autorisations retirés lors de la reprise d’un compte.
+var claimedUserTables = []string{
+	"sessions",
+	"user_identities",
+	"webauthn_credentials",
+	"webauthn_ceremonies",
+	"user_wallets",
+	"user_totp",
+	"totp_recovery_codes",
+	"login_challenges",
+	"password_reset_tokens",
+	"oauth_consents",
+	"oauth_authorization_requests",
+	"oauth_authorization_codes",
+}
+
+/*
+Fusion d’un compte jamais vérifié avec une identité dont le fournisseur garantit l’email.
+Le compte a pu être créé par quelqu’un qui n’a jamais prouvé posséder l’adresse
+(inscription sans vérification, fournisseur qui ne garantit pas l’email) : tout ce qu’il
+a pu y attacher est supprimé (mot de passe, sessions, identités, passkeys, wallets, TOTP,
+challenges en cours, consentements OpenID Connect), puis l’identité est reliée et le compte
+devient vérifié, dans la même transaction.
+Si le compte a été vérifié entre-temps, l’identité est seulement reliée.
+*/
+func (s Service) ClaimUnverifiedUser(userID int, provider, providerUserID, email string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET password = NULL, verified = TRUE WHERE id = ? AND verified = FALSE", userID)
+	if err != nil {
+		return err
+	}
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if claimed > 0 {
+		for _, table := range claimedUserTables {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO user_identities (user_id, provider, provider_user_id, email) VALUES (?, ?, ?, ?)",
+		userID, provider, providerUserID, email,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+/*
+Compte les moyens de connexion d’un utilisateur :
+mot de passe, identités externes, passkeys et wallets vérifiés.
+*/
+func (s Service) CountLoginMethods(userID int) (int, error) {
+	var count int
+	err := s.DB.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE id = ? AND password IS NOT NULL) +
+			(SELECT COUNT(*) FROM user_identities WHERE user_id = ?) +
+			(SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = ?) +
+			(SELECT COUNT(*) FROM user_wallets WHERE user_id = ?)`,
+		userID, userID, userID, userID,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,811 @@
+/*
+Ce fichier définit MemoryStore, l’implémentation en mémoire de Store.
+
+Elle reproduit le comportement des requêtes SQL (unicité, expirations, usage unique,
+erreurs sql.ErrNoRows…) pour que les tests de l’API HTTP tournent avec httptest, sans base.
+Les comparaisons d’emails ignorent la casse, comme la collation utf8mb4_0900_ai_ci.
+
+Toutes les données sont protégées par un seul mutex : chaque méthode est atomique,
+comme les transactions de Service. Les clés étrangères ne sont pas vérifiées.
+
+Les opérations sont réparties dans memory.go (comptes, sessions, codes, compteurs d’échecs),
+memory_security.go (2FA, passkeys, wallets, OpenID Connect, clés de signature)
+et memory_admin.go (rôles, administration, signalements, file d’e-mails, journal d’audit).
+*/
+
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"auth/internal/mailer"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Équivalent de l’erreur MySQL 1062 (violation d’une clé unique).
+var errDuplicateEntry = errors.New("duplicate entry")
+
+type MemoryStore struct {
+	// Durées de vie des sessions (absolue, inactivité, remember me)
+	SessionPolicy SessionPolicy
+
+	// Clé HMAC des codes de vérification
+	CodeKey []byte
+
+	mu  sync.Mutex
+	ids map[string]int64 // dernier identifiant attribué, par table
+
+	users             map[int64]*memoryUser
+	identities        []*memoryIdentity
+	sessions          []*memorySession
+	verificationCodes []*memoryVerificationCode
+	resetTokens       []*memoryResetToken
+	throttles         map[string]Throttle
+
+	totp                map[int]*TOTPEnrollment
+	recoveryCodes       []*memoryRecoveryCode
+	loginChallenges     []*memoryLoginChallenge
+	webAuthnCredentials []*WebAuthnCredential
+	webAuthnCeremonies  map[string]*memoryCeremony
+	siweNonces          map[string]time.Time
+	wallets             []*memoryWallet
+	oauthClients        map[string]*OAuthClient
+	oauthConsents       []*memoryConsent
+	oauthRequests       map[string]*memoryAuthorization
+	oauthCodes          map[string]*memoryAuthorization
+	signingKeys         []StoredSigningKey
+
+	roles     []Role
+	userRoles []*memoryUserRole
+	reports   []*memoryReport
+	outbox    []*memoryOutboxEmail
+	events    []AuthEvent
+}
+
+type memoryUser struct {
+	User
+	suspensionReason string
+}
+
+type memoryIdentity struct {
+	Identity
+	userID int
+}
+
+type memorySession struct {
+	id                int64
+	userID            int
+	tokenHash         string
+	userAgent         string
+	ipAddress         string
+	rememberMe        bool
+	createdAt         time.Time
+	lastSeenAt        *time.Time
+	expiresAt         time.Time
+	absoluteExpiresAt time.Time
+}
+
+type memoryVerificationCode struct {
+	id        int64
+	email     string
+	codeHash  string
+	used      bool
+	attempts  int
+	expiresAt time.Time
+	createdAt time.Time
+}
+
+type memoryResetToken struct {
+	id        int64
+	userID    int
+	tokenHash string
+	used      bool
+	expiresAt time.Time
+}
+
+/*
+Crée un stockage vide avec la politique de sessions par défaut,
+une clé de codes aléatoire et les rôles définis par les migrations.
+*/
+func NewMemoryStore() *MemoryStore {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate verification code key: %v", err))
+	}
+
+	return &MemoryStore{
+		SessionPolicy: DefaultSessionPolicy,
+		CodeKey:       key,
+
+		ids:                map[string]int64{},
+		users:              map[int64]*memoryUser{},
+		throttles:          map[string]Throttle{},
+		totp:               map[int]*TOTPEnrollment{},
+		webAuthnCeremonies: map[string]*memoryCeremony{},
+		siweNonces:         map[string]time.Time{},
+		oauthClients:       map[string]*OAuthClient{},
+		oauthRequests:      map[string]*memoryAuthorization{},
+		oauthCodes:         map[string]*memoryAuthorization{},
+		roles:              defaultRoles(),
+	}
+}
+
+// Prochain identifiant auto-incrémenté de la table.
+func (m *MemoryStore) nextID(table string) int64 {
+	m.ids[table]++
+	return m.ids[table]
+}
+
+// Intervalle [start, end) d’une page LIMIT / OFFSET sur total éléments.
+func page(total, limit, offset int) (int, int) {
+	start := offset
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if limit < 0 || end > total {
+		end = total
+	}
+	return start, end
+}
+
+func (m *MemoryStore) Health() map[string]string {
+	return map[string]string{
+		"status":  "up",
+		"message": "It's healthy",
+	}
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// ===== Utilisateurs =====
+
+func (m *MemoryStore) userByEmail(email string) *memoryUser {
+	for _, user := range m.sortedUsers() {
+		if strings.EqualFold(user.Email, email) {
+			return user
+		}
+	}
+	return nil
+}
+
+// Utilisateurs par identifiant croissant.
+func (m *MemoryStore) sortedUsers() []*memoryUser {
+	users := make([]*memoryUser, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (m *MemoryStore) insertUser(user User) (*memoryUser, error) {
+	if m.userByEmail(user.Email) != nil {
+		return nil, errDuplicateEntry
+	}
+	user.ID = m.nextID("users")
+	user.CreatedAt = time.Now().UTC()
+	stored := &memoryUser{User: user}
+	m.users[user.ID] = stored
+	return stored, nil
+}
+
+func (m *MemoryStore) FindUserByEmail(email string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.userByEmail(email)
+	if user == nil {
+		return nil, sql.ErrNoRows
+	}
+	found := user.User
+	return &found, nil
+}
+
+// Comme Service.GetUserByID, le mot de passe n’est pas renvoyé.
+func (m *MemoryStore) GetUserByID(userID int) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[int64(userID)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := user.User
+	found.Password = sql.NullString{}
+	return &found, nil
+}
+
+func (m *MemoryStore) SearchUsers(query string, limit int) ([]PublicUser, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = strings.ToLower(query)
+	var results []PublicUser
+	for _, user := range m.sortedUsers() {
+		if strings.Contains(strings.ToLower(user.Name), query) || strings.Contains(strings.ToLower(user.Email), query) {
+			results = append(results, PublicUser{ID: user.ID, Name: user.Name, AvatarURL: user.AvatarURL})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (m *MemoryStore) CreateEmailUser(email, password, name, locale, code string, codeExpiresAt time.Time, verificationEmail mailer.Message) (int, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.insertUser(User{
+		Email:    email,
+		Password: sql.NullString{String: string(hashedPassword), Valid: true},
+		Name:     name,
+		Locale:   locale,
+	})
+	if err != nil {
+		return 0, err
+	}
+	m.saveVerificationCode(email, code, codeExpiresAt)
+	m.enqueueEmail(verificationEmail)
+	return int(user.ID), nil
+}
+
+func (m *MemoryStore) VerifyPassword(hashedPassword, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
+}
+
+func (m *MemoryStore) UpdateUserPassword(userID int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[int64(userID)]; ok {
+		user.Password = sql.NullString{String: string(hashedPassword), Valid: true}
+	}
+	return nil
+}
+
+func (m *MemoryStore) MarkUserAsVerified(email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user := m.userByEmail(email); user != nil {
+		user.IsVerified = true
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetUserLocale(userID int, locale string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[int64(userID)]; ok {
+		user.Locale = locale
+	}
+	return nil
+}
+
+// ===== Sessions =====
+
+func (m *MemoryStore) CreateSession(userID int, token string, rememberMe bool, userAgent, ipAddress string) (time.Time, error) {
+	now := time.Now().UTC()
+	absoluteExpiresAt := m.SessionPolicy.AbsoluteExpiry(now, rememberMe)
+	expiresAt := m.SessionPolicy.IdleExpiry(now, absoluteExpiresAt, rememberMe)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokenHash := HashToken(token)
+	for _, session := range m.sessions {
+		if session.tokenHash == tokenHash {
+			return time.Time{}, errDuplicateEntry
+		}
+	}
+	m.sessions = append(m.sessions, &memorySession{
+		id:                m.nextID("sessions"),
+		userID:            userID,
+		tokenHash:         tokenHash,
+		userAgent:         truncate(userAgent, 255),
+		ipAddress:         truncate(ipAddress, 45),
+		rememberMe:        rememberMe,
+		createdAt:         now,
+		expiresAt:         expiresAt,
+		absoluteExpiresAt: absoluteExpiresAt,
+	})
+	return absoluteExpiresAt, nil
+}
+
+func (s *memorySession) active(now time.Time) bool {
+	return s.expiresAt.After(now) && s.absoluteExpiresAt.After(now)
+}
+
+// Même contrôle que Service.GetUserBySessionToken, y compris l’expiration glissante.
+func (m *MemoryStore) GetUserBySessionToken(token string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	tokenHash := HashToken(token)
+	for _, session := range m.sessions {
+		if session.tokenHash != tokenHash || !session.active(now) {
+			continue
+		}
+		user, ok := m.users[int64(session.userID)]
+		if !ok || user.IsSuspended(now) {
+			break
+		}
+
+		if session.lastSeenAt == nil || session.lastSeenAt.Before(now.Add(-time.Minute)) {
+			session.lastSeenAt = &now
+			session.expiresAt = m.SessionPolicy.IdleExpiry(now, session.absoluteExpiresAt, session.rememberMe)
+		}
+
+		return &User{
+			ID:         user.ID,
+			Email:      user.Email,
+			Name:       user.Name,
+			AvatarURL:  user.AvatarURL,
+			IsVerified: user.IsVerified,
+			Locale:     user.Locale,
+		}, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStore) ListUserSessions(userID int, currentToken string) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	currentHash := HashToken(currentToken)
+	var active []*memorySession
+	for _, session := range m.sessions {
+		if session.userID == userID && session.active(now) {
+			active = append(active, session)
+		}
+	}
+	// ORDER BY last_seen_at DESC : les sessions jamais renouvelées (NULL) en dernier
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[j].lastSeenAt == nil {
+			return active[i].lastSeenAt != nil
+		}
+		return active[i].lastSeenAt != nil && active[i].lastSeenAt.After(*active[j].lastSeenAt)
+	})
+
+	sessions := make([]Session, 0, len(active))
+	for _, session := range active {
+		listed := Session{
+			ID:         session.id,
+			UserAgent:  session.userAgent,
+			IPAddress:  session.ipAddress,
+			CreatedAt:  session.createdAt,
+			LastSeenAt: session.createdAt,
+			ExpiresAt:  session.expiresAt,
+			RememberMe: session.rememberMe,
+			Current:    session.tokenHash == currentHash,
+		}
+		if session.lastSeenAt != nil {
+			listed.LastSeenAt = *session.lastSeenAt
+		}
+		sessions = append(sessions, listed)
+	}
+	return sessions, nil
+}
+
+func (m *MemoryStore) IsNewDevice(userID int, userAgent string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userAgent = truncate(userAgent, 255)
+	total, sameDevice := 0, 0
+	for _, session := range m.sessions {
+		if session.userID != userID {
+			continue
+		}
+		total++
+		if session.userAgent == userAgent {
+			sameDevice++
+		}
+	}
+	return total > 0 && sameDevice == 0, nil
+}
+
+// Supprime les sessions pour lesquelles remove renvoie true ; retourne leur nombre.
+func (m *MemoryStore) deleteSessions(remove func(*memorySession) bool) int64 {
+	kept := m.sessions[:0]
+	var removed int64
+	for _, session := range m.sessions {
+		if remove(session) {
+			removed++
+			continue
+		}
+		kept = append(kept, session)
+	}
+	m.sessions = kept
+	return removed
+}
+
+func (m *MemoryStore) DeleteSession(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokenHash := HashToken(token)
+	m.deleteSessions(func(s *memorySession) bool { return s.tokenHash == tokenHash })
+	return nil
+}
+
+func (m *MemoryStore) DeleteUserSessions(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteSessions(func(s *memorySession) bool { return s.userID == userID })
+	return nil
+}
+
+func (m *MemoryStore) DeleteUserSessionByID(userID int, sessionID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := m.deleteSessions(func(s *memorySession) bool { return s.id == sessionID && s.userID == userID })
+	return removed > 0, nil
+}
+
+func (m *MemoryStore) DeleteOtherUserSessions(userID int, keepToken string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keepHash := HashToken(keepToken)
+	return m.deleteSessions(func(s *memorySession) bool { return s.userID == userID && s.tokenHash != keepHash }), nil
+}
+
+// ===== Codes de vérification et réinitialisation du mot de passe =====
+
+func (m *MemoryStore) saveVerificationCode(email, code string, expiresAt time.Time) {
+	kept := m.verificationCodes[:0]
+	for _, c := range m.verificationCodes {
+		if !strings.EqualFold(c.email, email) {
+			kept = append(kept, c)
+		}
+	}
+	m.verificationCodes = append(kept, &memoryVerificationCode{
+		id:        m.nextID("verification_codes"),
+		email:     email,
+		codeHash:  hashVerificationCode(m.CodeKey, email, code),
+		expiresAt: expiresAt.UTC(),
+		createdAt: time.Now().UTC(),
+	})
+}
+
+// Code le plus récent de l’email (nil s’il n’en a pas).
+func (m *MemoryStore) latestVerificationCode(email string) *memoryVerificationCode {
+	var latest *memoryVerificationCode
+	for _, c := range m.verificationCodes {
+		if strings.EqualFold(c.email, email) && (latest == nil || !c.createdAt.Before(latest.createdAt)) {
+			latest = c
+		}
+	}
+	return latest
+}
+
+func (m *MemoryStore) SaveVerificationCode(email, code string, expiresAt time.Time, verificationEmail mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.saveVerificationCode(email, code, expiresAt)
+	m.enqueueEmail(verificationEmail)
+	return nil
+}
+
+func (m *MemoryStore) VerifyCode(email, code string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	latest := m.latestVerificationCode(email)
+	if latest == nil || latest.used || latest.attempts >= MaxVerificationAttempts || time.Now().After(latest.expiresAt) {
+		return false, nil
+	}
+
+	latest.attempts++
+	if !hmac.Equal([]byte(latest.codeHash), []byte(hashVerificationCode(m.CodeKey, email, code))) {
+		return false, nil
+	}
+	latest.used = true
+	return true, nil
+}
+
+func (m *MemoryStore) VerificationCodeCooldown(email string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	latest := m.latestVerificationCode(email)
+	if latest == nil {
+		return 0, nil
+	}
+	if wait := latest.createdAt.Add(VerificationResendCooldown).Sub(time.Now()); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+func (m *MemoryStore) SavePasswordResetToken(userID int, tokenHash string, expiresAt time.Time, resetEmail mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.resetTokens[:0]
+	for _, token := range m.resetTokens {
+		if token.tokenHash == tokenHash {
+			return errDuplicateEntry
+		}
+		if token.userID != userID || token.used {
+			kept = append(kept, token)
+		}
+	}
+	m.resetTokens = append(kept, &memoryResetToken{
+		id:        m.nextID("password_reset_tokens"),
+		userID:    userID,
+		tokenHash: tokenHash,
+		expiresAt: expiresAt,
+	})
+	m.enqueueEmail(resetEmail)
+	return nil
+}
+
+func (m *MemoryStore) ConsumePasswordResetToken(tokenHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.resetTokens {
+		if token.tokenHash != tokenHash {
+			continue
+		}
+		if token.used || time.Now().After(token.expiresAt) {
+			return 0, sql.ErrNoRows
+		}
+		token.used = true
+		return token.userID, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+// ===== Protection contre la force brute =====
+
+func (m *MemoryStore) GetThrottles(keys ...string) (map[string]Throttle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	throttles := make(map[string]Throttle, len(keys))
+	for _, key := range keys {
+		if throttle, ok := m.throttles[key]; ok {
+			throttles[key] = throttle
+		}
+	}
+	return throttles, nil
+}
+
+// Comme Service.ReserveThrottleAttempt : refusé si le compteur n’est plus dans l’état seen.
+func (m *MemoryStore) ReserveThrottleAttempt(key string, seen *Throttle, window time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	throttle, ok := m.throttles[key]
+	if ok != (seen != nil) || (ok && (throttle.Failures != seen.Failures || !throttle.LastFailureAt.Equal(seen.LastFailureAt))) {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	if !ok || throttle.LastFailureAt.Before(now.Add(-window)) {
+		throttle.Failures = 0
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = now
+	m.throttles[key] = throttle
+	return true, nil
+}
+
+func (m *MemoryStore) ReleaseThrottleAttempt(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if throttle, ok := m.throttles[key]; ok && throttle.Failures > 0 {
+		throttle.Failures--
+		m.throttles[key] = throttle
+	}
+	return nil
+}
+
+func (m *MemoryStore) ResetThrottle(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.throttles, key)
+	return nil
+}
+
+func (m *MemoryStore) PruneThrottles(olderThan time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := time.Now().UTC().Add(-olderThan)
+	var pruned int64
+	for key, throttle := range m.throttles {
+		if throttle.LastFailureAt.Before(cutoff) {
+			delete(m.throttles, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// ===== Identités externes =====
+
+func (m *MemoryStore) identity(provider, providerUserID string) *memoryIdentity {
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.ProviderUserID == providerUserID {
+			return identity
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) linkIdentity(userID int, provider, providerUserID, email string) error {
+	if m.identity(provider, providerUserID) != nil {
+		return errDuplicateEntry
+	}
+	m.identities = append(m.identities, &memoryIdentity{
+		Identity: Identity{
+			ID:             m.nextID("user_identities"),
+			Provider:       provider,
+			ProviderUserID: providerUserID,
+			Email:          email,
+			CreatedAt:      time.Now().UTC(),
+		},
+		userID: userID,
+	})
+	return nil
+}
+
+func (m *MemoryStore) FindUserByIdentity(provider, providerUserID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity := m.identity(provider, providerUserID)
+	if identity == nil {
+		return 0, sql.ErrNoRows
+	}
+	return identity.userID, nil
+}
+
+func (m *MemoryStore) CreateProviderUser(provider, providerUserID, email, name, picture string, verified bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.identity(provider, providerUserID) != nil {
+		return 0, errDuplicateEntry
+	}
+	user, err := m.insertUser(User{Email: email, Name: name, AvatarURL: picture, IsVerified: verified})
+	if err != nil {
+		return 0, err
+	}
+	return int(user.ID), m.linkIdentity(int(user.ID), provider, providerUserID, email)
+}
+
+func (m *MemoryStore) ListUserIdentities(userID int) ([]Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identities := []Identity{}
+	for _, identity := range m.identities {
+		if identity.userID == userID {
+			identities = append(identities, identity.Identity)
+		}
+	}
+	return identities, nil
+}
+
+func (m *MemoryStore) LinkIdentity(userID int, provider, providerUserID, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.linkIdentity(userID, provider, providerUserID, email)
+}
+
+func (m *MemoryStore) UnlinkIdentity(userID int, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, identity := range m.identities {
+		if identity.ID == id && identity.userID == userID {
+			m.identities = append(m.identities[:i], m.identities[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStore) ClaimUnverifiedUser(userID int, provider, providerUserID, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.identity(provider, providerUserID) != nil {
+		return errDuplicateEntry
+	}
+	if user, ok := m.users[int64(userID)]; ok && !user.IsVerified {
+		user.Password = sql.NullString{}
+		user.IsVerified = true
+		m.deleteUserCredentials(userID)
+	}
+	return m.linkIdentity(userID, provider, providerUserID, email)
+}
+
+// Supprime les données de claimedUserTables appartenant à l’utilisateur.
+func (m *MemoryStore) deleteUserCredentials(userID int) {
+	m.deleteSessions(func(s *memorySession) bool { return s.userID == userID })
+	m.identities = slices.DeleteFunc(m.identities, func(i *memoryIdentity) bool { return i.userID == userID })
+	m.webAuthnCredentials = slices.DeleteFunc(m.webAuthnCredentials, func(c *WebAuthnCredential) bool { return c.UserID == userID })
+	maps.DeleteFunc(m.webAuthnCeremonies, func(_ string, c *memoryCeremony) bool { return c.userID == userID })
+	m.wallets = slices.DeleteFunc(m.wallets, func(w *memoryWallet) bool { return w.userID == userID })
+	delete(m.totp, userID)
+	m.recoveryCodes = slices.DeleteFunc(m.recoveryCodes, func(c *memoryRecoveryCode) bool { return c.userID == userID })
+	m.loginChallenges = slices.DeleteFunc(m.loginChallenges, func(c *memoryLoginChallenge) bool { return c.UserID == userID })
+	m.resetTokens = slices.DeleteFunc(m.resetTokens, func(t *memoryResetToken) bool { return t.userID == userID })
+	m.oauthConsents = slices.DeleteFunc(m.oauthConsents, func(c *memoryConsent) bool { return c.userID == userID })
+	maps.DeleteFunc(m.oauthRequests, func(_ string, a *memoryAuthorization) bool { return a.UserID == userID })
+	maps.DeleteFunc(m.oauthCodes, func(_ string, a *memoryAuthorization) bool { return a.UserID == userID })
+}
+
+func (m *MemoryStore) CountLoginMethods(userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	if user, ok := m.users[int64(userID)]; ok && user.Password.Valid {
+		count++
+	}
+	for _, identity := range m.identities {
+		if identity.userID == userID {
+			count++
+		}
+	}
+	for _, credential := range m.webAuthnCredentials {
+		if credential.UserID == userID {
+			count++
+		}
+	}
+	for _, wallet := range m.wallets {
+		if wallet.userID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
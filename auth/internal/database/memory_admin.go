@@ -0,0 +1,699 @@
+/*
+Ce fichier regroupe les opérations de MemoryStore liées à l’administration :
+rôles et permissions, comptes, signalements, file d’envoi des e-mails et journal d’audit.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"auth/internal/mailer"
+)
+
+type memoryUserRole struct {
+	userID    int
+	role      string
+	grantedBy int
+	grantedAt time.Time
+}
+
+type memoryReport struct {
+	Report
+	comments []ReportComment
+}
+
+type memoryOutboxEmail struct {
+	OutboxEmail
+	message mailer.Message
+}
+
+// Rôles et permissions insérés par la migration initiale.
+func defaultRoles() []Role {
+	return []Role{
+		{
+			Name:        RoleAdmin,
+			Description: "Full access, including role management",
+			Permissions: []string{
+				PermissionAuditRead, PermissionEmailsManage, PermissionReportsModerate, PermissionReportsRead,
+				PermissionRolesManage, PermissionUsersManage, PermissionUsersRead,
+			},
+		},
+		{
+			Name:        RoleModerator,
+			Description: "Handles abuse reports",
+			Permissions: []string{PermissionReportsModerate, PermissionReportsRead, PermissionUsersRead},
+		},
+	}
+}
+
+// ===== Rôles et permissions =====
+
+func (m *MemoryStore) role(name string) *Role {
+	for i := range m.roles {
+		if m.roles[i].Name == name {
+			return &m.roles[i]
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) hasRole(userID int, role string) bool {
+	for _, ur := range m.userRoles {
+		if ur.userID == userID && ur.role == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) countRole(role string) int {
+	count := 0
+	for _, ur := range m.userRoles {
+		if ur.role == role {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *MemoryStore) grantRole(userID int, role string, grantedBy int) {
+	m.userRoles = append(m.userRoles, &memoryUserRole{userID: userID, role: role, grantedBy: grantedBy, grantedAt: time.Now().UTC()})
+}
+
+func (m *MemoryStore) GetUserRoles(userID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := []string{}
+	for _, ur := range m.userRoles {
+		if ur.userID == userID {
+			roles = append(roles, ur.role)
+		}
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+func (m *MemoryStore) GetUserPermissions(userID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := map[string]bool{}
+	permissions := []string{}
+	for _, ur := range m.userRoles {
+		if ur.userID != userID {
+			continue
+		}
+		for _, permission := range m.role(ur.role).Permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (m *MemoryStore) UserHasPermission(userID int, permission string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ur := range m.userRoles {
+		if ur.userID != userID {
+			continue
+		}
+		for _, p := range m.role(ur.role).Permissions {
+			if p == permission {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStore) ListRoles() ([]Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := make([]Role, 0, len(m.roles))
+	for _, role := range m.roles {
+		role.Permissions = append([]string{}, role.Permissions...)
+		sort.Strings(role.Permissions)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (m *MemoryStore) ListRoleMembers(role string) ([]RoleMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := []RoleMember{}
+	for _, ur := range m.userRoles {
+		user, ok := m.users[int64(ur.userID)]
+		if ur.role != role || !ok {
+			continue
+		}
+		members = append(members, RoleMember{UserID: user.ID, Email: user.Email, GrantedAt: ur.grantedAt})
+	}
+	sort.SliceStable(members, func(i, j int) bool { return members[i].GrantedAt.Before(members[j].GrantedAt) })
+	return members, nil
+}
+
+// Comme l’INSERT IGNORE de Service.GrantRole : sans effet pour un compte inexistant.
+func (m *MemoryStore) GrantRole(userID int, role string, grantedBy int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.role(role) == nil {
+		return ErrUnknownRole
+	}
+	if _, ok := m.users[int64(userID)]; ok && !m.hasRole(userID, role) {
+		m.grantRole(userID, role, grantedBy)
+	}
+	return nil
+}
+
+func (m *MemoryStore) RevokeRole(userID int, role string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if role == RoleAdmin && m.countRole(RoleAdmin) <= 1 {
+		return false, nil
+	}
+	for i, ur := range m.userRoles {
+		if ur.userID == userID && ur.role == role {
+			m.userRoles = append(m.userRoles[:i], m.userRoles[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStore) BootstrapAdmin(email string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.userByEmail(email)
+	if user == nil || !user.IsVerified || m.countRole(RoleAdmin) > 0 {
+		return false, nil
+	}
+	m.grantRole(int(user.ID), RoleAdmin, 0)
+	return true, nil
+}
+
+func (m *MemoryStore) HasAdmin() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.countRole(RoleAdmin) > 0, nil
+}
+
+// ===== Administration des comptes =====
+
+func (u *memoryUser) adminView(now time.Time) AdminUser {
+	status := UserStatusActive
+	switch {
+	case u.Banned:
+		status = UserStatusBanned
+	case u.SuspendedUntil != nil && u.SuspendedUntil.After(now):
+		status = UserStatusSuspended
+	}
+	return AdminUser{
+		ID:               u.ID,
+		Email:            u.Email,
+		Name:             u.Name,
+		AvatarURL:        u.AvatarURL,
+		Verified:         u.IsVerified,
+		HasPassword:      u.Password.Valid,
+		CreatedAt:        u.CreatedAt,
+		Status:           status,
+		SuspendedUntil:   u.SuspendedUntil,
+		SuspensionReason: u.suspensionReason,
+	}
+}
+
+func (m *MemoryStore) ListUsers(filter UserFilter) ([]AdminUser, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	query := strings.ToLower(filter.Query)
+	matched := []AdminUser{}
+	for _, user := range m.sortedUsers() {
+		view := user.adminView(now)
+		if query != "" && !strings.Contains(strings.ToLower(view.Email), query) && !strings.Contains(strings.ToLower(view.Name), query) {
+			continue
+		}
+		if filter.Status != "" && view.Status != filter.Status {
+			continue
+		}
+		if filter.Verified != nil && view.Verified != *filter.Verified {
+			continue
+		}
+		matched = append(matched, view)
+	}
+	// ORDER BY created_at DESC, id DESC
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+
+	start, end := page(len(matched), filter.Limit, filter.Offset)
+	return matched[start:end], len(matched), nil
+}
+
+func (m *MemoryStore) GetAdminUser(userID int) (*AdminUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[int64(userID)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	view := user.adminView(time.Now())
+	return &view, nil
+}
+
+func (m *MemoryStore) SuspendUser(userID int, until *time.Time, banned bool, reason string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[int64(userID)]; ok {
+		user.SuspendedUntil = until
+		user.Banned = banned
+		user.suspensionReason = reason
+	}
+	return m.deleteSessions(func(s *memorySession) bool { return s.userID == userID }), nil
+}
+
+func (m *MemoryStore) ReinstateUser(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[int64(userID)]; ok {
+		user.SuspendedUntil = nil
+		user.Banned = false
+		user.suspensionReason = ""
+	}
+	return nil
+}
+
+func (m *MemoryStore) RevokeAllUserSessions(userID int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteSessions(func(s *memorySession) bool { return s.userID == userID }), nil
+}
+
+func (m *MemoryStore) MarkUserVerifiedByID(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[int64(userID)]
+	if !ok {
+		return nil
+	}
+	user.IsVerified = true
+	kept := m.verificationCodes[:0]
+	for _, c := range m.verificationCodes {
+		if !strings.EqualFold(c.email, user.Email) {
+			kept = append(kept, c)
+		}
+	}
+	m.verificationCodes = kept
+	return nil
+}
+
+// ===== Signalements =====
+
+func (m *MemoryStore) CreateReport(reporterID int, reportType, target, description string, notification mailer.Message) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, report := range m.reports {
+		if report.ReporterID == int64(reporterID) && report.Type == reportType && report.Target == target {
+			return report.ID, ErrDuplicateReport
+		}
+	}
+
+	now := time.Now().UTC()
+	report := &memoryReport{Report: Report{
+		ID:          m.nextID("reports"),
+		ReporterID:  int64(reporterID),
+		Type:        reportType,
+		Target:      target,
+		Description: description,
+		Status:      ReportOpen,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}}
+	m.reports = append(m.reports, report)
+	if len(notification.To) > 0 {
+		m.enqueueEmail(notification)
+	}
+	return report.ID, nil
+}
+
+func (m *MemoryStore) report(id int64) *memoryReport {
+	for _, report := range m.reports {
+		if report.ID == id {
+			return report
+		}
+	}
+	return nil
+}
+
+// Copie d’un signalement, avec l’email du signaleur (LEFT JOIN users).
+func (m *MemoryStore) reportView(report *memoryReport) Report {
+	view := report.Report
+	if reporter, ok := m.users[report.ReporterID]; ok {
+		view.ReporterEmail = reporter.Email
+	}
+	if report.AssigneeID != nil {
+		assignee := *report.AssigneeID
+		view.AssigneeID = &assignee
+	}
+	if report.ResolvedAt != nil {
+		resolvedAt := *report.ResolvedAt
+		view.ResolvedAt = &resolvedAt
+	}
+	return view
+}
+
+func (m *MemoryStore) ListReportsByReporter(reporterID int) ([]Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reports := []Report{}
+	for i := len(m.reports) - 1; i >= 0; i-- {
+		if m.reports[i].ReporterID == int64(reporterID) {
+			reports = append(reports, m.reportView(m.reports[i]))
+		}
+	}
+	return reports, nil
+}
+
+func (m *MemoryStore) ListReports(filter ReportFilter) ([]Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reports := []Report{}
+	for _, report := range m.reports {
+		if filter.Status != "" && report.Status != filter.Status {
+			continue
+		}
+		if filter.Type != "" && report.Type != filter.Type {
+			continue
+		}
+		switch {
+		case filter.AssigneeID == -1 && report.AssigneeID != nil:
+			continue
+		case filter.AssigneeID > 0 && (report.AssigneeID == nil || *report.AssigneeID != filter.AssigneeID):
+			continue
+		}
+		reports = append(reports, m.reportView(report))
+	}
+	// ORDER BY resolved_at IS NOT NULL, created_at ASC : les non résolus d’abord
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].ResolvedAt == nil && reports[j].ResolvedAt != nil
+	})
+
+	start, end := page(len(reports), filter.Limit, filter.Offset)
+	return reports[start:end], nil
+}
+
+func (m *MemoryStore) GetReport(id int64) (*Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := m.report(id)
+	if report == nil {
+		return nil, sql.ErrNoRows
+	}
+	view := m.reportView(report)
+	return &view, nil
+}
+
+func (m *MemoryStore) UpdateReportStatus(id int64, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := m.report(id)
+	if report == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	report.Status = status
+	report.UpdatedAt = now
+	switch {
+	case status != ReportActioned && status != ReportDismissed:
+		report.ResolvedAt = nil
+	case report.ResolvedAt == nil:
+		report.ResolvedAt = &now
+	}
+	return nil
+}
+
+func (m *MemoryStore) AssignReport(id int64, assigneeID *int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := m.report(id)
+	if report == nil {
+		return nil
+	}
+	report.AssigneeID = nil
+	if assigneeID != nil {
+		assignee := *assigneeID
+		report.AssigneeID = &assignee
+	}
+	report.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (m *MemoryStore) AddReportComment(reportID int64, authorID int, body string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := m.report(reportID)
+	if report == nil {
+		return 0, sql.ErrNoRows
+	}
+	comment := ReportComment{
+		ID:        m.nextID("report_comments"),
+		AuthorID:  int64(authorID),
+		Body:      body,
+		CreatedAt: time.Now().UTC(),
+	}
+	report.comments = append(report.comments, comment)
+	return comment.ID, nil
+}
+
+func (m *MemoryStore) ListReportComments(reportID int64) ([]ReportComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comments := []ReportComment{}
+	if report := m.report(reportID); report != nil {
+		for _, comment := range report.comments {
+			if author, ok := m.users[comment.AuthorID]; ok {
+				comment.AuthorEmail = author.Email
+			}
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+// ===== File d’envoi des e-mails =====
+
+func (m *MemoryStore) enqueueEmail(msg mailer.Message) {
+	now := time.Now().UTC()
+	msg.To = append([]string(nil), msg.To...)
+	m.outbox = append(m.outbox, &memoryOutboxEmail{
+		OutboxEmail: OutboxEmail{
+			ID:            m.nextID("email_outbox"),
+			Template:      msg.Template,
+			Recipient:     strings.Join(msg.To, ", "),
+			Subject:       msg.Subject,
+			Status:        OutboxPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		},
+		message: msg,
+	})
+}
+
+func (m *MemoryStore) outboxEmail(id int64) *memoryOutboxEmail {
+	for _, email := range m.outbox {
+		if email.ID == id {
+			return email
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) EnqueueEmail(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enqueueEmail(msg)
+	return nil
+}
+
+func (m *MemoryStore) ClaimOutboxEmails(limit int, lease time.Duration) ([]QueuedEmail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []*memoryOutboxEmail
+	for _, email := range m.outbox {
+		if email.Status == OutboxPending && !email.NextAttemptAt.After(now) {
+			due = append(due, email)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var emails []QueuedEmail
+	for _, email := range due {
+		email.Attempts++
+		email.NextAttemptAt = now.Add(lease)
+		msg := email.message
+		msg.To = append([]string(nil), msg.To...)
+		emails = append(emails, QueuedEmail{ID: email.ID, Attempts: email.Attempts, Message: msg})
+	}
+	return emails, nil
+}
+
+func (m *MemoryStore) MarkOutboxEmailSent(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if email := m.outboxEmail(id); email != nil {
+		now := time.Now().UTC()
+		email.Status = OutboxSent
+		email.SentAt = &now
+		email.LastError = ""
+	}
+	return nil
+}
+
+func (m *MemoryStore) MarkOutboxEmailFailed(id int64, lastError string, nextAttemptAt time.Time, dead bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if email := m.outboxEmail(id); email != nil {
+		email.Status = OutboxPending
+		if dead {
+			email.Status = OutboxDead
+		}
+		email.LastError = truncate(lastError, 1000)
+		email.NextAttemptAt = nextAttemptAt
+	}
+	return nil
+}
+
+func (m *MemoryStore) ListOutboxEmails(status string, limit, offset int) ([]OutboxEmail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	emails := []OutboxEmail{}
+	for i := len(m.outbox) - 1; i >= 0; i-- {
+		if status == "" || m.outbox[i].Status == status {
+			emails = append(emails, m.outbox[i].OutboxEmail)
+		}
+	}
+	start, end := page(len(emails), limit, offset)
+	return emails[start:end], nil
+}
+
+func (m *MemoryStore) CountOutboxEmails() (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := map[string]int{OutboxPending: 0, OutboxSent: 0, OutboxDead: 0}
+	for _, email := range m.outbox {
+		counts[email.Status]++
+	}
+	return counts, nil
+}
+
+func (m *MemoryStore) RetryOutboxEmail(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	email := m.outboxEmail(id)
+	if email == nil || email.Status != OutboxDead {
+		return false, nil
+	}
+	email.Status = OutboxPending
+	email.Attempts = 0
+	email.NextAttemptAt = time.Now().UTC()
+	return true, nil
+}
+
+// ===== Journal d’audit =====
+
+// Les métadonnées passent par JSON, comme la colonne metadata : les nombres reviennent en float64.
+func (m *MemoryStore) RecordAuthEvent(event AuthEvent) error {
+	var metadata map[string]interface{}
+	if len(event.Metadata) > 0 {
+		raw, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw, &metadata); err != nil {
+			return err
+		}
+	}
+	event.Metadata = metadata
+	event.UserAgent = truncate(event.UserAgent, 255)
+	event.Email = truncate(event.Email, 100)
+	if event.ActorID != nil {
+		actorID := *event.ActorID
+		event.ActorID = &actorID
+	}
+	if event.UserID != nil {
+		userID := *event.UserID
+		event.UserID = &userID
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.ID = m.nextID("auth_events")
+	event.CreatedAt = time.Now().UTC()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *MemoryStore) ListAuthEvents(filter AuthEventFilter) ([]AuthEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []AuthEvent{}
+	for i := len(m.events) - 1; i >= 0; i-- {
+		event := m.events[i]
+		switch {
+		case filter.UserID != 0 && (event.UserID == nil || *event.UserID != filter.UserID),
+			filter.ActorID != 0 && (event.ActorID == nil || *event.ActorID != filter.ActorID),
+			filter.Event != "" && event.Event != filter.Event,
+			filter.Outcome != "" && event.Outcome != filter.Outcome,
+			filter.IPAddress != "" && event.IPAddress != filter.IPAddress,
+			filter.Since != nil && event.CreatedAt.Before(*filter.Since),
+			filter.Until != nil && !event.CreatedAt.Before(*filter.Until):
+			continue
+		}
+		events = append(events, event)
+	}
+	start, end := page(len(events), filter.Limit, filter.Offset)
+	return events[start:end], nil
+}
@@ -0,0 +1,620 @@
+/*
+Ce fichier regroupe les opérations de MemoryStore liées aux facteurs d’authentification
+et au fournisseur OpenID Connect : TOTP et codes de récupération, challenges de connexion,
+passkeys, wallets SIWE, clients / consentements / codes OAuth et clés de signature.
+*/
+
+package database
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"auth/internal/auth"
+)
+
+type memoryRecoveryCode struct {
+	userID   int
+	codeHash string
+	used     bool
+}
+
+type memoryLoginChallenge struct {
+	LoginChallenge
+	tokenHash string
+}
+
+type memoryCeremony struct {
+	userID    int
+	ceremony  string
+	data      []byte
+	expiresAt time.Time
+}
+
+type memoryWallet struct {
+	Wallet
+	userID int
+}
+
+type memoryConsent struct {
+	userID    int
+	clientID  string
+	scope     string
+	grantedAt time.Time
+}
+
+type memoryAuthorization struct {
+	OAuthAuthorization
+	expiresAt time.Time
+}
+
+// ===== Double authentification (TOTP) =====
+
+func (m *MemoryStore) GetTOTPEnrollment(userID int) (*TOTPEnrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	enrollment, ok := m.totp[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *enrollment
+	return &found, nil
+}
+
+func (m *MemoryStore) HasTOTPEnabled(userID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	enrollment, ok := m.totp[userID]
+	return ok && enrollment.Confirmed, nil
+}
+
+func (m *MemoryStore) SaveTOTPSecret(userID int, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	enrollment, ok := m.totp[userID]
+	if !ok {
+		m.totp[userID] = &TOTPEnrollment{UserID: userID, Secret: secret}
+		return nil
+	}
+	if !enrollment.Confirmed {
+		enrollment.Secret = secret
+	}
+	return nil
+}
+
+func (m *MemoryStore) ConfirmTOTP(userID int, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if enrollment, ok := m.totp[userID]; ok {
+		enrollment.Confirmed = true
+		enrollment.LastStep = step
+	}
+	return nil
+}
+
+func (m *MemoryStore) MarkTOTPStepUsed(userID int, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	enrollment, ok := m.totp[userID]
+	if !ok || enrollment.LastStep >= step {
+		return false, nil
+	}
+	enrollment.LastStep = step
+	return true, nil
+}
+
+func (m *MemoryStore) DeleteTOTP(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteRecoveryCodes(userID)
+	delete(m.totp, userID)
+	return nil
+}
+
+func (m *MemoryStore) deleteRecoveryCodes(userID int) {
+	kept := m.recoveryCodes[:0]
+	for _, code := range m.recoveryCodes {
+		if code.userID != userID {
+			kept = append(kept, code)
+		}
+	}
+	m.recoveryCodes = kept
+}
+
+func (m *MemoryStore) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, hash := range codeHashes {
+		if seen[hash] {
+			return errDuplicateEntry
+		}
+		seen[hash] = true
+	}
+
+	m.deleteRecoveryCodes(userID)
+	for _, hash := range codeHashes {
+		m.recoveryCodes = append(m.recoveryCodes, &memoryRecoveryCode{userID: userID, codeHash: hash})
+	}
+	return nil
+}
+
+func (m *MemoryStore) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, code := range m.recoveryCodes {
+		if code.userID == userID && code.codeHash == codeHash && !code.used {
+			code.used = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStore) CountRecoveryCodes(userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, code := range m.recoveryCodes {
+		if code.userID == userID && !code.used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryStore) CreateLoginChallenge(userID int, tokenHash string, rememberMe bool, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	kept := m.loginChallenges[:0]
+	for _, challenge := range m.loginChallenges {
+		if challenge.tokenHash == tokenHash {
+			return errDuplicateEntry
+		}
+		if !challenge.ExpiresAt.Before(now) {
+			kept = append(kept, challenge)
+		}
+	}
+	m.loginChallenges = append(kept, &memoryLoginChallenge{
+		LoginChallenge: LoginChallenge{
+			ID:         m.nextID("login_challenges"),
+			UserID:     userID,
+			RememberMe: rememberMe,
+			ExpiresAt:  expiresAt,
+		},
+		tokenHash: tokenHash,
+	})
+	return nil
+}
+
+func (m *MemoryStore) GetLoginChallenge(tokenHash string) (*LoginChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, challenge := range m.loginChallenges {
+		if challenge.tokenHash == tokenHash && challenge.ExpiresAt.After(now) && challenge.Attempts < MaxLoginChallengeAttempts {
+			found := challenge.LoginChallenge
+			return &found, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStore) ReserveLoginChallengeAttempt(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, challenge := range m.loginChallenges {
+		if challenge.ID == id && challenge.Attempts < MaxLoginChallengeAttempts && challenge.ExpiresAt.After(time.Now()) {
+			challenge.Attempts++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStore) ConsumeLoginChallenge(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, challenge := range m.loginChallenges {
+		if challenge.ID == id {
+			m.loginChallenges = append(m.loginChallenges[:i], m.loginChallenges[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ===== Passkeys (WebAuthn) =====
+
+func (m *MemoryStore) SaveWebAuthnCredential(userID int, credentialID []byte, name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, credential := range m.webAuthnCredentials {
+		if bytes.Equal(credential.CredentialID, credentialID) {
+			return errDuplicateEntry
+		}
+	}
+	m.webAuthnCredentials = append(m.webAuthnCredentials, &WebAuthnCredential{
+		ID:           m.nextID("webauthn_credentials"),
+		UserID:       userID,
+		CredentialID: bytes.Clone(credentialID),
+		Name:         truncate(name, 100),
+		Data:         bytes.Clone(data),
+		CreatedAt:    time.Now().UTC(),
+	})
+	return nil
+}
+
+func (m *MemoryStore) ListWebAuthnCredentials(userID int) ([]WebAuthnCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credentials := []WebAuthnCredential{}
+	for _, credential := range m.webAuthnCredentials {
+		if credential.UserID == userID {
+			listed := *credential
+			listed.CredentialID = bytes.Clone(credential.CredentialID)
+			listed.Data = bytes.Clone(credential.Data)
+			credentials = append(credentials, listed)
+		}
+	}
+	return credentials, nil
+}
+
+func (m *MemoryStore) UpdateWebAuthnCredential(credentialID []byte, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, credential := range m.webAuthnCredentials {
+		if bytes.Equal(credential.CredentialID, credentialID) {
+			credential.Data = bytes.Clone(data)
+			credential.LastUsedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteWebAuthnCredential(userID int, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, credential := range m.webAuthnCredentials {
+		if credential.ID == id && credential.UserID == userID {
+			m.webAuthnCredentials = append(m.webAuthnCredentials[:i], m.webAuthnCredentials[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStore) SaveWebAuthnCeremony(idHash string, userID int, ceremony string, data []byte, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for hash, c := range m.webAuthnCeremonies {
+		if c.expiresAt.Before(now) {
+			delete(m.webAuthnCeremonies, hash)
+		}
+	}
+	if _, ok := m.webAuthnCeremonies[idHash]; ok {
+		return errDuplicateEntry
+	}
+	m.webAuthnCeremonies[idHash] = &memoryCeremony{userID: userID, ceremony: ceremony, data: bytes.Clone(data), expiresAt: expiresAt}
+	return nil
+}
+
+func (m *MemoryStore) ConsumeWebAuthnCeremony(idHash string, ceremony string) (int, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.webAuthnCeremonies[idHash]
+	if !ok || c.ceremony != ceremony || !c.expiresAt.After(time.Now()) {
+		return 0, nil, sql.ErrNoRows
+	}
+	delete(m.webAuthnCeremonies, idHash)
+	return c.userID, c.data, nil
+}
+
+// ===== Wallets (Sign-In with Ethereum) =====
+
+func (m *MemoryStore) SaveSIWENonce(nonce string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for n, nonceExpiresAt := range m.siweNonces {
+		if nonceExpiresAt.Before(now) {
+			delete(m.siweNonces, n)
+		}
+	}
+	if _, ok := m.siweNonces[nonce]; ok {
+		return errDuplicateEntry
+	}
+	m.siweNonces[nonce] = expiresAt
+	return nil
+}
+
+func (m *MemoryStore) ConsumeSIWENonce(nonce string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt, ok := m.siweNonces[nonce]
+	if !ok || !expiresAt.After(time.Now()) {
+		return false, nil
+	}
+	delete(m.siweNonces, nonce)
+	return true, nil
+}
+
+func (m *MemoryStore) wallet(address string) *memoryWallet {
+	address = strings.ToLower(address)
+	for _, wallet := range m.wallets {
+		if wallet.Address == address {
+			return wallet
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) FindUserByWallet(address string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wallet := m.wallet(address)
+	if wallet == nil {
+		return 0, sql.ErrNoRows
+	}
+	return wallet.userID, nil
+}
+
+// Comme l’ON DUPLICATE KEY de Service.LinkWallet : une adresse liée à un autre compte reste inchangée.
+func (m *MemoryStore) LinkWallet(userID int, address string, chainID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	if wallet := m.wallet(address); wallet != nil {
+		if wallet.userID == userID {
+			wallet.VerifiedAt = now
+		}
+		return nil
+	}
+	m.wallets = append(m.wallets, &memoryWallet{
+		Wallet: Wallet{Address: strings.ToLower(address), ChainID: chainID, VerifiedAt: now},
+		userID: userID,
+	})
+	return nil
+}
+
+func (m *MemoryStore) ListUserWallets(userID int) ([]Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wallets := []Wallet{}
+	for _, wallet := range m.wallets {
+		if wallet.userID == userID {
+			wallets = append(wallets, wallet.Wallet)
+		}
+	}
+	sort.SliceStable(wallets, func(i, j int) bool { return wallets[i].VerifiedAt.Before(wallets[j].VerifiedAt) })
+	return wallets, nil
+}
+
+func (m *MemoryStore) UnlinkWallet(userID int, address string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	address = strings.ToLower(address)
+	for i, wallet := range m.wallets {
+		if wallet.userID == userID && wallet.Address == address {
+			m.wallets = append(m.wallets[:i], m.wallets[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ===== Fournisseur OpenID Connect =====
+
+func (m *MemoryStore) CreateOAuthClient(client OAuthClient) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.oauthClients[client.ID]; ok {
+		return errDuplicateEntry
+	}
+	client.RedirectURIs = append([]string(nil), client.RedirectURIs...)
+	client.CreatedAt = time.Now().UTC()
+	m.oauthClients[client.ID] = &client
+	return nil
+}
+
+func (m *MemoryStore) GetOAuthClient(clientID string) (*OAuthClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.oauthClients[clientID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *client
+	found.RedirectURIs = append([]string(nil), client.RedirectURIs...)
+	return &found, nil
+}
+
+func (m *MemoryStore) consent(userID int, clientID string) *memoryConsent {
+	for _, consent := range m.oauthConsents {
+		if consent.userID == userID && consent.clientID == clientID {
+			return consent
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetOAuthConsent(userID int, clientID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if consent := m.consent(userID, clientID); consent != nil {
+		return consent.scope, nil
+	}
+	return "", nil
+}
+
+func (m *MemoryStore) SaveOAuthConsent(userID int, clientID, scope string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	if consent := m.consent(userID, clientID); consent != nil {
+		consent.scope = scope
+		consent.grantedAt = now
+		return nil
+	}
+	m.oauthConsents = append(m.oauthConsents, &memoryConsent{userID: userID, clientID: clientID, scope: scope, grantedAt: now})
+	return nil
+}
+
+func (m *MemoryStore) ListOAuthConsents(userID int) ([]OAuthConsent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	consents := []OAuthConsent{}
+	for _, consent := range m.oauthConsents {
+		client, ok := m.oauthClients[consent.clientID]
+		if consent.userID != userID || !ok {
+			continue
+		}
+		consents = append(consents, OAuthConsent{
+			ClientID:   consent.clientID,
+			ClientName: client.Name,
+			Scope:      consent.scope,
+			GrantedAt:  consent.grantedAt,
+		})
+	}
+	sort.SliceStable(consents, func(i, j int) bool { return consents[i].GrantedAt.After(consents[j].GrantedAt) })
+	return consents, nil
+}
+
+func (m *MemoryStore) DeleteOAuthConsent(userID int, clientID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, consent := range m.oauthConsents {
+		if consent.userID == userID && consent.clientID == clientID {
+			m.oauthConsents = append(m.oauthConsents[:i], m.oauthConsents[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStore) SaveOAuthAuthorizationRequest(idHash string, a OAuthAuthorization, expiresAt time.Time) error {
+	return m.saveOAuthAuthorization(m.oauthRequests, idHash, a, expiresAt)
+}
+
+func (m *MemoryStore) ConsumeOAuthAuthorizationRequest(idHash string) (*OAuthAuthorization, error) {
+	return m.consumeOAuthAuthorization(m.oauthRequests, idHash)
+}
+
+func (m *MemoryStore) SaveOAuthAuthorizationCode(codeHash string, a OAuthAuthorization, expiresAt time.Time) error {
+	return m.saveOAuthAuthorization(m.oauthCodes, codeHash, a, expiresAt)
+}
+
+func (m *MemoryStore) ConsumeOAuthAuthorizationCode(codeHash string) (*OAuthAuthorization, error) {
+	return m.consumeOAuthAuthorization(m.oauthCodes, codeHash)
+}
+
+func (m *MemoryStore) saveOAuthAuthorization(table map[string]*memoryAuthorization, hash string, a OAuthAuthorization, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for h, pending := range table {
+		if pending.expiresAt.Before(now) {
+			delete(table, h)
+		}
+	}
+	if _, ok := table[hash]; ok {
+		return errDuplicateEntry
+	}
+	table[hash] = &memoryAuthorization{OAuthAuthorization: a, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *MemoryStore) consumeOAuthAuthorization(table map[string]*memoryAuthorization, hash string) (*OAuthAuthorization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending, ok := table[hash]
+	if !ok || !pending.expiresAt.After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+	delete(table, hash)
+	a := pending.OAuthAuthorization
+	return &a, nil
+}
+
+// ===== Clés de signature des jetons d’accès =====
+
+func (m *MemoryStore) SaveSigningKey(key auth.SigningKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.signingKeys {
+		if stored.Key.ID == key.ID {
+			return errDuplicateEntry
+		}
+	}
+	key.PrivateKey = ed25519.PrivateKey(bytes.Clone(key.PrivateKey))
+	m.signingKeys = append(m.signingKeys, StoredSigningKey{Key: key, CreatedAt: time.Now().UTC()})
+	return nil
+}
+
+func (m *MemoryStore) ListSigningKeys(since time.Time) ([]StoredSigningKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []StoredSigningKey{}
+	for _, stored := range m.signingKeys {
+		if stored.CreatedAt.After(since) && len(stored.Key.PrivateKey) == ed25519.PrivateKeySize {
+			keys = append(keys, stored)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (m *MemoryStore) DeleteSigningKeysBefore(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.signingKeys[:0]
+	for _, stored := range m.signingKeys {
+		if stored.CreatedAt.After(before) {
+			kept = append(kept, stored)
+		}
+	}
+	m.signingKeys = kept
+	return nil
+}
@@ -0,0 +1,228 @@
+/*
+Ce paquet contient les migrations du schéma, embarquées dans le binaire (embed.FS).
+
+Chaque base a son propre jeu de migrations (mysql/, postgres/), avec les mêmes numéros et les mêmes noms.
+Chaque migration est une paire de fichiers numérotés :
+
+	0002_add_something.up.sql    appliquée par Up
+	0002_add_something.down.sql  appliquée par Down (annulation)
+
+Les numéros sont strictement croissants et une migration appliquée n’est jamais modifiée :
+tout changement de schéma passe par une nouvelle migration, écrite pour les deux bases.
+*/
+
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"auth/internal/database/dialect"
+)
+
+//go:embed mysql/*.sql postgres/*.sql
+var files embed.FS
+
+// Dossiers des migrations de chaque base dans files.
+const (
+	MySQLDir    = "mysql"
+	PostgresDir = "postgres"
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations MySQL embarquées, triées par version.
+func MySQL() ([]Migration, error) {
+	return Load(files, MySQLDir)
+}
+
+// Migrations PostgreSQL embarquées, triées par version.
+func Postgres() ([]Migration, error) {
+	return Load(files, PostgresDir)
+}
+
+func ForDialect(d dialect.Dialect) ([]Migration, error) {
+	if d == dialect.Postgres {
+		return Postgres()
+	}
+	return MySQL()
+}
+
+/*
+Lit les fichiers <version>_<nom>.up.sql et <version>_<nom>.down.sql d’un dossier.
+Une version sans fichier up, ou présente deux fois, est une erreur.
+*/
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		version, name, direction, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, name)
+		}
+
+		switch direction {
+		case "up":
+			if m.Up != "" {
+				return nil, fmt.Errorf("migration %d has two up files", version)
+			}
+			m.Up = string(content)
+		case "down":
+			if m.Down != "" {
+				return nil, fmt.Errorf("migration %d has two down files", version)
+			}
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Découpe "0002_add_something.up.sql" en 2, "add_something", "up".
+func parseFileName(fileName string) (int64, string, string, error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+
+	var direction string
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("migration %s: expected a .up.sql or .down.sql suffix", fileName)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	number, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", "", fmt.Errorf("migration %s: expected <version>_<name>", fileName)
+	}
+	version, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration %s: invalid version %q", fileName, number)
+	}
+	return version, name, direction, nil
+}
+
+// Découpe un script en instructions sur les « ; » qui ne sont ni dans une chaîne
+// ni dans un commentaire (-- ou bloc /* */). Les commentaires sont retirés.
+// Les corps $$ … $$ (ou $tag$ … $tag$) des fonctions PostgreSQL sont conservés tels quels.
+// Les drivers n’exécutant qu’une instruction par requête, chaque instruction est envoyée séparément.
+func SplitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      rune // ', " ou ` en cours, 0 sinon
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		if quote != 0 {
+			current.WriteRune(c)
+			if c == '\\' && quote != '`' && i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			current.WriteRune(c)
+		case c == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+			current.WriteRune(' ')
+		case c == '$' && dollarTag(runes[i:]) != nil:
+			tag := dollarTag(runes[i:])
+			end := len(runes)
+			for j := i + len(tag); j+len(tag) <= len(runes); j++ {
+				if string(runes[j:j+len(tag)]) == string(tag) {
+					end = j + len(tag)
+					break
+				}
+			}
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+		case c == ';':
+			flush()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// Délimiteur $$ ou $tag$ au début de runes (chaîne PostgreSQL), nil sinon.
+func dollarTag(runes []rune) []rune {
+	for j := 1; j < len(runes); j++ {
+		c := runes[j]
+		if c == '$' {
+			return runes[:j+1]
+		}
+		if c != '_' && !unicode.IsLetter(c) && (j == 1 || !unicode.IsDigit(c)) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package migrations
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	sets := map[string]func() ([]Migration, error){MySQLDir: MySQL, PostgresDir: Postgres}
+	for dir, load := range sets {
+		migrations, err := load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(migrations) == 0 || migrations[0].Version != InitialVersion {
+			t.Fatalf("%s: expected the initial schema as first migration, got %+v", dir, migrations)
+		}
+		for i, m := range migrations {
+			if m.Down == "" {
+				t.Errorf("%s: migration %d (%s) has no down file", dir, m.Version, m.Name)
+			}
+			if i > 0 && m.Version <= migrations[i-1].Version {
+				t.Errorf("%s: migrations are not sorted: %d after %d", dir, m.Version, migrations[i-1].Version)
+			}
+			if len(SplitStatements(m.Up)) == 0 {
+				t.Errorf("%s: migration %d (%s) has no statement", dir, m.Version, m.Name)
+			}
+		}
+	}
+}
+
+// Chaque changement de schéma doit exister pour les deux bases, sous le même numéro.
+func TestMySQLAndPostgresMigrationsMatch(t *testing.T) {
+	mysql, err := MySQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := Postgres()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(migrations []Migration) []string {
+		var out []string
+		for _, m := range migrations {
+			out = append(out, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+		return out
+	}
+	if !reflect.DeepEqual(names(mysql), names(postgres)) {
+		t.Fatalf("mysql and postgres migrations differ:\n%v\n%v", names(mysql), names(postgres))
+	}
+}
+
+var createTablePattern = regexp.MustCompile("(?i)^CREATE TABLE `?(\\w+)`?")
+
+// Tables créées par le script, dans l’ordre.
+func createdTables(script string) []string {
+	var tables []string
+	for _, statement := range SplitStatements(script) {
+		if match := createTablePattern.FindStringSubmatch(statement); match != nil {
+			tables = append(tables, match[1])
+		}
+	}
+	return tables
+}
+
+// Baseline vérifie baselineTables et firstUpgradeTable : ils doivent suivre les scripts.
+func TestInitialMigrationIsTheBaselineSchema(t *testing.T) {
+	sets := map[string]func() ([]Migration, error){MySQLDir: MySQL, PostgresDir: Postgres}
+	for dir, load := range sets {
+		migrations, err := load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := createdTables(migrations[0].Up); !reflect.DeepEqual(got, baselineTables) {
+			t.Errorf("%s: initial migration creates %v, expected the baseline tables %v", dir, got, baselineTables)
+		}
+		if len(migrations) < 2 {
+			t.Fatalf("%s: expected migrations after the initial schema", dir)
+		}
+		if got := createdTables(migrations[1].Up); len(got) == 0 || got[0] != firstUpgradeTable {
+			t.Errorf("%s: migration %d should create %s first, got %v", dir, migrations[1].Version, firstUpgradeTable, got)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing up":   {"m/0001_init.down.sql": {Data: []byte("DROP TABLE t;")}},
+		"no direction": {"m/0001_init.sql": {Data: []byte("SELECT 1;")}},
+		"no version":   {"m/init.up.sql": {Data: []byte("SELECT 1;")}},
+		"two names": {
+			"m/0001_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"m/0001_other.up.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := Load(fsys, "m"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- commentaire ; ignoré
+CREATE TABLE t (
+  a varchar(10) DEFAULT 'x;y', -- point-virgule ; dans un commentaire
+  b int /* bloc ; */ NOT NULL
+);
+INSERT INTO t (a, b) VALUES ('it\'s;', 1);
+
+`
+	got := SplitStatements(script)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", len(got), got)
+	}
+	if !strings.Contains(got[0], "DEFAULT 'x;y'") || strings.Contains(got[0], "commentaire") {
+		t.Errorf("unexpected first statement %q", got[0])
+	}
+	if want := `INSERT INTO t (a, b) VALUES ('it\'s;', 1)`; !reflect.DeepEqual(got[1], want) {
+		t.Errorf("got %q, want %q", got[1], want)
+	}
+}
+
+func TestSplitStatementsKeepsDollarQuotedBodies(t *testing.T) {
+	script := `CREATE FUNCTION f() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'read-only; really';
+END;
+$$;
+CREATE TRIGGER t BEFORE DELETE ON t FOR EACH ROW EXECUTE FUNCTION f();`
+
+	got := SplitStatements(script)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", len(got), got)
+	}
+	if !strings.HasSuffix(got[0], "END;\n$$") {
+		t.Errorf("function body was split: %q", got[0])
+	}
+}
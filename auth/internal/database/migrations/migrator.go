@@ -0,0 +1,447 @@
+/*
+Ce fichier applique les migrations et tient la table schema_migrations à jour.
+
+Toutes les opérations prennent d’abord un verrou consultatif (GET_LOCK sous MySQL, pg_advisory_lock
+sous PostgreSQL) sur une connexion dédiée : plusieurs instances du service qui démarrent en même temps
+s’attendent au lieu de migrer en parallèle.
+
+Sous PostgreSQL, chaque migration s’exécute dans une transaction avec sa ligne de schema_migrations :
+si elle échoue, rien n’est appliqué et elle peut être relancée telle quelle.
+Le DDL MySQL n’est pas transactionnel : une migration y est marquée dirty pendant son exécution.
+Si elle échoue en cours de route, elle reste dirty et plus aucune migration n’est lancée
+tant que le schéma n’a pas été réparé à la main (voir Status).
+*/
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"auth/internal/database/dialect"
+)
+
+// Nom du verrou consultatif partagé par toutes les instances.
+const lockName = "auth_schema_migrations"
+
+// Version du schéma initial, celui de l’ancien SQL_DB.sql (voir Baseline).
+const InitialVersion = 1
+
+// Tables du schéma initial, qui doivent exister avant Baseline.
+var baselineTables = []string{"users", "sessions", "verification_codes"}
+
+// Première table ajoutée après le schéma initial : sa présence indique une base déjà mise à jour à la main.
+const firstUpgradeTable = "user_identities"
+
+// Attente maximale du verrou (en secondes) si une autre instance migre déjà.
+const lockTimeout = 60
+
+var ErrNothingToRollback = errors.New("no applied migration to roll back")
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect.Dialect
+	migrations []Migration
+}
+
+// État d’une migration connue (embarquée ou enregistrée en base).
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	name      string
+	dirty     bool
+	appliedAt time.Time
+}
+
+// Migrator avec les migrations embarquées du dialecte (mysql/ ou postgres/).
+func New(db *sql.DB, d dialect.Dialect) (*Migrator, error) {
+	migrations, err := ForDialect(d)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: d, migrations: migrations}, nil
+}
+
+// Connexion verrouillée ou transaction ouverte dessus (PostgreSQL).
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Exécute une requête écrite avec des « ? » sur la connexion verrouillée ou sa transaction.
+func (m *Migrator) exec(ctx context.Context, conn execer, query string, args ...interface{}) error {
+	_, err := conn.ExecContext(ctx, m.dialect.Rebind(query), args...)
+	return err
+}
+
+/*
+Applique, dans l’ordre, toutes les migrations qui ne le sont pas encore.
+Retourne le nombre de migrations appliquées.
+*/
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkDirty(applied); err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			if err := m.checkUnmanagedSchema(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := m.run(ctx, conn, migration, migration.Up, func(db execer) error {
+				return m.exec(ctx, db,
+					"UPDATE schema_migrations SET dirty = FALSE, applied_at = ? WHERE version = ?",
+					time.Now().UTC(), migration.Version,
+				)
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Annule la dernière migration appliquée et la retourne.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var rolledBack *Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkDirty(applied); err != nil {
+			return err
+		}
+
+		var last int64
+		for version := range applied {
+			if version > last {
+				last = version
+			}
+		}
+		if last == 0 {
+			return ErrNothingToRollback
+		}
+
+		migration, ok := m.find(last)
+		if !ok {
+			return fmt.Errorf("migration %d is applied but unknown to this binary", last)
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("migration %d (%s) has no down file", migration.Version, migration.Name)
+		}
+
+		err = m.run(ctx, conn, migration, migration.Down, func(db execer) error {
+			return m.exec(ctx, db, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back migration %d_%s", migration.Version, migration.Name)
+		rolledBack = &migration
+		return nil
+	})
+	return rolledBack, err
+}
+
+// État de chaque migration, par version croissante.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if a, ok := applied[migration.Version]; ok {
+				status.Applied = !a.dirty
+				status.Dirty = a.dirty
+				status.AppliedAt = &a.appliedAt
+				delete(applied, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		// Migrations enregistrées en base mais absentes du binaire (binaire plus ancien que le schéma)
+		for version, a := range applied {
+			appliedAt := a.appliedAt
+			statuses = append(statuses, Status{Version: version, Name: a.name, Applied: !a.dirty, Dirty: a.dirty, AppliedAt: &appliedAt})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+/*
+Marque les migrations jusqu’à version comme appliquées sans les exécuter,
+pour une base créée avant les migrations (ancien SQL_DB.sql).
+Refusé si des migrations sont déjà enregistrées, s’il manque une table du schéma initial
+ou si la base contient déjà les tables des migrations suivantes (Up échouerait ensuite sur ces tables).
+*/
+func (m *Migrator) Baseline(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) > 0 {
+			return errors.New("schema_migrations is not empty, baseline is only for databases without migration history")
+		}
+		if err := m.checkBaselineSchema(ctx, conn); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if err := m.exec(ctx, conn,
+				"INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, FALSE, ?)",
+				migration.Version, migration.Name, time.Now().UTC(),
+			); err != nil {
+				return err
+			}
+			log.Printf("Marked migration %d_%s as applied", migration.Version, migration.Name)
+		}
+		return nil
+	})
+}
+
+// Exécute fn avec le verrou consultatif, sur une connexion dédiée (le verrou est lié à la connexion).
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer m.unlock(conn)
+
+	createTable := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		   version bigint NOT NULL,
+		   name varchar(255) NOT NULL,
+		   dirty tinyint(1) NOT NULL DEFAULT '0',
+		   applied_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		   PRIMARY KEY (version)
+		 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci`
+	if m.dialect == dialect.Postgres {
+		createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		   version bigint NOT NULL PRIMARY KEY,
+		   name varchar(255) NOT NULL,
+		   dirty boolean NOT NULL DEFAULT FALSE,
+		   applied_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+		 )`
+	}
+	if err := m.exec(ctx, conn, createTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+/*
+Prend le verrou consultatif, en attendant au plus lockTimeout secondes.
+PostgreSQL n’a pas de délai d’attente sur pg_advisory_lock : pg_try_advisory_lock est retenté chaque seconde.
+*/
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) error {
+	timeout := fmt.Errorf("timed out waiting for the %s lock (another instance is migrating)", lockName)
+
+	if m.dialect != dialect.Postgres {
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&locked); err != nil {
+			return err
+		}
+		if locked.Int64 != 1 {
+			return timeout
+		}
+		return nil
+	}
+
+	deadline := time.Now().Add(lockTimeout * time.Second)
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", lockName).Scan(&locked); err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return timeout
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (m *Migrator) unlock(conn *sql.Conn) {
+	query := "SELECT RELEASE_LOCK(?)"
+	if m.dialect == dialect.Postgres {
+		query = "SELECT pg_advisory_unlock(hashtext(?))"
+	}
+	m.exec(context.Background(), conn, query, lockName)
+}
+
+/*
+Exécute le script d’une migration puis finish, qui enregistre le résultat dans schema_migrations.
+Sous PostgreSQL, le tout est fait dans une transaction : un échec annule le script et la ligne dirty.
+*/
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, script string, finish func(db execer) error) error {
+	if m.dialect != dialect.Postgres {
+		return m.runScript(ctx, conn, migration, script, finish)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := m.runScript(ctx, tx, migration, script, finish); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Marque la migration dirty, exécute le script instruction par instruction puis finish.
+func (m *Migrator) runScript(ctx context.Context, db execer, migration Migration, script string, finish func(db execer) error) error {
+	query := `INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, TRUE, ?)
+		 ON DUPLICATE KEY UPDATE dirty = TRUE`
+	if m.dialect == dialect.Postgres {
+		query = `INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, TRUE, ?)
+		 ON CONFLICT (version) DO UPDATE SET dirty = TRUE`
+	}
+	if err := m.exec(ctx, db, query, migration.Version, migration.Name, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	for _, statement := range SplitStatements(script) {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d (%s): %v", migration.Version, migration.Name, err)
+		}
+	}
+	return finish(db)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.dirty, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func checkDirty(applied map[int64]appliedMigration) error {
+	for version, a := range applied {
+		if a.dirty {
+			return fmt.Errorf("migration %d (%s) failed halfway: repair the schema by hand, then delete its schema_migrations row to retry it or set dirty = 0 to keep it", version, a.name)
+		}
+	}
+	return nil
+}
+
+// Une base sans historique mais avec des tables a été créée avec l’ancien SQL_DB.sql.
+func (m *Migrator) checkUnmanagedSchema(ctx context.Context, conn *sql.Conn) error {
+	exists, err := m.tableExists(ctx, conn, "users")
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("the database already has tables but no migration history: run the auth binary with -migrate baseline once")
+	}
+	return nil
+}
+
+// Vérifie que la base correspond au schéma initial avant de l’enregistrer comme appliqué.
+func (m *Migrator) checkBaselineSchema(ctx context.Context, conn *sql.Conn) error {
+	var missing []string
+	for _, table := range baselineTables {
+		exists, err := m.tableExists(ctx, conn, table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the database does not have the initial schema (missing tables: %s): create it from migration %d instead of running baseline", strings.Join(missing, ", "), InitialVersion)
+	}
+
+	upgraded, err := m.tableExists(ctx, conn, firstUpgradeTable)
+	if err != nil {
+		return err
+	}
+	if upgraded {
+		return fmt.Errorf("the database already has the %s table, which is newer than the initial schema: restore the initial schema or record the applied migrations in schema_migrations by hand", firstUpgradeTable)
+	}
+	return nil
+}
+
+// Table du schéma courant (DATABASE() sous MySQL, current_schema() sous PostgreSQL).
+func (m *Migrator) tableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	schema := "DATABASE()"
+	if m.dialect == dialect.Postgres {
+		schema = "current_schema()"
+	}
+	var count int
+	if err := conn.QueryRowContext(ctx,
+		m.dialect.Rebind("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = "+schema+" AND table_name = ?"),
+		table,
+	).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -1,21 +1,12 @@
--- MySQL Schema for miniprojet
--- Compatible with Docker initialization
-
-SET SQL_MODE = "NO_AUTO_VALUE_ON_ZERO";
-START TRANSACTION;
-SET time_zone = "+00:00";
-
-/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
-/*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;
-/*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION */;
-/*!40101 SET NAMES utf8mb4 */;
+-- Schéma initial du service auth (MySQL 8) : celui de l’ancien SQL_DB.sql, sans les DROP TABLE ni les SET.
+-- Une base créée avec ce fichier est enregistrée à cette version par `-migrate baseline`,
+-- puis mise à jour par les migrations suivantes.
 
 -- --------------------------------------------------------
 --
 -- Structure de la table `users`
 --
-DROP TABLE IF EXISTS `users`;
-CREATE TABLE IF NOT EXISTS `users` (
+CREATE TABLE `users` (
   `id` int NOT NULL AUTO_INCREMENT,
   `email` varchar(100) DEFAULT NULL,
   `password` varchar(255) DEFAULT NULL,
@@ -33,8 +24,7 @@ CREATE TABLE IF NOT EXISTS `users` (
 --
 -- Structure de la table `sessions`
 --
-DROP TABLE IF EXISTS `sessions`;
-CREATE TABLE IF NOT EXISTS `sessions` (
+CREATE TABLE `sessions` (
   `id` int NOT NULL AUTO_INCREMENT,
   `user_id` int NOT NULL,
   `session_token` varchar(191) NOT NULL,
@@ -49,8 +39,7 @@ CREATE TABLE IF NOT EXISTS `sessions` (
 --
 -- Structure de la table `verification_codes`
 --
-DROP TABLE IF EXISTS `verification_codes`;
-CREATE TABLE IF NOT EXISTS `verification_codes` (
+CREATE TABLE `verification_codes` (
   `id` int NOT NULL AUTO_INCREMENT,
   `email` varchar(100) NOT NULL,
   `code` varchar(6) NOT NULL,
@@ -68,9 +57,3 @@ CREATE TABLE IF NOT EXISTS `verification_codes` (
 --
 ALTER TABLE `sessions`
   ADD CONSTRAINT `fk_sessions_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
-
-COMMIT;
-
-/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
-/*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;
-/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;
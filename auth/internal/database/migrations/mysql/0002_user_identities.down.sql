@@ -0,0 +1,10 @@
+-- Remet users.google_id depuis user_identities (les autres fournisseurs sont perdus).
+
+ALTER TABLE `users`
+  ADD COLUMN `google_id` varchar(100) DEFAULT NULL AFTER `password`,
+  ADD UNIQUE KEY `google_id` (`google_id`);
+
+UPDATE `users` u JOIN `user_identities` i ON i.`user_id` = u.`id` AND i.`provider` = 'google'
+  SET u.`google_id` = i.`provider_user_id`;
+
+DROP TABLE IF EXISTS `user_identities`;
@@ -0,0 +1,31 @@
+-- Comptes externes (Google, GitHub, OIDC…) reliés à un utilisateur (MySQL 8)
+-- users.google_id est déplacé dans user_identities, les comptes Google déjà liés sont conservés.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `user_identities`
+-- (comptes Google, GitHub, Microsoft, GitLab, OIDC… reliés à un utilisateur)
+--
+CREATE TABLE `user_identities` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `provider` varchar(50) NOT NULL,
+  `provider_user_id` varchar(255) NOT NULL,
+  `email` varchar(100) DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `provider_user` (`provider`, `provider_user_id`),
+  KEY `fk_user_identities_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT INTO `user_identities` (`user_id`, `provider`, `provider_user_id`, `email`)
+  SELECT `id`, 'google', `google_id`, `email` FROM `users` WHERE `google_id` IS NOT NULL;
+
+ALTER TABLE `users` DROP INDEX `google_id`, DROP COLUMN `google_id`;
+
+-- --------------------------------------------------------
+--
+-- Contraintes pour les tables déchargées
+--
+ALTER TABLE `user_identities`
+  ADD CONSTRAINT `fk_user_identities_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,12 @@
+-- Remet la colonne session_token ; les sessions sont supprimées (leur empreinte ne redonne pas le jeton).
+
+DELETE FROM `sessions`;
+
+ALTER TABLE `sessions`
+  CHANGE `token_hash` `session_token` varchar(191) NOT NULL,
+  RENAME INDEX `token_hash` TO `session_token`,
+  DROP COLUMN `user_agent`,
+  DROP COLUMN `ip_address`,
+  DROP COLUMN `remember_me`,
+  DROP COLUMN `absolute_expires_at`,
+  DROP COLUMN `last_seen_at`;
@@ -0,0 +1,18 @@
+-- Empreinte des jetons de session et suivi des appareils (MySQL 8)
+-- Les jetons en clair ne peuvent pas être convertis : les sessions ouvertes sont supprimées
+-- (chacun se reconnecte).
+
+-- --------------------------------------------------------
+--
+-- Table `sessions` : empreinte du jeton (se_sess_…) et suivi des appareils
+--
+DELETE FROM `sessions`;
+
+ALTER TABLE `sessions`
+  CHANGE `session_token` `token_hash` char(64) NOT NULL, -- SHA-256 du jeton (se_sess_…), jamais le jeton en clair
+  RENAME INDEX `session_token` TO `token_hash`,
+  ADD COLUMN `user_agent` varchar(255) DEFAULT NULL AFTER `token_hash`,
+  ADD COLUMN `ip_address` varchar(45) DEFAULT NULL AFTER `user_agent`,
+  ADD COLUMN `remember_me` tinyint(1) DEFAULT '0' AFTER `ip_address`,
+  ADD COLUMN `absolute_expires_at` datetime NOT NULL AFTER `expires_at`,
+  ADD COLUMN `last_seen_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP AFTER `created_at`;
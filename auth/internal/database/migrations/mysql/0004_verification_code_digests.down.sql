@@ -0,0 +1,9 @@
+-- Remet la colonne code ; les codes en attente sont supprimés (leur empreinte ne redonne pas le code).
+
+DELETE FROM `verification_codes`;
+
+ALTER TABLE `verification_codes`
+  CHANGE `code_hash` `code` varchar(6) NOT NULL,
+  DROP COLUMN `attempts`,
+  DROP INDEX `idx_email_created`,
+  ADD KEY `idx_email_code` (`email`,`code`);
@@ -0,0 +1,15 @@
+-- Codes de vérification hachés et nombre d’essais (MySQL 8)
+-- Les codes en clair ne peuvent pas être convertis : les codes en attente sont supprimés
+-- (chacun en redemande un).
+
+-- --------------------------------------------------------
+--
+-- Table `verification_codes` : code haché et nombre d’essais
+--
+DELETE FROM `verification_codes`;
+
+ALTER TABLE `verification_codes`
+  CHANGE `code` `code_hash` char(64) NOT NULL, -- HMAC-SHA256 du code (VERIFICATION_CODE_KEY), jamais le code en clair
+  ADD COLUMN `attempts` int NOT NULL DEFAULT '0' AFTER `used`, -- mauvais codes saisis (code invalidé au-delà de la limite)
+  DROP INDEX `idx_email_code`,
+  ADD KEY `idx_email_created` (`email`,`created_at`);
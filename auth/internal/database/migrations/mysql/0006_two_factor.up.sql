@@ -0,0 +1,59 @@
+-- Authentification à deux facteurs : TOTP, codes de récupération et défi de connexion (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `user_totp`
+--
+CREATE TABLE `user_totp` (
+  `user_id` int NOT NULL,
+  `secret` varchar(64) NOT NULL,
+  `confirmed` tinyint(1) DEFAULT '0',
+  `last_used_step` bigint NOT NULL DEFAULT '0',
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `confirmed_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `totp_recovery_codes`
+--
+CREATE TABLE `totp_recovery_codes` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `used_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `user_code` (`user_id`,`code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `login_challenges`
+--
+CREATE TABLE `login_challenges` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `remember_me` tinyint(1) DEFAULT '0',
+  `attempts` int NOT NULL DEFAULT '0',
+  `expires_at` timestamp NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `token_hash` (`token_hash`),
+  KEY `fk_login_challenges_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Contraintes pour les tables déchargées
+--
+ALTER TABLE `user_totp`
+  ADD CONSTRAINT `fk_user_totp_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `totp_recovery_codes`
+  ADD CONSTRAINT `fk_totp_recovery_codes_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `login_challenges`
+  ADD CONSTRAINT `fk_login_challenges_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,40 @@
+-- Clés d’accès WebAuthn (passkeys) (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `webauthn_credentials`
+--
+CREATE TABLE `webauthn_credentials` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `credential_id` varbinary(1023) NOT NULL,
+  `name` varchar(100) DEFAULT NULL,
+  `credential` json NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_used_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `credential_id` (`credential_id`),
+  KEY `fk_webauthn_credentials_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `webauthn_ceremonies`
+--
+CREATE TABLE `webauthn_ceremonies` (
+  `id_hash` char(64) NOT NULL,
+  `user_id` int DEFAULT NULL,
+  `ceremony` varchar(20) NOT NULL,
+  `data` json NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id_hash`),
+  KEY `idx_expires` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Contraintes pour les tables déchargées
+--
+ALTER TABLE `webauthn_credentials`
+  ADD CONSTRAINT `fk_webauthn_credentials_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
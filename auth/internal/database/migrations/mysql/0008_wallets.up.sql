@@ -0,0 +1,36 @@
+-- Portefeuilles Ethereum et connexion Sign-In with Ethereum (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `user_wallets`
+--
+CREATE TABLE `user_wallets` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `address` char(42) NOT NULL,
+  `chain_id` bigint DEFAULT NULL,
+  `verified_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `address` (`address`),
+  KEY `fk_user_wallets_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `siwe_nonces`
+--
+CREATE TABLE `siwe_nonces` (
+  `nonce` varchar(32) NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`nonce`),
+  KEY `idx_expires` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Contraintes pour les tables déchargées
+--
+ALTER TABLE `user_wallets`
+  ADD CONSTRAINT `fk_user_wallets_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `oauth_authorization_codes`;
+DROP TABLE IF EXISTS `oauth_authorization_requests`;
+DROP TABLE IF EXISTS `oauth_consents`;
+DROP TABLE IF EXISTS `oauth_clients`;
+DROP TABLE IF EXISTS `signing_keys`;
@@ -0,0 +1,94 @@
+-- Clés de signature des jetons et fournisseur OpenID Connect (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `signing_keys`
+--
+CREATE TABLE `signing_keys` (
+  `kid` varchar(32) NOT NULL,
+  `private_key` varbinary(64) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`kid`),
+  KEY `idx_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `oauth_clients`
+--
+CREATE TABLE `oauth_clients` (
+  `client_id` varchar(64) NOT NULL,
+  `client_secret_hash` char(64) DEFAULT NULL,
+  `name` varchar(255) NOT NULL,
+  `redirect_uris` text NOT NULL,
+  `scopes` varchar(255) NOT NULL DEFAULT 'openid profile email',
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`client_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `oauth_consents`
+--
+CREATE TABLE `oauth_consents` (
+  `user_id` int NOT NULL,
+  `client_id` varchar(64) NOT NULL,
+  `scope` varchar(255) NOT NULL,
+  `granted_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`, `client_id`),
+  KEY `fk_oauth_consents_client` (`client_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `oauth_authorization_requests`
+--
+CREATE TABLE `oauth_authorization_requests` (
+  `request_hash` char(64) NOT NULL,
+  `user_id` int NOT NULL,
+  `client_id` varchar(64) NOT NULL,
+  `redirect_uri` varchar(2048) NOT NULL,
+  `scope` varchar(255) NOT NULL,
+  `state` varchar(512) NOT NULL DEFAULT '',
+  `nonce` varchar(512) NOT NULL DEFAULT '',
+  `code_challenge` varchar(128) NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`request_hash`),
+  KEY `fk_oauth_authorization_requests_user` (`user_id`),
+  KEY `idx_expires` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `oauth_authorization_codes`
+--
+CREATE TABLE `oauth_authorization_codes` (
+  `code_hash` char(64) NOT NULL,
+  `user_id` int NOT NULL,
+  `client_id` varchar(64) NOT NULL,
+  `redirect_uri` varchar(2048) NOT NULL,
+  `scope` varchar(255) NOT NULL,
+  `state` varchar(512) NOT NULL DEFAULT '',
+  `nonce` varchar(512) NOT NULL DEFAULT '',
+  `code_challenge` varchar(128) NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`code_hash`),
+  KEY `fk_oauth_authorization_codes_user` (`user_id`),
+  KEY `idx_expires` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Contraintes pour les tables déchargées
+--
+ALTER TABLE `oauth_consents`
+  ADD CONSTRAINT `fk_oauth_consents_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  ADD CONSTRAINT `fk_oauth_consents_client` FOREIGN KEY (`client_id`) REFERENCES `oauth_clients` (`client_id`) ON DELETE CASCADE;
+
+ALTER TABLE `oauth_authorization_requests`
+  ADD CONSTRAINT `fk_oauth_authorization_requests_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `oauth_authorization_codes`
+  ADD CONSTRAINT `fk_oauth_authorization_codes_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
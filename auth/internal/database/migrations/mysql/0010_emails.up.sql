@@ -0,0 +1,31 @@
+-- Langue des e-mails et file d’envoi (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Table `users` : langue des e-mails
+--
+ALTER TABLE `users`
+  ADD COLUMN `locale` varchar(10) DEFAULT NULL AFTER `verified`; -- langue des e-mails (fr, en) ; NULL : Accept-Language
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `email_outbox`
+-- (file d’envoi des e-mails, remplie dans la même transaction que le changement métier)
+--
+CREATE TABLE `email_outbox` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `template` varchar(50) NOT NULL DEFAULT '',
+  `sender` varchar(255) NOT NULL,
+  `recipient` varchar(255) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `html_body` mediumtext NOT NULL,
+  `text_body` mediumtext,
+  `status` enum('pending','sent','dead') NOT NULL DEFAULT 'pending',
+  `attempts` int NOT NULL DEFAULT '0',
+  `next_attempt_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_error` varchar(1000) DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `sent_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_status_next_attempt` (`status`,`next_attempt_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
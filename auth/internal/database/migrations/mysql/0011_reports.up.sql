@@ -0,0 +1,51 @@
+-- Signalements et leur modération (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `reports`
+-- (signalements de contrats / utilisateurs, un seul par signaleur et par cible)
+--
+CREATE TABLE `reports` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `reporter_id` int NOT NULL,
+  `type` enum('contract','user') NOT NULL,
+  `target` varchar(255) NOT NULL,
+  `description` text NOT NULL,
+  `status` enum('open','triaged','actioned','dismissed') NOT NULL DEFAULT 'open',
+  `assignee_id` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `resolved_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_reporter_target` (`reporter_id`,`type`,`target`),
+  KEY `fk_reports_assignee` (`assignee_id`),
+  KEY `idx_status_created` (`status`,`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `report_comments`
+-- (notes internes des modérateurs)
+--
+CREATE TABLE `report_comments` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `report_id` bigint NOT NULL,
+  `author_id` int NOT NULL,
+  `body` text NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `fk_report_comments_report` (`report_id`),
+  KEY `fk_report_comments_author` (`author_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- --------------------------------------------------------
+--
+-- Contraintes pour les tables déchargées
+--
+ALTER TABLE `reports`
+  ADD CONSTRAINT `fk_reports_reporter` FOREIGN KEY (`reporter_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  ADD CONSTRAINT `fk_reports_assignee` FOREIGN KEY (`assignee_id`) REFERENCES `users` (`id`) ON DELETE SET NULL;
+
+ALTER TABLE `report_comments`
+  ADD CONSTRAINT `fk_report_comments_report` FOREIGN KEY (`report_id`) REFERENCES `reports` (`id`) ON DELETE CASCADE,
+  ADD CONSTRAINT `fk_report_comments_author` FOREIGN KEY (`author_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+ALTER TABLE `users`
+  DROP COLUMN `suspended_until`,
+  DROP COLUMN `banned`,
+  DROP COLUMN `suspension_reason`;
@@ -0,0 +1,10 @@
+-- Suspension et bannissement des comptes par les administrateurs (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Table `users` : suspension et bannissement
+--
+ALTER TABLE `users`
+  ADD COLUMN `suspended_until` timestamp NULL DEFAULT NULL AFTER `locale`, -- suspension temporaire (connexion refusée jusqu’à cette date)
+  ADD COLUMN `banned` tinyint(1) NOT NULL DEFAULT '0' AFTER `suspended_until`, -- bannissement définitif
+  ADD COLUMN `suspension_reason` varchar(255) DEFAULT NULL AFTER `banned`;
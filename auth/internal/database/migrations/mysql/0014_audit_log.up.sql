@@ -0,0 +1,32 @@
+-- Journal d’audit des événements d’authentification (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `auth_events`
+-- (journal d’audit en ajout seul : les triggers refusent toute modification ou suppression ;
+--  pas de clé étrangère pour conserver la trace des comptes supprimés)
+--
+CREATE TABLE `auth_events` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `actor_id` int DEFAULT NULL, -- auteur de l’action (NULL : anonyme)
+  `user_id` int DEFAULT NULL, -- compte concerné
+  `email` varchar(100) DEFAULT NULL, -- identifiant saisi (connexion échouée sur un compte inconnu…)
+  `event` varchar(50) NOT NULL,
+  `outcome` enum('success','failure') NOT NULL,
+  `ip_address` varchar(45) DEFAULT NULL,
+  `user_agent` varchar(255) DEFAULT NULL,
+  `metadata` json DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_created` (`user_id`,`created_at`),
+  KEY `idx_actor_created` (`actor_id`,`created_at`),
+  KEY `idx_event_created` (`event`,`created_at`),
+  KEY `idx_ip_created` (`ip_address`,`created_at`),
+  KEY `idx_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TRIGGER `auth_events_no_update` BEFORE UPDATE ON `auth_events`
+  FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'auth_events is append-only';
+
+CREATE TRIGGER `auth_events_no_delete` BEFORE DELETE ON `auth_events`
+  FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'auth_events is append-only';
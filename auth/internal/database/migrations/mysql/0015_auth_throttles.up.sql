@@ -0,0 +1,14 @@
+-- Compteurs d’échecs contre la force brute (MySQL 8)
+
+-- --------------------------------------------------------
+--
+-- Structure de la table `auth_throttles`
+-- (compteurs d’échecs par compte et par adresse IP, partagés entre les instances du service)
+--
+CREATE TABLE `auth_throttles` (
+  `throttle_key` varchar(191) NOT NULL, -- ex. login:account:<sha256(email)>, login:ip:<ip>
+  `failures` int NOT NULL DEFAULT '0',
+  `last_failure_at` timestamp NOT NULL,
+  PRIMARY KEY (`throttle_key`),
+  KEY `idx_last_failure` (`last_failure_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
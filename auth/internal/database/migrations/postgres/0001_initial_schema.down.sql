@@ -0,0 +1,5 @@
+-- Supprime le schéma initial (les tables dépendantes d’abord)
+
+DROP TABLE IF EXISTS verification_codes;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,49 @@
+-- Schéma initial du service auth (PostgreSQL 13+)
+-- Même schéma que mysql/0001_initial_schema.up.sql (ancien SQL_DB.sql), traduit :
+--   AUTO_INCREMENT -> GENERATED BY DEFAULT AS IDENTITY, tinyint(1) -> boolean, timestamp -> timestamptz,
+--   emails en citext (comparaisons insensibles à la casse, comme la collation MySQL utf8mb4_0900_ai_ci)
+
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- --------------------------------------------------------
+--
+-- Structure de la table users
+--
+CREATE TABLE users (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  email citext UNIQUE,
+  password varchar(255),
+  google_id varchar(100) UNIQUE,
+  name varchar(100),
+  picture varchar(255),
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  verified boolean DEFAULT FALSE
+);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table sessions
+--
+CREATE TABLE sessions (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  session_token varchar(191) NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX sessions_user_idx ON sessions (user_id);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table verification_codes
+--
+CREATE TABLE verification_codes (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  email citext NOT NULL,
+  code varchar(6) NOT NULL,
+  expires_at timestamptz NOT NULL,
+  used boolean DEFAULT FALSE,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX verification_codes_email_code_idx ON verification_codes (email, code);
+CREATE INDEX verification_codes_expires_idx ON verification_codes (expires_at);
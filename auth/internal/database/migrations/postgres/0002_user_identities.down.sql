@@ -0,0 +1,8 @@
+-- Comme mysql/0002_user_identities.down.sql.
+
+ALTER TABLE users ADD COLUMN google_id varchar(100) UNIQUE;
+
+UPDATE users SET google_id = i.provider_user_id
+  FROM user_identities i WHERE i.user_id = users.id AND i.provider = 'google';
+
+DROP TABLE IF EXISTS user_identities;
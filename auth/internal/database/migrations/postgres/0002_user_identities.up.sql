@@ -0,0 +1,24 @@
+-- Comptes externes (Google, GitHub, OIDC…) reliés à un utilisateur (PostgreSQL 13+)
+-- Même migration que mysql/0002_user_identities.up.sql, traduite comme 0001.
+-- users.google_id est déplacé dans user_identities, les comptes Google déjà liés sont conservés.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table user_identities
+-- (comptes Google, GitHub, Microsoft, GitLab, OIDC… reliés à un utilisateur)
+--
+CREATE TABLE user_identities (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  provider varchar(50) NOT NULL,
+  provider_user_id varchar(255) NOT NULL,
+  email varchar(100),
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (provider, provider_user_id)
+);
+CREATE INDEX user_identities_user_idx ON user_identities (user_id);
+
+INSERT INTO user_identities (user_id, provider, provider_user_id, email)
+  SELECT id, 'google', google_id, email FROM users WHERE google_id IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN google_id;
@@ -0,0 +1,13 @@
+-- Comme mysql/0003_session_digests.down.sql.
+
+DELETE FROM sessions;
+
+ALTER TABLE sessions
+  DROP COLUMN user_agent,
+  DROP COLUMN ip_address,
+  DROP COLUMN remember_me,
+  DROP COLUMN absolute_expires_at,
+  DROP COLUMN last_seen_at;
+ALTER TABLE sessions RENAME COLUMN token_hash TO session_token;
+ALTER TABLE sessions RENAME CONSTRAINT sessions_token_hash_key TO sessions_session_token_key;
+ALTER TABLE sessions ALTER COLUMN session_token TYPE varchar(191);
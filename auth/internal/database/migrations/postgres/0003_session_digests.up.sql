@@ -0,0 +1,21 @@
+-- Empreinte des jetons de session et suivi des appareils (PostgreSQL 13+)
+-- Même migration que mysql/0003_session_digests.up.sql, traduite comme 0001.
+-- Les jetons en clair ne peuvent pas être convertis : les sessions ouvertes sont supprimées
+-- (chacun se reconnecte).
+
+-- --------------------------------------------------------
+--
+-- Table sessions : empreinte du jeton (se_sess_…) et suivi des appareils
+--
+DELETE FROM sessions;
+
+ALTER TABLE sessions RENAME COLUMN session_token TO token_hash;
+ALTER TABLE sessions RENAME CONSTRAINT sessions_session_token_key TO sessions_token_hash_key;
+
+ALTER TABLE sessions
+  ALTER COLUMN token_hash TYPE char(64), -- SHA-256 du jeton (se_sess_…), jamais le jeton en clair
+  ADD COLUMN user_agent varchar(255),
+  ADD COLUMN ip_address varchar(45),
+  ADD COLUMN remember_me boolean DEFAULT FALSE,
+  ADD COLUMN absolute_expires_at timestamptz NOT NULL,
+  ADD COLUMN last_seen_at timestamptz DEFAULT CURRENT_TIMESTAMP;
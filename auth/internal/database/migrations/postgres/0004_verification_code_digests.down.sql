@@ -0,0 +1,9 @@
+-- Comme mysql/0004_verification_code_digests.down.sql.
+
+DELETE FROM verification_codes;
+
+DROP INDEX verification_codes_email_created_idx;
+ALTER TABLE verification_codes DROP COLUMN attempts;
+ALTER TABLE verification_codes RENAME COLUMN code_hash TO code;
+ALTER TABLE verification_codes ALTER COLUMN code TYPE varchar(6);
+CREATE INDEX verification_codes_email_code_idx ON verification_codes (email, code);
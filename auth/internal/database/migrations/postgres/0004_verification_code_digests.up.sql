@@ -0,0 +1,19 @@
+-- Codes de vérification hachés et nombre d’essais (PostgreSQL 13+)
+-- Même migration que mysql/0004_verification_code_digests.up.sql, traduite comme 0001.
+-- Les codes en clair ne peuvent pas être convertis : les codes en attente sont supprimés
+-- (chacun en redemande un).
+
+-- --------------------------------------------------------
+--
+-- Table verification_codes : code haché et nombre d’essais
+--
+DELETE FROM verification_codes;
+
+ALTER TABLE verification_codes RENAME COLUMN code TO code_hash;
+
+ALTER TABLE verification_codes
+  ALTER COLUMN code_hash TYPE char(64), -- HMAC-SHA256 du code (VERIFICATION_CODE_KEY), jamais le code en clair
+  ADD COLUMN attempts integer NOT NULL DEFAULT 0; -- mauvais codes saisis (code invalidé au-delà de la limite)
+
+DROP INDEX verification_codes_email_code_idx;
+CREATE INDEX verification_codes_email_created_idx ON verification_codes (email, created_at);
@@ -0,0 +1,3 @@
+-- Comme mysql/0005_password_reset_tokens.down.sql.
+
+DROP TABLE IF EXISTS password_reset_tokens;
@@ -0,0 +1,16 @@
+-- Réinitialisation du mot de passe (PostgreSQL 13+)
+-- Même migration que mysql/0005_password_reset_tokens.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table password_reset_tokens
+--
+CREATE TABLE password_reset_tokens (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash char(64) NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  used boolean DEFAULT FALSE,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX password_reset_tokens_user_idx ON password_reset_tokens (user_id);
@@ -0,0 +1,5 @@
+-- Comme mysql/0006_two_factor.down.sql.
+
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS totp_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
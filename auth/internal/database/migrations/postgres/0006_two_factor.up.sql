@@ -0,0 +1,43 @@
+-- Authentification à deux facteurs : TOTP, codes de récupération et défi de connexion (PostgreSQL 13+)
+-- Même migration que mysql/0006_two_factor.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table user_totp
+--
+CREATE TABLE user_totp (
+  user_id integer PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  secret varchar(64) NOT NULL,
+  confirmed boolean DEFAULT FALSE,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  confirmed_at timestamptz
+);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table totp_recovery_codes
+--
+CREATE TABLE totp_recovery_codes (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  code_hash char(64) NOT NULL,
+  used_at timestamptz,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (user_id, code_hash)
+);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table login_challenges
+--
+CREATE TABLE login_challenges (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash char(64) NOT NULL UNIQUE,
+  remember_me boolean DEFAULT FALSE,
+  attempts integer NOT NULL DEFAULT 0,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX login_challenges_user_idx ON login_challenges (user_id);
@@ -0,0 +1,4 @@
+-- Comme mysql/0007_webauthn.down.sql.
+
+DROP TABLE IF EXISTS webauthn_ceremonies;
+DROP TABLE IF EXISTS webauthn_credentials;
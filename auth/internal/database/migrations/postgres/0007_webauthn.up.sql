@@ -0,0 +1,31 @@
+-- Clés d’accès WebAuthn (passkeys) (PostgreSQL 13+)
+-- Même migration que mysql/0007_webauthn.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table webauthn_credentials
+--
+CREATE TABLE webauthn_credentials (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  credential_id bytea NOT NULL UNIQUE,
+  name varchar(100),
+  credential jsonb NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  last_used_at timestamptz
+);
+CREATE INDEX webauthn_credentials_user_idx ON webauthn_credentials (user_id);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table webauthn_ceremonies
+--
+CREATE TABLE webauthn_ceremonies (
+  id_hash char(64) PRIMARY KEY,
+  user_id integer,
+  ceremony varchar(20) NOT NULL,
+  data jsonb NOT NULL,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX webauthn_ceremonies_expires_idx ON webauthn_ceremonies (expires_at);
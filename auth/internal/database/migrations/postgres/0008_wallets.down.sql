@@ -0,0 +1,4 @@
+-- Comme mysql/0008_wallets.down.sql.
+
+DROP TABLE IF EXISTS siwe_nonces;
+DROP TABLE IF EXISTS user_wallets;
@@ -0,0 +1,27 @@
+-- Portefeuilles Ethereum et connexion Sign-In with Ethereum (PostgreSQL 13+)
+-- Même migration que mysql/0008_wallets.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table user_wallets
+--
+CREATE TABLE user_wallets (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  address char(42) NOT NULL UNIQUE,
+  chain_id bigint,
+  verified_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX user_wallets_user_idx ON user_wallets (user_id);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table siwe_nonces
+--
+CREATE TABLE siwe_nonces (
+  nonce varchar(32) PRIMARY KEY,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX siwe_nonces_expires_idx ON siwe_nonces (expires_at);
@@ -0,0 +1,7 @@
+-- Comme mysql/0009_oidc_provider.down.sql.
+
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_authorization_requests;
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_clients;
+DROP TABLE IF EXISTS signing_keys;
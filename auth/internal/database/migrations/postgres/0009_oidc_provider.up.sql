@@ -0,0 +1,77 @@
+-- Clés de signature des jetons et fournisseur OpenID Connect (PostgreSQL 13+)
+-- Même migration que mysql/0009_oidc_provider.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table signing_keys
+--
+CREATE TABLE signing_keys (
+  kid varchar(32) PRIMARY KEY,
+  private_key bytea NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX signing_keys_created_idx ON signing_keys (created_at);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table oauth_clients
+--
+CREATE TABLE oauth_clients (
+  client_id varchar(64) PRIMARY KEY,
+  client_secret_hash char(64),
+  name varchar(255) NOT NULL,
+  redirect_uris text NOT NULL,
+  scopes varchar(255) NOT NULL DEFAULT 'openid profile email',
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table oauth_consents
+--
+CREATE TABLE oauth_consents (
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  client_id varchar(64) NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+  scope varchar(255) NOT NULL,
+  granted_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, client_id)
+);
+CREATE INDEX oauth_consents_client_idx ON oauth_consents (client_id);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table oauth_authorization_requests
+--
+CREATE TABLE oauth_authorization_requests (
+  request_hash char(64) PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  client_id varchar(64) NOT NULL,
+  redirect_uri varchar(2048) NOT NULL,
+  scope varchar(255) NOT NULL,
+  state varchar(512) NOT NULL DEFAULT '',
+  nonce varchar(512) NOT NULL DEFAULT '',
+  code_challenge varchar(128) NOT NULL,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX oauth_authorization_requests_user_idx ON oauth_authorization_requests (user_id);
+CREATE INDEX oauth_authorization_requests_expires_idx ON oauth_authorization_requests (expires_at);
+
+-- --------------------------------------------------------
+--
+-- Structure de la table oauth_authorization_codes
+--
+CREATE TABLE oauth_authorization_codes (
+  code_hash char(64) PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  client_id varchar(64) NOT NULL,
+  redirect_uri varchar(2048) NOT NULL,
+  scope varchar(255) NOT NULL,
+  state varchar(512) NOT NULL DEFAULT '',
+  nonce varchar(512) NOT NULL DEFAULT '',
+  code_challenge varchar(128) NOT NULL,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX oauth_authorization_codes_user_idx ON oauth_authorization_codes (user_id);
+CREATE INDEX oauth_authorization_codes_expires_idx ON oauth_authorization_codes (expires_at);
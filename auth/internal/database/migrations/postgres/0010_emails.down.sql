@@ -0,0 +1,5 @@
+-- Comme mysql/0010_emails.down.sql.
+
+DROP TABLE IF EXISTS email_outbox;
+
+ALTER TABLE users DROP COLUMN locale;
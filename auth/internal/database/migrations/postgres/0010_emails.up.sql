@@ -0,0 +1,31 @@
+-- Langue des e-mails et file d’envoi (PostgreSQL 13+)
+-- Même migration que mysql/0010_emails.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Table users : langue des e-mails
+--
+ALTER TABLE users
+  ADD COLUMN locale varchar(10); -- langue des e-mails (fr, en) ; NULL : Accept-Language
+
+-- --------------------------------------------------------
+--
+-- Structure de la table email_outbox
+-- (file d’envoi des e-mails, remplie dans la même transaction que le changement métier)
+--
+CREATE TABLE email_outbox (
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  template varchar(50) NOT NULL DEFAULT '',
+  sender varchar(255) NOT NULL,
+  recipient varchar(255) NOT NULL,
+  subject varchar(255) NOT NULL,
+  html_body text NOT NULL,
+  text_body text,
+  status varchar(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error varchar(1000),
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  sent_at timestamptz
+);
+CREATE INDEX email_outbox_status_next_attempt_idx ON email_outbox (status, next_attempt_at);
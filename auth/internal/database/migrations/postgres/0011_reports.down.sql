@@ -0,0 +1,5 @@
+-- Comme mysql/0011_reports.down.sql.
+
+DROP TABLE IF EXISTS report_comments;
+DROP TABLE IF EXISTS reports;
+DROP FUNCTION IF EXISTS set_updated_at();
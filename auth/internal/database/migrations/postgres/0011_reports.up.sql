@@ -0,0 +1,49 @@
+-- Signalements et leur modération (PostgreSQL 13+)
+-- Même migration que mysql/0011_reports.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table reports
+-- (signalements de contrats / utilisateurs, un seul par signaleur et par cible)
+--
+CREATE TABLE reports (
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  reporter_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  type varchar(10) NOT NULL CHECK (type IN ('contract', 'user')),
+  target varchar(255) NOT NULL,
+  description text NOT NULL,
+  status varchar(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'triaged', 'actioned', 'dismissed')),
+  assignee_id integer REFERENCES users (id) ON DELETE SET NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz DEFAULT CURRENT_TIMESTAMP, -- tenu à jour par reports_set_updated_at
+  resolved_at timestamptz,
+  CONSTRAINT uniq_reporter_target UNIQUE (reporter_id, type, target)
+);
+CREATE INDEX reports_assignee_idx ON reports (assignee_id);
+CREATE INDEX reports_status_created_idx ON reports (status, created_at);
+
+-- Équivalent de ON UPDATE CURRENT_TIMESTAMP
+CREATE FUNCTION set_updated_at() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER reports_set_updated_at BEFORE UPDATE ON reports
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- --------------------------------------------------------
+--
+-- Structure de la table report_comments
+-- (notes internes des modérateurs)
+--
+CREATE TABLE report_comments (
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  report_id bigint NOT NULL REFERENCES reports (id) ON DELETE CASCADE,
+  author_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  body text NOT NULL,
+  created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX report_comments_report_idx ON report_comments (report_id);
+CREATE INDEX report_comments_author_idx ON report_comments (author_id);
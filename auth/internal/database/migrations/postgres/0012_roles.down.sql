@@ -0,0 +1,6 @@
+-- Comme mysql/0012_roles.down.sql.
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,66 @@
+-- Rôles et permissions (admin, moderator) (PostgreSQL 13+)
+-- Même migration que mysql/0012_roles.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table roles
+--
+CREATE TABLE roles (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  name varchar(50) NOT NULL UNIQUE,
+  description varchar(255) NOT NULL DEFAULT ''
+);
+
+INSERT INTO roles (name, description) VALUES
+('admin', 'Full access, including role management'),
+('moderator', 'Handles abuse reports');
+
+-- --------------------------------------------------------
+--
+-- Structure de la table permissions
+--
+CREATE TABLE permissions (
+  id integer GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  name varchar(50) NOT NULL UNIQUE,
+  description varchar(255) NOT NULL DEFAULT ''
+);
+
+INSERT INTO permissions (name, description) VALUES
+('reports:read', 'List and view abuse reports'),
+('reports:moderate', 'Assign, comment on and resolve abuse reports'),
+('users:read', 'View user accounts'),
+('users:manage', 'Suspend, ban and edit user accounts'),
+('emails:manage', 'Monitor and retry outgoing emails'),
+('roles:manage', 'Grant and revoke roles'),
+('audit:read', 'Query the authentication audit log');
+
+-- --------------------------------------------------------
+--
+-- Structure de la table role_permissions
+--
+CREATE TABLE role_permissions (
+  role_id integer NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+  permission_id integer NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+CREATE INDEX role_permissions_permission_idx ON role_permissions (permission_id);
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON r.name = 'admin'
+UNION ALL
+SELECT r.id, p.id FROM roles r JOIN permissions p
+  ON r.name = 'moderator' AND p.name IN ('reports:read', 'reports:moderate', 'users:read');
+
+-- --------------------------------------------------------
+--
+-- Structure de la table user_roles
+--
+CREATE TABLE user_roles (
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  role_id integer NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+  granted_by integer REFERENCES users (id) ON DELETE SET NULL,
+  granted_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, role_id)
+);
+CREATE INDEX user_roles_role_idx ON user_roles (role_id);
+CREATE INDEX user_roles_granted_by_idx ON user_roles (granted_by);
@@ -0,0 +1,6 @@
+-- Comme mysql/0013_user_suspension.down.sql.
+
+ALTER TABLE users
+  DROP COLUMN suspended_until,
+  DROP COLUMN banned,
+  DROP COLUMN suspension_reason;
@@ -0,0 +1,11 @@
+-- Suspension et bannissement des comptes par les administrateurs (PostgreSQL 13+)
+-- Même migration que mysql/0013_user_suspension.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Table users : suspension et bannissement
+--
+ALTER TABLE users
+  ADD COLUMN suspended_until timestamptz, -- suspension temporaire (connexion refusée jusqu’à cette date)
+  ADD COLUMN banned boolean NOT NULL DEFAULT FALSE, -- bannissement définitif
+  ADD COLUMN suspension_reason varchar(255);
@@ -0,0 +1,4 @@
+-- Comme mysql/0014_audit_log.down.sql.
+
+DROP TABLE IF EXISTS auth_events;
+DROP FUNCTION IF EXISTS auth_events_append_only();
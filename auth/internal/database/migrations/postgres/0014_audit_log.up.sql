@@ -0,0 +1,38 @@
+-- Journal d’audit des événements d’authentification (PostgreSQL 13+)
+-- Même migration que mysql/0014_audit_log.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table auth_events
+-- (journal d’audit en ajout seul : les triggers refusent toute modification ou suppression ;
+--  pas de clé étrangère pour conserver la trace des comptes supprimés)
+--
+CREATE TABLE auth_events (
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  actor_id integer, -- auteur de l’action (NULL : anonyme)
+  user_id integer, -- compte concerné
+  email varchar(100), -- identifiant saisi (connexion échouée sur un compte inconnu…)
+  event varchar(50) NOT NULL,
+  outcome varchar(10) NOT NULL CHECK (outcome IN ('success', 'failure')),
+  ip_address varchar(45),
+  user_agent varchar(255),
+  metadata jsonb,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX auth_events_user_created_idx ON auth_events (user_id, created_at);
+CREATE INDEX auth_events_actor_created_idx ON auth_events (actor_id, created_at);
+CREATE INDEX auth_events_event_created_idx ON auth_events (event, created_at);
+CREATE INDEX auth_events_ip_created_idx ON auth_events (ip_address, created_at);
+CREATE INDEX auth_events_created_idx ON auth_events (created_at);
+
+CREATE FUNCTION auth_events_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'auth_events is append-only' USING ERRCODE = '45000';
+END;
+$$;
+
+CREATE TRIGGER auth_events_no_update BEFORE UPDATE ON auth_events
+  FOR EACH ROW EXECUTE FUNCTION auth_events_append_only();
+
+CREATE TRIGGER auth_events_no_delete BEFORE DELETE ON auth_events
+  FOR EACH ROW EXECUTE FUNCTION auth_events_append_only();
@@ -0,0 +1,3 @@
+-- Comme mysql/0015_auth_throttles.down.sql.
+
+DROP TABLE IF EXISTS auth_throttles;
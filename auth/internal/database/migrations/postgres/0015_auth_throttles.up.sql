@@ -0,0 +1,14 @@
+-- Compteurs d’échecs contre la force brute (PostgreSQL 13+)
+-- Même migration que mysql/0015_auth_throttles.up.sql, traduite comme 0001.
+
+-- --------------------------------------------------------
+--
+-- Structure de la table auth_throttles
+-- (compteurs d’échecs par compte et par adresse IP, partagés entre les instances du service)
+--
+CREATE TABLE auth_throttles (
+  throttle_key varchar(191) PRIMARY KEY, -- ex. login:account:<sha256(email)>, login:ip:<ip>
+  failures integer NOT NULL DEFAULT 0,
+  last_failure_at timestamptz NOT NULL
+);
+CREATE INDEX auth_throttles_last_failure_idx ON auth_throttles (last_failure_at);
@@ -0,0 +1,194 @@
+/*
+Ce fichier gère les données du fournisseur OpenID Connect :
+
+oauth_clients                : applications enregistrées (redirect URIs, scopes autorisés, secret haché)
+oauth_consents               : scopes accordés par un utilisateur à un client
+oauth_authorization_requests : demandes en attente de consentement (écran "Autoriser / Refuser")
+oauth_authorization_codes    : codes d’autorisation à usage unique (flux authorization code + PKCE)
+
+Les identifiants des demandes et les codes ne sont stockés que hachés (SHA-256).
+*/
+
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"auth/internal/database/dialect"
+)
+
+type OAuthClient struct {
+	ID           string
+	SecretHash   sql.NullString // NULL : client public (PKCE seul)
+	Name         string
+	RedirectURIs []string
+	Scopes       string
+	CreatedAt    time.Time
+}
+
+// Paramètres d’une autorisation, conservés entre /oauth/authorize et /oauth/token.
+type OAuthAuthorization struct {
+	UserID        int
+	ClientID      string
+	RedirectURI   string
+	Scope         string
+	State         string
+	Nonce         string
+	CodeChallenge string
+}
+
+type OAuthConsent struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scope      string    `json:"scope"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
+
+// Enregistre un client. Les redirect URIs sont stockées une par ligne.
+func (s Service) CreateOAuthClient(client OAuthClient) error {
+	_, err := s.DB.Exec(
+		"INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, scopes) VALUES (?, ?, ?, ?, ?)",
+		client.ID, client.SecretHash, client.Name, strings.Join(client.RedirectURIs, "\n"), client.Scopes,
+	)
+	return err
+}
+
+// Retrouve un client par son identifiant (sql.ErrNoRows s’il n’existe pas).
+func (s Service) GetOAuthClient(clientID string) (*OAuthClient, error) {
+	var client OAuthClient
+	var redirectURIs string
+	err := s.DB.QueryRow(
+		"SELECT client_id, client_secret_hash, name, redirect_uris, scopes, created_at FROM oauth_clients WHERE client_id = ?",
+		clientID,
+	).Scan(&client.ID, &client.SecretHash, &client.Name, &redirectURIs, &client.Scopes, &client.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	client.RedirectURIs = strings.Fields(redirectURIs)
+	return &client, nil
+}
+
+// Scopes déjà accordés par l’utilisateur au client ("" si aucun consentement).
+func (s Service) GetOAuthConsent(userID int, clientID string) (string, error) {
+	var scope string
+	err := s.DB.QueryRow(
+		"SELECT scope FROM oauth_consents WHERE user_id = ? AND client_id = ?",
+		userID, clientID,
+	).Scan(&scope)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return scope, err
+}
+
+// Enregistre (ou remplace) le consentement de l’utilisateur pour un client.
+func (s Service) SaveOAuthConsent(userID int, clientID, scope string) error {
+	query := `INSERT INTO oauth_consents (user_id, client_id, scope, granted_at) VALUES (?, ?, ?, NOW())
+		 ON DUPLICATE KEY UPDATE scope = VALUES(scope), granted_at = NOW()`
+	if s.DB.Dialect == dialect.Postgres {
+		query = `INSERT INTO oauth_consents (user_id, client_id, scope, granted_at) VALUES (?, ?, ?, NOW())
+		 ON CONFLICT (user_id, client_id) DO UPDATE SET scope = EXCLUDED.scope, granted_at = NOW()`
+	}
+	_, err := s.DB.Exec(query, userID, clientID, scope)
+	return err
+}
+
+// Liste les applications autorisées par un utilisateur.
+func (s Service) ListOAuthConsents(userID int) ([]OAuthConsent, error) {
+	rows, err := s.DB.Query(
+		`SELECT c.client_id, oc.name, c.scope, c.granted_at
+		 FROM oauth_consents c
+		 INNER JOIN oauth_clients oc ON oc.client_id = c.client_id
+		 WHERE c.user_id = ?
+		 ORDER BY c.granted_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []OAuthConsent{}
+	for rows.Next() {
+		var consent OAuthConsent
+		if err := rows.Scan(&consent.ClientID, &consent.ClientName, &consent.Scope, &consent.GrantedAt); err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+	return consents, rows.Err()
+}
+
+// Retire le consentement accordé à un client.
+func (s Service) DeleteOAuthConsent(userID int, clientID string) (bool, error) {
+	res, err := s.DB.Exec("DELETE FROM oauth_consents WHERE user_id = ? AND client_id = ?", userID, clientID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Conserve une demande d’autorisation en attente de consentement.
+func (s Service) SaveOAuthAuthorizationRequest(idHash string, a OAuthAuthorization, expiresAt time.Time) error {
+	return s.saveOAuthAuthorization("oauth_authorization_requests", "request_hash", idHash, a, expiresAt)
+}
+
+// Récupère (une seule fois) une demande en attente ; sql.ErrNoRows si inconnue ou expirée.
+func (s Service) ConsumeOAuthAuthorizationRequest(idHash string) (*OAuthAuthorization, error) {
+	return s.consumeOAuthAuthorization("oauth_authorization_requests", "request_hash", idHash)
+}
+
+// Enregistre un code d’autorisation.
+func (s Service) SaveOAuthAuthorizationCode(codeHash string, a OAuthAuthorization, expiresAt time.Time) error {
+	return s.saveOAuthAuthorization("oauth_authorization_codes", "code_hash", codeHash, a, expiresAt)
+}
+
+// Consomme un code d’autorisation ; sql.ErrNoRows s’il est inconnu, expiré ou déjà utilisé.
+func (s Service) ConsumeOAuthAuthorizationCode(codeHash string) (*OAuthAuthorization, error) {
+	return s.consumeOAuthAuthorization("oauth_authorization_codes", "code_hash", codeHash)
+}
+
+// table et column sont des constantes internes, jamais des valeurs fournies par l’utilisateur.
+func (s Service) saveOAuthAuthorization(table, column, hash string, a OAuthAuthorization, expiresAt time.Time) error {
+	if _, err := s.DB.Exec("DELETE FROM " + table + " WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(
+		"INSERT INTO "+table+" ("+column+`, user_id, client_id, redirect_uri, scope, state, nonce, code_challenge, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		hash, a.UserID, a.ClientID, a.RedirectURI, a.Scope, a.State, a.Nonce, a.CodeChallenge, expiresAt,
+	)
+	return err
+}
+
+func (s Service) consumeOAuthAuthorization(table, column, hash string) (*OAuthAuthorization, error) {
+	var a OAuthAuthorization
+	err := s.DB.QueryRow(
+		"SELECT user_id, client_id, redirect_uri, scope, state, nonce, code_challenge FROM "+table+
+			" WHERE "+column+" = ? AND expires_at > NOW()",
+		hash,
+	).Scan(&a.UserID, &a.ClientID, &a.RedirectURI, &a.Scope, &a.State, &a.Nonce, &a.CodeChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	// La suppression garantit l’usage unique même en cas de requêtes concurrentes
+	res, err := s.DB.Exec("DELETE FROM "+table+" WHERE "+column+" = ?", hash)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &a, nil
+}
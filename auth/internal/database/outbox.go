@@ -0,0 +1,216 @@
+/*
+Ce fichier gère la file d’envoi des e-mails (table email_outbox, pattern "transactional outbox").
+
+Un e-mail est inséré dans la même transaction que le changement qui le motive
+(création du compte, nouveau code, jeton de réinitialisation…) : soit les deux sont enregistrés,
+soit aucun. Un worker (voir server/outbox.go) envoie ensuite les messages :
+
+	pending -> sent  : envoi réussi
+	pending -> dead  : nombre maximal de tentatives atteint (dead-letter, relançable par un admin)
+
+Un message réservé par un worker reste "pending" avec next_attempt_at repoussé :
+si le worker s’arrête en cours d’envoi, le message sera repris après ce délai.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"auth/internal/mailer"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// Exécuteur commun à *DB et *Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// État d’un e-mail de la file, tel qu’exposé aux administrateurs (sans le contenu).
+type OutboxEmail struct {
+	ID            int64      `json:"id"`
+	Template      string     `json:"template"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// E-mail réservé par le worker, prêt à être envoyé.
+type QueuedEmail struct {
+	ID       int64
+	Attempts int // tentatives, y compris celle en cours
+	Message  mailer.Message
+}
+
+func enqueueEmail(exec execer, msg mailer.Message) error {
+	_, err := exec.Exec(
+		`INSERT INTO email_outbox (template, sender, recipient, subject, html_body, text_body)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		msg.Template, msg.From, strings.Join(msg.To, ", "), msg.Subject, msg.HTML, msg.Text,
+	)
+	return err
+}
+
+// Ajoute un e-mail à la file, hors de toute autre modification.
+func (s Service) EnqueueEmail(msg mailer.Message) error {
+	return enqueueEmail(s.DB, msg)
+}
+
+/*
+Réserve jusqu’à limit e-mails à envoyer : leur prochaine tentative est repoussée de lease
+et le compteur de tentatives incrémenté. SKIP LOCKED permet à plusieurs instances
+du service de vider la file en parallèle sans envoyer deux fois le même message.
+*/
+func (s Service) ClaimOutboxEmails(limit int, lease time.Duration) ([]QueuedEmail, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		`SELECT id, attempts, template, sender, recipient, subject, html_body, text_body
+		 FROM email_outbox
+		 WHERE status = ? AND next_attempt_at <= NOW()
+		 ORDER BY next_attempt_at ASC
+		 LIMIT ?
+		 FOR UPDATE SKIP LOCKED`,
+		OutboxPending, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []QueuedEmail
+	for rows.Next() {
+		var email QueuedEmail
+		var recipient string
+		var text sql.NullString
+		if err := rows.Scan(&email.ID, &email.Attempts, &email.Message.Template, &email.Message.From,
+			&recipient, &email.Message.Subject, &email.Message.HTML, &text); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		email.Attempts++
+		email.Message.To = strings.Split(recipient, ", ")
+		email.Message.Text = text.String
+		emails = append(emails, email)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, email := range emails {
+		if _, err := tx.Exec(
+			"UPDATE email_outbox SET attempts = attempts + 1, next_attempt_at = ? WHERE id = ?",
+			time.Now().UTC().Add(lease), email.ID,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	return emails, tx.Commit()
+}
+
+func (s Service) MarkOutboxEmailSent(id int64) error {
+	_, err := s.DB.Exec(
+		"UPDATE email_outbox SET status = ?, sent_at = NOW(), last_error = NULL WHERE id = ?",
+		OutboxSent, id,
+	)
+	return err
+}
+
+// Enregistre un échec : nouvelle tentative à nextAttemptAt, ou dead-letter si dead.
+func (s Service) MarkOutboxEmailFailed(id int64, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := OutboxPending
+	if dead {
+		status = OutboxDead
+	}
+	_, err := s.DB.Exec(
+		"UPDATE email_outbox SET status = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		status, truncate(lastError, 1000), nextAttemptAt, id,
+	)
+	return err
+}
+
+// Liste les e-mails de la file, les plus récents en premier (status vide : tous).
+func (s Service) ListOutboxEmails(status string, limit, offset int) ([]OutboxEmail, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, template, recipient, subject, status, attempts, next_attempt_at, last_error, created_at, sent_at
+		 FROM email_outbox
+		 WHERE (? = '' OR status = ?)
+		 ORDER BY id DESC
+		 LIMIT ? OFFSET ?`,
+		status, status, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []OutboxEmail{}
+	for rows.Next() {
+		var email OutboxEmail
+		var lastError sql.NullString
+		var sentAt sql.NullTime
+		if err := rows.Scan(&email.ID, &email.Template, &email.Recipient, &email.Subject, &email.Status,
+			&email.Attempts, &email.NextAttemptAt, &lastError, &email.CreatedAt, &sentAt); err != nil {
+			return nil, err
+		}
+		email.LastError = lastError.String
+		if sentAt.Valid {
+			email.SentAt = &sentAt.Time
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// Nombre d’e-mails par statut.
+func (s Service) CountOutboxEmails() (map[string]int, error) {
+	rows, err := s.DB.Query("SELECT status, COUNT(*) FROM email_outbox GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{OutboxPending: 0, OutboxSent: 0, OutboxDead: 0}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// Remet un e-mail en dead-letter dans la file ; retourne false s’il n’est pas en dead-letter.
+func (s Service) RetryOutboxEmail(id int64) (bool, error) {
+	res, err := s.DB.Exec(
+		"UPDATE email_outbox SET status = ?, attempts = 0, next_attempt_at = NOW() WHERE id = ? AND status = ?",
+		OutboxPending, id, OutboxDead,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
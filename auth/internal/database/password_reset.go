@@ -0,0 +1,105 @@
+/*
+Ce fichier gère les jetons de réinitialisation de mot de passe.
+Seul le hash SHA-256 du jeton est stocké : le jeton en clair n’existe que dans l’e-mail envoyé.
+Un jeton est à usage unique et expire après un délai court.
+*/
+
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"auth/internal/mailer"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Calcule l’empreinte SHA-256 (hex) d’un jeton avant stockage ou recherche.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+Sauvegarde un nouveau jeton de réinitialisation pour un utilisateur
+et met en file l’e-mail contenant le lien, dans la même transaction.
+Les jetons non utilisés précédents sont supprimés : un seul lien reste valide à la fois.
+*/
+func (s Service) SavePasswordResetToken(userID int, tokenHash string, expiresAt time.Time, resetEmail mailer.Message) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_reset_tokens WHERE user_id = ? AND used = FALSE", userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES (?, ?, ?)",
+		userID, tokenHash, expiresAt,
+	); err != nil {
+		return err
+	}
+
+	if err := enqueueEmail(tx, resetEmail); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+/*
+Consomme un jeton de réinitialisation et retourne l’ID de l’utilisateur associé.
+
+Le jeton doit exister, ne pas être utilisé et ne pas être expiré.
+Le passage à used = TRUE est conditionnel : si deux requêtes arrivent en même temps,
+une seule obtient une ligne modifiée.
+Retourne sql.ErrNoRows si le jeton est invalide.
+*/
+func (s Service) ConsumePasswordResetToken(tokenHash string) (int, error) {
+	var id, userID int
+	var used bool
+	var expiresAt time.Time
+
+	err := s.DB.QueryRow(
+		"SELECT id, user_id, used, expires_at FROM password_reset_tokens WHERE token_hash = ?",
+		tokenHash,
+	).Scan(&id, &userID, &used, &expiresAt)
+	if err != nil {
+		return 0, err
+	}
+
+	if used || time.Now().After(expiresAt) {
+		return 0, sql.ErrNoRows
+	}
+
+	res, err := s.DB.Exec("UPDATE password_reset_tokens SET used = TRUE WHERE id = ? AND used = FALSE", id)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	return userID, nil
+}
+
+// Remplace le mot de passe d’un utilisateur (hash bcrypt).
+func (s Service) UpdateUserPassword(userID int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	_, err = s.DB.Exec("UPDATE users SET password = ? WHERE id = ?", string(hashedPassword), userID)
+	return err
+}
@@ -0,0 +1,230 @@
+/*
+Ce fichier gère les signalements (tables reports et report_comments).
+
+Un signalement vise un contrat ou un utilisateur et suit le cycle de modération :
+
+	open -> triaged -> actioned | dismissed
+
+Un même utilisateur ne peut signaler qu’une fois la même cible (clé unique reporter/type/target).
+Les commentaires sont internes à la modération et ne sont jamais montrés au signaleur.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"auth/internal/database/dialect"
+	"auth/internal/mailer"
+)
+
+const (
+	ReportOpen      = "open"
+	ReportTriaged   = "triaged"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+var ReportStatuses = []string{ReportOpen, ReportTriaged, ReportActioned, ReportDismissed}
+
+var ReportTypes = []string{"contract", "user"}
+
+// Le signaleur a déjà signalé cette cible.
+var ErrDuplicateReport = errors.New("target already reported by this user")
+
+type Report struct {
+	ID            int64      `json:"id"`
+	ReporterID    int64      `json:"reporter_id"`
+	ReporterEmail string     `json:"reporter_email,omitempty"`
+	Type          string     `json:"type"`
+	Target        string     `json:"target"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	AssigneeID    *int64     `json:"assignee_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ResolvedAt    *time.Time `json:"resolved_at"`
+}
+
+type ReportComment struct {
+	ID          int64     `json:"id"`
+	AuthorID    int64     `json:"author_id"`
+	AuthorEmail string    `json:"author_email"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Filtres de la liste de modération (valeurs vides : pas de filtre).
+type ReportFilter struct {
+	Status     string
+	Type       string
+	AssigneeID int64 // 0 : tous, -1 : non assignés
+	Limit      int
+	Offset     int
+}
+
+/*
+Enregistre un signalement et met en file l’e-mail aux modérateurs dans la même transaction
+(aucun e-mail si notification n’a pas de destinataire).
+Si ce signaleur a déjà signalé cette cible, retourne l’identifiant du signalement existant
+avec ErrDuplicateReport.
+*/
+func (s Service) CreateReport(reporterID int, reportType, target, description string, notification mailer.Message) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := tx.InsertID(
+		"INSERT INTO reports (reporter_id, type, target, description) VALUES (?, ?, ?, ?)",
+		reporterID, reportType, target, description,
+	)
+	if dialect.IsDuplicateKey(err) {
+		var existingID int64
+		if err := s.DB.QueryRow(
+			"SELECT id FROM reports WHERE reporter_id = ? AND type = ? AND target = ?",
+			reporterID, reportType, target,
+		).Scan(&existingID); err != nil {
+			return 0, err
+		}
+		return existingID, ErrDuplicateReport
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if len(notification.To) > 0 {
+		if err := enqueueEmail(tx, notification); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+const reportColumns = `r.id, r.reporter_id, u.email, r.type, r.target, r.description, r.status,
+	r.assignee_id, r.created_at, r.updated_at, r.resolved_at`
+
+func scanReport(row interface{ Scan(...interface{}) error }) (*Report, error) {
+	var report Report
+	var reporterEmail sql.NullString
+	var assigneeID sql.NullInt64
+	var resolvedAt sql.NullTime
+	if err := row.Scan(&report.ID, &report.ReporterID, &reporterEmail, &report.Type, &report.Target,
+		&report.Description, &report.Status, &assigneeID, &report.CreatedAt, &report.UpdatedAt, &resolvedAt); err != nil {
+		return nil, err
+	}
+	report.ReporterEmail = reporterEmail.String
+	if assigneeID.Valid {
+		report.AssigneeID = &assigneeID.Int64
+	}
+	if resolvedAt.Valid {
+		report.ResolvedAt = &resolvedAt.Time
+	}
+	return &report, nil
+}
+
+func (s Service) queryReports(query string, args ...interface{}) ([]Report, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+// Signalements déposés par un utilisateur, les plus récents en premier.
+func (s Service) ListReportsByReporter(reporterID int) ([]Report, error) {
+	return s.queryReports(
+		`SELECT `+reportColumns+` FROM reports r LEFT JOIN users u ON u.id = r.reporter_id
+		 WHERE r.reporter_id = ? ORDER BY r.created_at DESC`,
+		reporterID,
+	)
+}
+
+// Liste de modération filtrée, les plus anciens non résolus d’abord.
+func (s Service) ListReports(filter ReportFilter) ([]Report, error) {
+	return s.queryReports(
+		`SELECT `+reportColumns+` FROM reports r LEFT JOIN users u ON u.id = r.reporter_id
+		 WHERE (? = '' OR r.status = ?)
+		   AND (? = '' OR r.type = ?)
+		   AND (? = 0 OR (? = -1 AND r.assignee_id IS NULL) OR r.assignee_id = ?)
+		 ORDER BY r.resolved_at IS NOT NULL, r.created_at ASC
+		 LIMIT ? OFFSET ?`,
+		filter.Status, filter.Status,
+		filter.Type, filter.Type,
+		filter.AssigneeID, filter.AssigneeID, filter.AssigneeID,
+		filter.Limit, filter.Offset,
+	)
+}
+
+// Un signalement par son identifiant (sql.ErrNoRows s’il n’existe pas).
+func (s Service) GetReport(id int64) (*Report, error) {
+	return scanReport(s.DB.QueryRow(
+		`SELECT `+reportColumns+` FROM reports r LEFT JOIN users u ON u.id = r.reporter_id WHERE r.id = ?`,
+		id,
+	))
+}
+
+/*
+Change le statut d’un signalement ; resolved_at est renseigné pour actioned / dismissed
+et remis à NULL si le signalement est rouvert.
+*/
+func (s Service) UpdateReportStatus(id int64, status string) error {
+	_, err := s.DB.Exec(
+		`UPDATE reports SET status = ?,
+		   resolved_at = CASE WHEN ? IN (?, ?) THEN COALESCE(resolved_at, NOW()) ELSE NULL END
+		 WHERE id = ?`,
+		status, status, ReportActioned, ReportDismissed, id,
+	)
+	return err
+}
+
+// Assigne un signalement à un modérateur (assigneeID nil : désassigner).
+func (s Service) AssignReport(id int64, assigneeID *int64) error {
+	_, err := s.DB.Exec("UPDATE reports SET assignee_id = ? WHERE id = ?", assigneeID, id)
+	return err
+}
+
+func (s Service) AddReportComment(reportID int64, authorID int, body string) (int64, error) {
+	return s.DB.InsertID(
+		"INSERT INTO report_comments (report_id, author_id, body) VALUES (?, ?, ?)",
+		reportID, authorID, body,
+	)
+}
+
+func (s Service) ListReportComments(reportID int64) ([]ReportComment, error) {
+	rows, err := s.DB.Query(
+		`SELECT c.id, c.author_id, u.email, c.body, c.created_at
+		 FROM report_comments c LEFT JOIN users u ON u.id = c.author_id
+		 WHERE c.report_id = ? ORDER BY c.created_at ASC, c.id ASC`,
+		reportID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []ReportComment{}
+	for rows.Next() {
+		var comment ReportComment
+		var authorEmail sql.NullString
+		if err := rows.Scan(&comment.ID, &comment.AuthorID, &authorEmail, &comment.Body, &comment.CreatedAt); err != nil {
+			return nil, err
+		}
+		comment.AuthorEmail = authorEmail.String
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
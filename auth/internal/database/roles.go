@@ -0,0 +1,257 @@
+/*
+Ce fichier gère les rôles et permissions (tables roles, permissions, role_permissions, user_roles).
+
+Les rôles et leurs permissions sont définis par les migrations (migrations/mysql et migrations/postgres) ; les handlers ne vérifient
+que des permissions, jamais des noms de rôles.
+*/
+
+package database
+
+import (
+	"errors"
+	"time"
+
+	"auth/internal/database/dialect"
+)
+
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+const (
+	PermissionReportsRead     = "reports:read"
+	PermissionReportsModerate = "reports:moderate"
+	PermissionUsersRead       = "users:read"
+	PermissionUsersManage     = "users:manage"
+	PermissionEmailsManage    = "emails:manage"
+	PermissionRolesManage     = "roles:manage"
+	PermissionAuditRead       = "audit:read"
+)
+
+var ErrUnknownRole = errors.New("unknown role")
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleMember struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+func (s Service) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func (s Service) GetUserRoles(userID int) ([]string, error) {
+	return s.queryStrings(
+		`SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		 WHERE ur.user_id = ? ORDER BY r.name`,
+		userID,
+	)
+}
+
+// Permissions accordées par l’ensemble des rôles de l’utilisateur.
+func (s Service) GetUserPermissions(userID int) ([]string, error) {
+	return s.queryStrings(
+		`SELECT DISTINCT p.name FROM user_roles ur
+		 JOIN role_permissions rp ON rp.role_id = ur.role_id
+		 JOIN permissions p ON p.id = rp.permission_id
+		 WHERE ur.user_id = ? ORDER BY p.name`,
+		userID,
+	)
+}
+
+func (s Service) UserHasPermission(userID int, permission string) (bool, error) {
+	var exists bool
+	err := s.DB.QueryRow(
+		`SELECT EXISTS (
+		   SELECT 1 FROM user_roles ur
+		   JOIN role_permissions rp ON rp.role_id = ur.role_id
+		   JOIN permissions p ON p.id = rp.permission_id
+		   WHERE ur.user_id = ? AND p.name = ?
+		 )`,
+		userID, permission,
+	).Scan(&exists)
+	return exists, err
+}
+
+// Rôles existants avec leurs permissions.
+func (s Service) ListRoles() ([]Role, error) {
+	rows, err := s.DB.Query(
+		`SELECT r.name, r.description, p.name FROM roles r
+		 LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		 LEFT JOIN permissions p ON p.id = rp.permission_id
+		 ORDER BY r.name, p.name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var name, description string
+		var permission *string
+		if err := rows.Scan(&name, &description, &permission); err != nil {
+			return nil, err
+		}
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, Role{Name: name, Description: description, Permissions: []string{}})
+		}
+		if permission != nil {
+			roles[len(roles)-1].Permissions = append(roles[len(roles)-1].Permissions, *permission)
+		}
+	}
+	return roles, rows.Err()
+}
+
+func (s Service) ListRoleMembers(role string) ([]RoleMember, error) {
+	rows, err := s.DB.Query(
+		`SELECT u.id, u.email, ur.granted_at FROM user_roles ur
+		 JOIN roles r ON r.id = ur.role_id
+		 JOIN users u ON u.id = ur.user_id
+		 WHERE r.name = ? ORDER BY ur.granted_at`,
+		role,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []RoleMember{}
+	for rows.Next() {
+		var member RoleMember
+		if err := rows.Scan(&member.UserID, &member.Email, &member.GrantedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+/*
+Accorde un rôle (sans effet si l’utilisateur l’a déjà).
+grantedBy vaut 0 pour un rôle accordé par le système (bootstrap).
+*/
+func (s Service) GrantRole(userID int, role string, grantedBy int) error {
+	query := `INSERT IGNORE INTO user_roles (user_id, role_id, granted_by)
+		 SELECT ?, id, NULLIF(?, 0) FROM roles WHERE name = ?`
+	if s.DB.Dialect == dialect.Postgres {
+		query = `INSERT INTO user_roles (user_id, role_id, granted_by)
+		 SELECT CAST(? AS integer), id, NULLIF(CAST(? AS integer), 0) FROM roles WHERE name = ?
+		 ON CONFLICT DO NOTHING`
+	}
+	res, err := s.DB.Exec(query, userID, grantedBy, role)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		if err := s.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM roles WHERE name = ?)", role).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrUnknownRole
+		}
+	}
+	return nil
+}
+
+/*
+Retire un rôle. Le dernier administrateur ne peut pas perdre le rôle admin :
+retourne false dans ce cas, comme lorsque l’utilisateur n’avait pas le rôle.
+*/
+func (s Service) RevokeRole(userID int, role string) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if role == RoleAdmin {
+		// Verrouille les administrateurs pour que deux retraits simultanés ne laissent personne
+		rows, err := tx.Query(
+			`SELECT ur.user_id FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			 WHERE r.name = ? FOR UPDATE`,
+			RoleAdmin,
+		)
+		if err != nil {
+			return false, err
+		}
+		admins := 0
+		for rows.Next() {
+			admins++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, err
+		}
+		if admins <= 1 {
+			return false, nil
+		}
+	}
+
+	res, err := tx.Exec(
+		"DELETE FROM user_roles WHERE user_id = ? AND role_id IN (SELECT id FROM roles WHERE name = ?)",
+		userID, role,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+/*
+Premier administrateur : accorde le rôle admin au compte vérifié portant cet email,
+uniquement si aucun administrateur n’existe encore. Retourne true si le rôle a été accordé.
+*/
+func (s Service) BootstrapAdmin(email string) (bool, error) {
+	res, err := s.DB.Exec(
+		`INSERT INTO user_roles (user_id, role_id)
+		 SELECT u.id, r.id FROM users u JOIN roles r ON r.name = ?
+		 WHERE u.email = ? AND u.verified = TRUE
+		   AND NOT EXISTS (
+		     SELECT 1 FROM (
+		       SELECT ur.user_id FROM user_roles ur JOIN roles ra ON ra.id = ur.role_id WHERE ra.name = ?
+		     ) admins
+		   )`,
+		RoleAdmin, email, RoleAdmin,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s Service) HasAdmin() (bool, error) {
+	var exists bool
+	err := s.DB.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id WHERE r.name = ?)",
+		RoleAdmin,
+	).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,71 @@
+/*
+Ce fichier définit la politique de durée de vie des sessions :
+
+AbsoluteTTL : durée maximale d’une session depuis sa création, quoi qu’il arrive.
+
+IdleTimeout : une session non utilisée pendant ce délai expire.
+Chaque utilisation la prolonge (expiration glissante) sans jamais dépasser l’expiration absolue.
+
+Les sessions « remember me » ont leurs propres durées, plus longues.
+*/
+
+package database
+
+import (
+	"time"
+
+	"auth/internal/config"
+)
+
+type SessionPolicy struct {
+	AbsoluteTTL           time.Duration
+	IdleTimeout           time.Duration
+	RememberMeTTL         time.Duration
+	RememberMeIdleTimeout time.Duration
+}
+
+// Politique par défaut, identique aux valeurs par défaut de la configuration
+var DefaultSessionPolicy = SessionPolicy{
+	AbsoluteTTL:           24 * time.Hour,
+	IdleTimeout:           2 * time.Hour,
+	RememberMeTTL:         30 * 24 * time.Hour,
+	RememberMeIdleTimeout: 7 * 24 * time.Hour,
+}
+
+/*
+Politique configurée par SESSION_TTL, SESSION_IDLE_TIMEOUT, SESSION_REMEMBER_ME_TTL
+et SESSION_REMEMBER_ME_IDLE_TIMEOUT (durées validées au chargement de la configuration).
+*/
+func NewSessionPolicy(cfg config.Sessions) SessionPolicy {
+	return SessionPolicy{
+		AbsoluteTTL:           cfg.AbsoluteTTL,
+		IdleTimeout:           cfg.IdleTimeout,
+		RememberMeTTL:         cfg.RememberMeTTL,
+		RememberMeIdleTimeout: cfg.RememberMeIdleTimeout,
+	}
+}
+
+// Expiration absolue d’une session créée à l’instant now.
+func (p SessionPolicy) AbsoluteExpiry(now time.Time, rememberMe bool) time.Time {
+	if rememberMe {
+		return now.Add(p.RememberMeTTL)
+	}
+	return now.Add(p.AbsoluteTTL)
+}
+
+/*
+Prochaine expiration glissante : now + délai d’inactivité,
+bornée par l’expiration absolue.
+*/
+func (p SessionPolicy) IdleExpiry(now, absoluteExpiresAt time.Time, rememberMe bool) time.Time {
+	idle := p.IdleTimeout
+	if rememberMe {
+		idle = p.RememberMeIdleTimeout
+	}
+
+	expiresAt := now.Add(idle)
+	if expiresAt.After(absoluteExpiresAt) {
+		return absoluteExpiresAt
+	}
+	return expiresAt
+}
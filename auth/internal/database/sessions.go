@@ -0,0 +1,135 @@
+/*
+Ce fichier regroupe les opérations de gestion des appareils (sessions multiples) :
+
+lister les sessions actives d’un utilisateur,
+
+révoquer une session précise ou toutes les autres,
+
+mettre à jour la date de dernière activité.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Représente une session active vue par son propriétaire (jamais le token lui-même).
+type Session struct {
+	ID         int64     `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	RememberMe bool      `json:"remember_me"`
+	Current    bool      `json:"current"`
+}
+
+/*
+Liste les sessions non expirées d’un utilisateur, la plus récemment utilisée en premier.
+currentToken permet de marquer la session qui fait la requête.
+*/
+func (s Service) ListUserSessions(userID int, currentToken string) ([]Session, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at, remember_me, token_hash = ?
+		 FROM sessions
+		 WHERE user_id = ? AND expires_at > NOW() AND absolute_expires_at > NOW()
+		 ORDER BY last_seen_at DESC`,
+		HashToken(currentToken), userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var (
+			session   Session
+			userAgent sql.NullString
+			ipAddress sql.NullString
+			lastSeen  sql.NullTime
+		)
+		if err := rows.Scan(&session.ID, &userAgent, &ipAddress, &session.CreatedAt, &lastSeen, &session.ExpiresAt, &session.RememberMe, &session.Current); err != nil {
+			return nil, err
+		}
+		session.UserAgent = userAgent.String
+		session.IPAddress = ipAddress.String
+		session.LastSeenAt = session.CreatedAt
+		if lastSeen.Valid {
+			session.LastSeenAt = lastSeen.Time
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+/*
+Révoque une session précise appartenant à l’utilisateur.
+La condition sur user_id empêche de supprimer la session de quelqu’un d’autre.
+Retourne false si aucune session ne correspond.
+*/
+func (s Service) DeleteUserSessionByID(userID int, sessionID int64) (bool, error) {
+	res, err := s.DB.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Révoque toutes les sessions de l’utilisateur sauf celle identifiée par keepToken.
+func (s Service) DeleteOtherUserSessions(userID int, keepToken string) (int64, error) {
+	res, err := s.DB.Exec("DELETE FROM sessions WHERE user_id = ? AND token_hash <> ?", userID, HashToken(keepToken))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+/*
+Met à jour last_seen_at et repousse l’expiration glissante de la session.
+L’écriture est limitée à une fois par minute pour ne pas faire un UPDATE à chaque requête /api/me.
+*/
+func (s Service) renewSession(tokenHash string, expiresAt time.Time) {
+	_, err := s.DB.Exec(
+		`UPDATE sessions SET last_seen_at = NOW(), expires_at = ?
+		 WHERE token_hash = ? AND (last_seen_at IS NULL OR last_seen_at < ?)`,
+		expiresAt, tokenHash, time.Now().UTC().Add(-time.Minute),
+	)
+	if err != nil {
+		log.Printf("renewSession: failed to renew session: %v", err)
+	}
+}
+
+// Coupe une chaîne pour respecter la taille d’une colonne VARCHAR.
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
+
+/*
+Indique si une connexion vient d’un appareil inconnu :
+l’utilisateur a déjà des sessions, mais aucune avec ce user agent.
+La toute première connexion n’est pas considérée comme un nouvel appareil.
+*/
+func (s Service) IsNewDevice(userID int, userAgent string) (bool, error) {
+	var total, sameDevice int
+	err := s.DB.QueryRow(
+		"SELECT COUNT(*), COUNT(CASE WHEN user_agent = ? THEN 1 END) FROM sessions WHERE user_id = ?",
+		truncate(userAgent, 255), userID,
+	).Scan(&total, &sameDevice)
+	if err != nil {
+		return false, err
+	}
+	return total > 0 && sameDevice == 0, nil
+}
@@ -0,0 +1,63 @@
+/*
+Ce fichier gère les clés de signature des jetons d’accès (table signing_keys).
+
+Les clés sont partagées en base pour que toutes les instances du service
+signent avec la même clé et publient le même JWKS.
+*/
+
+package database
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	"auth/internal/auth"
+)
+
+// Clé de signature avec sa date de création (qui pilote la rotation).
+type StoredSigningKey struct {
+	Key       auth.SigningKey
+	CreatedAt time.Time
+}
+
+// Enregistre une nouvelle clé de signature.
+func (s Service) SaveSigningKey(key auth.SigningKey) error {
+	_, err := s.DB.Exec(
+		"INSERT INTO signing_keys (kid, private_key, created_at) VALUES (?, ?, ?)",
+		key.ID, []byte(key.PrivateKey), time.Now().UTC(),
+	)
+	return err
+}
+
+// Liste les clés créées après since, de la plus récente à la plus ancienne.
+func (s Service) ListSigningKeys(since time.Time) ([]StoredSigningKey, error) {
+	rows, err := s.DB.Query(
+		"SELECT kid, private_key, created_at FROM signing_keys WHERE created_at > ? ORDER BY created_at DESC",
+		since.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []StoredSigningKey{}
+	for rows.Next() {
+		var stored StoredSigningKey
+		var privateKey []byte
+		if err := rows.Scan(&stored.Key.ID, &privateKey, &stored.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(privateKey) != ed25519.PrivateKeySize {
+			continue
+		}
+		stored.Key.PrivateKey = ed25519.PrivateKey(privateKey)
+		keys = append(keys, stored)
+	}
+	return keys, rows.Err()
+}
+
+// Supprime les clés créées avant before (plus publiées dans le JWKS).
+func (s Service) DeleteSigningKeysBefore(before time.Time) error {
+	_, err := s.DB.Exec("DELETE FROM signing_keys WHERE created_at <= ?", before.UTC())
+	return err
+}
@@ -0,0 +1,164 @@
+/*
+Ce fichier définit Store, l’interface de stockage utilisée par le serveur HTTP.
+
+Deux implémentations :
+
+Service : MySQL ou PostgreSQL (BLUEPRINT_DB_DRIVER), utilisée en production (voir database.go).
+
+MemoryStore : en mémoire, pour exercer toute l’API avec httptest sans base de données (voir memory.go).
+
+Les deux respectent le même contrat, y compris les erreurs attendues par les handlers :
+sql.ErrNoRows quand une ligne n’existe pas, ErrDuplicateReport, ErrUnknownRole.
+*/
+
+package database
+
+import (
+	"time"
+
+	"auth/internal/auth"
+	"auth/internal/mailer"
+)
+
+type Store interface {
+	Health() map[string]string
+	Close() error
+
+	// Utilisateurs
+	FindUserByEmail(email string) (*User, error)
+	GetUserByID(userID int) (*User, error)
+	SearchUsers(query string, limit int) ([]PublicUser, error)
+	CreateEmailUser(email, password, name, locale, code string, codeExpiresAt time.Time, verificationEmail mailer.Message) (int, error)
+	VerifyPassword(hashedPassword, password string) bool
+	UpdateUserPassword(userID int, password string) error
+	MarkUserAsVerified(email string) error
+	SetUserLocale(userID int, locale string) error
+
+	// Sessions
+	CreateSession(userID int, token string, rememberMe bool, userAgent, ipAddress string) (time.Time, error)
+	GetUserBySessionToken(token string) (*User, error)
+	ListUserSessions(userID int, currentToken string) ([]Session, error)
+	IsNewDevice(userID int, userAgent string) (bool, error)
+	DeleteSession(token string) error
+	DeleteUserSessions(userID int) error
+	DeleteUserSessionByID(userID int, sessionID int64) (bool, error)
+	DeleteOtherUserSessions(userID int, keepToken string) (int64, error)
+
+	// Codes de vérification d’email et réinitialisation du mot de passe
+	SaveVerificationCode(email, code string, expiresAt time.Time, verificationEmail mailer.Message) error
+	VerifyCode(email, code string) (bool, error)
+	VerificationCodeCooldown(email string) (time.Duration, error)
+	SavePasswordResetToken(userID int, tokenHash string, expiresAt time.Time, resetEmail mailer.Message) error
+	ConsumePasswordResetToken(tokenHash string) (int, error)
+
+	// Protection contre la force brute
+	GetThrottles(keys ...string) (map[string]Throttle, error)
+	ReserveThrottleAttempt(key string, seen *Throttle, window time.Duration) (bool, error)
+	ReleaseThrottleAttempt(key string) error
+	ResetThrottle(key string) error
+	PruneThrottles(olderThan time.Duration) (int64, error)
+
+	// Identités externes
+	FindUserByIdentity(provider, providerUserID string) (int, error)
+	CreateProviderUser(provider, providerUserID, email, name, picture string, verified bool) (int, error)
+	ListUserIdentities(userID int) ([]Identity, error)
+	LinkIdentity(userID int, provider, providerUserID, email string) error
+	UnlinkIdentity(userID int, id int64) (bool, error)
+	ClaimUnverifiedUser(userID int, provider, providerUserID, email string) error
+	CountLoginMethods(userID int) (int, error)
+
+	// Double authentification (TOTP)
+	GetTOTPEnrollment(userID int) (*TOTPEnrollment, error)
+	HasTOTPEnabled(userID int) (bool, error)
+	SaveTOTPSecret(userID int, secret string) error
+	ConfirmTOTP(userID int, step int64) error
+	MarkTOTPStepUsed(userID int, step int64) (bool, error)
+	DeleteTOTP(userID int) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int) (int, error)
+	CreateLoginChallenge(userID int, tokenHash string, rememberMe bool, expiresAt time.Time) error
+	GetLoginChallenge(tokenHash string) (*LoginChallenge, error)
+	ReserveLoginChallengeAttempt(id int64) (bool, error)
+	ConsumeLoginChallenge(id int64) (bool, error)
+
+	// Passkeys (WebAuthn)
+	SaveWebAuthnCredential(userID int, credentialID []byte, name string, data []byte) error
+	ListWebAuthnCredentials(userID int) ([]WebAuthnCredential, error)
+	UpdateWebAuthnCredential(credentialID []byte, data []byte) error
+	DeleteWebAuthnCredential(userID int, id int64) (bool, error)
+	SaveWebAuthnCeremony(idHash string, userID int, ceremony string, data []byte, expiresAt time.Time) error
+	ConsumeWebAuthnCeremony(idHash string, ceremony string) (int, []byte, error)
+
+	// Wallets (Sign-In with Ethereum)
+	SaveSIWENonce(nonce string, expiresAt time.Time) error
+	ConsumeSIWENonce(nonce string) (bool, error)
+	FindUserByWallet(address string) (int, error)
+	LinkWallet(userID int, address string, chainID int64) error
+	ListUserWallets(userID int) ([]Wallet, error)
+	UnlinkWallet(userID int, address string) (bool, error)
+
+	// Fournisseur OpenID Connect
+	CreateOAuthClient(client OAuthClient) error
+	GetOAuthClient(clientID string) (*OAuthClient, error)
+	GetOAuthConsent(userID int, clientID string) (string, error)
+	SaveOAuthConsent(userID int, clientID, scope string) error
+	ListOAuthConsents(userID int) ([]OAuthConsent, error)
+	DeleteOAuthConsent(userID int, clientID string) (bool, error)
+	SaveOAuthAuthorizationRequest(idHash string, a OAuthAuthorization, expiresAt time.Time) error
+	ConsumeOAuthAuthorizationRequest(idHash string) (*OAuthAuthorization, error)
+	SaveOAuthAuthorizationCode(codeHash string, a OAuthAuthorization, expiresAt time.Time) error
+	ConsumeOAuthAuthorizationCode(codeHash string) (*OAuthAuthorization, error)
+
+	// Clés de signature des jetons d’accès
+	SaveSigningKey(key auth.SigningKey) error
+	ListSigningKeys(since time.Time) ([]StoredSigningKey, error)
+	DeleteSigningKeysBefore(before time.Time) error
+
+	// File d’envoi des e-mails
+	EnqueueEmail(msg mailer.Message) error
+	ClaimOutboxEmails(limit int, lease time.Duration) ([]QueuedEmail, error)
+	MarkOutboxEmailSent(id int64) error
+	MarkOutboxEmailFailed(id int64, lastError string, nextAttemptAt time.Time, dead bool) error
+	ListOutboxEmails(status string, limit, offset int) ([]OutboxEmail, error)
+	CountOutboxEmails() (map[string]int, error)
+	RetryOutboxEmail(id int64) (bool, error)
+
+	// Journal d’audit
+	RecordAuthEvent(event AuthEvent) error
+	ListAuthEvents(filter AuthEventFilter) ([]AuthEvent, error)
+
+	// Rôles et permissions
+	GetUserRoles(userID int) ([]string, error)
+	GetUserPermissions(userID int) ([]string, error)
+	UserHasPermission(userID int, permission string) (bool, error)
+	ListRoles() ([]Role, error)
+	ListRoleMembers(role string) ([]RoleMember, error)
+	GrantRole(userID int, role string, grantedBy int) error
+	RevokeRole(userID int, role string) (bool, error)
+	BootstrapAdmin(email string) (bool, error)
+	HasAdmin() (bool, error)
+
+	// Administration des comptes
+	ListUsers(filter UserFilter) ([]AdminUser, int, error)
+	GetAdminUser(userID int) (*AdminUser, error)
+	SuspendUser(userID int, until *time.Time, banned bool, reason string) (int64, error)
+	ReinstateUser(userID int) error
+	RevokeAllUserSessions(userID int) (int64, error)
+	MarkUserVerifiedByID(userID int) error
+
+	// Signalements
+	CreateReport(reporterID int, reportType, target, description string, notification mailer.Message) (int64, error)
+	ListReportsByReporter(reporterID int) ([]Report, error)
+	ListReports(filter ReportFilter) ([]Report, error)
+	GetReport(id int64) (*Report, error)
+	UpdateReportStatus(id int64, status string) error
+	AssignReport(id int64, assigneeID *int64) error
+	AddReportComment(reportID int64, authorID int, body string) (int64, error)
+	ListReportComments(reportID int64) ([]ReportComment, error)
+}
+
+var (
+	_ Store = Service{}
+	_ Store = (*MemoryStore)(nil)
+)
@@ -0,0 +1,108 @@
+/*
+Ce fichier gère les compteurs d’échecs de la protection contre la force brute (table auth_throttles).
+
+Les compteurs sont en base pour être partagés par toutes les instances du service ;
+la politique (délais, verrouillage) est dans auth.ThrottlePolicy.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"auth/internal/database/dialect"
+)
+
+// Nombre d’échecs récents d’un compteur et date du dernier.
+type Throttle struct {
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// Compteurs demandés (les clés sans échec sont absentes du résultat).
+func (s Service) GetThrottles(keys ...string) (map[string]Throttle, error) {
+	throttles := make(map[string]Throttle, len(keys))
+	for _, key := range keys {
+		var throttle Throttle
+		err := s.DB.QueryRow(
+			"SELECT failures, last_failure_at FROM auth_throttles WHERE throttle_key = ?",
+			key,
+		).Scan(&throttle.Failures, &throttle.LastFailureAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		throttles[key] = throttle
+	}
+	return throttles, nil
+}
+
+/*
+Réserve une tentative avant son exécution : elle compte d’avance comme un échec
+(un compteur dont le dernier échec est plus ancien que window repart de 1).
+
+seen est l’état lu par GetThrottles (nil : compteur absent) sur lequel la politique a été évaluée.
+La réservation n’aboutit que si le compteur n’a pas changé depuis ; sinon elle retourne false
+et l’appelant relit le compteur. Des requêtes simultanées ne peuvent donc pas toutes passer
+sur le même état, y compris entre plusieurs instances.
+*/
+func (s Service) ReserveThrottleAttempt(key string, seen *Throttle, window time.Duration) (bool, error) {
+	now := time.Now().UTC()
+
+	var (
+		res sql.Result
+		err error
+	)
+	switch {
+	case seen == nil:
+		query := "INSERT IGNORE INTO auth_throttles (throttle_key, failures, last_failure_at) VALUES (?, 1, ?)"
+		if s.DB.Dialect == dialect.Postgres {
+			query = `INSERT INTO auth_throttles (throttle_key, failures, last_failure_at) VALUES (?, 1, ?)
+			 ON CONFLICT DO NOTHING`
+		}
+		res, err = s.DB.Exec(query, key, now)
+	case seen.LastFailureAt.Before(now.Add(-window)):
+		res, err = s.DB.Exec(
+			`UPDATE auth_throttles SET failures = 1, last_failure_at = ?
+			 WHERE throttle_key = ? AND failures = ? AND last_failure_at = ?`,
+			now, key, seen.Failures, seen.LastFailureAt,
+		)
+	default:
+		res, err = s.DB.Exec(
+			`UPDATE auth_throttles SET failures = failures + 1, last_failure_at = ?
+			 WHERE throttle_key = ? AND failures = ? AND last_failure_at = ?`,
+			now, key, seen.Failures, seen.LastFailureAt,
+		)
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Rend une tentative réservée qui a réussi (le compteur ne descend pas sous zéro).
+func (s Service) ReleaseThrottleAttempt(key string) error {
+	_, err := s.DB.Exec("UPDATE auth_throttles SET failures = failures - 1 WHERE throttle_key = ? AND failures > 0", key)
+	return err
+}
+
+func (s Service) ResetThrottle(key string) error {
+	_, err := s.DB.Exec("DELETE FROM auth_throttles WHERE throttle_key = ?", key)
+	return err
+}
+
+// Supprime les compteurs sans échec depuis olderThan.
+func (s Service) PruneThrottles(olderThan time.Duration) (int64, error) {
+	res, err := s.DB.Exec(
+		"DELETE FROM auth_throttles WHERE last_failure_at < ?",
+		time.Now().UTC().Add(-olderThan),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,223 @@
+/*
+Ce fichier gère le stockage de l’authentification à deux facteurs (TOTP) :
+
+user_totp : secret TOTP de l’utilisateur, confirmé ou non, et dernier pas utilisé (anti-rejeu).
+
+totp_recovery_codes : codes de récupération à usage unique (hash SHA-256 uniquement).
+
+login_challenges : jetons courts émis après le mot de passe, échangés contre une session
+une fois le code TOTP vérifié.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"auth/internal/database/dialect"
+)
+
+// Nombre maximal de codes erronés pour un même challenge de connexion
+const MaxLoginChallengeAttempts = 5
+
+type TOTPEnrollment struct {
+	UserID    int
+	Secret    string
+	Confirmed bool
+	LastStep  int64
+}
+
+type LoginChallenge struct {
+	ID         int64
+	UserID     int
+	RememberMe bool
+	Attempts   int
+	ExpiresAt  time.Time
+}
+
+// Récupère l’inscription TOTP d’un utilisateur (sql.ErrNoRows s’il n’en a pas).
+func (s Service) GetTOTPEnrollment(userID int) (*TOTPEnrollment, error) {
+	var enrollment TOTPEnrollment
+	err := s.DB.QueryRow(
+		"SELECT user_id, secret, confirmed, last_used_step FROM user_totp WHERE user_id = ?",
+		userID,
+	).Scan(&enrollment.UserID, &enrollment.Secret, &enrollment.Confirmed, &enrollment.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// Indique si l’utilisateur a une double authentification TOTP active.
+func (s Service) HasTOTPEnabled(userID int) (bool, error) {
+	enrollment, err := s.GetTOTPEnrollment(userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enrollment.Confirmed, nil
+}
+
+/*
+Enregistre un nouveau secret non confirmé.
+Un secret en attente de confirmation est remplacé ; un secret déjà confirmé ne l’est jamais ici.
+*/
+func (s Service) SaveTOTPSecret(userID int, secret string) error {
+	query := `INSERT INTO user_totp (user_id, secret, confirmed, last_used_step) VALUES (?, ?, FALSE, 0)
+		 ON DUPLICATE KEY UPDATE secret = IF(confirmed, secret, VALUES(secret))`
+	if s.DB.Dialect == dialect.Postgres {
+		query = `INSERT INTO user_totp (user_id, secret, confirmed, last_used_step) VALUES (?, ?, FALSE, 0)
+		 ON CONFLICT (user_id) DO UPDATE
+		   SET secret = CASE WHEN user_totp.confirmed THEN user_totp.secret ELSE EXCLUDED.secret END`
+	}
+	_, err := s.DB.Exec(query, userID, secret)
+	return err
+}
+
+// Active la double authentification après vérification du premier code.
+func (s Service) ConfirmTOTP(userID int, step int64) error {
+	_, err := s.DB.Exec(
+		"UPDATE user_totp SET confirmed = TRUE, confirmed_at = NOW(), last_used_step = ? WHERE user_id = ?",
+		step, userID,
+	)
+	return err
+}
+
+/*
+Mémorise le dernier pas TOTP accepté.
+La condition last_used_step < ? garantit qu’un code ne peut être utilisé qu’une fois,
+même si deux requêtes arrivent simultanément.
+*/
+func (s Service) MarkTOTPStepUsed(userID int, step int64) (bool, error) {
+	res, err := s.DB.Exec(
+		"UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?",
+		step, userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Désactive la double authentification et supprime les codes de récupération.
+func (s Service) DeleteTOTP(userID int) error {
+	if _, err := s.DB.Exec("DELETE FROM totp_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec("DELETE FROM user_totp WHERE user_id = ?", userID)
+	return err
+}
+
+// Remplace tous les codes de récupération de l’utilisateur (hashs uniquement).
+func (s Service) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM totp_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Consomme un code de récupération ; retourne false s’il est inconnu ou déjà utilisé.
+func (s Service) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	res, err := s.DB.Exec(
+		"UPDATE totp_recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Nombre de codes de récupération encore utilisables.
+func (s Service) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := s.DB.QueryRow(
+		"SELECT COUNT(*) FROM totp_recovery_codes WHERE user_id = ? AND used_at IS NULL",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// Crée un challenge de connexion (seul le hash du jeton est stocké) et purge les challenges expirés.
+func (s Service) CreateLoginChallenge(userID int, tokenHash string, rememberMe bool, expiresAt time.Time) error {
+	if _, err := s.DB.Exec("DELETE FROM login_challenges WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	_, err := s.DB.Exec(
+		"INSERT INTO login_challenges (user_id, token_hash, remember_me, expires_at) VALUES (?, ?, ?, ?)",
+		userID, tokenHash, rememberMe, expiresAt,
+	)
+	return err
+}
+
+/*
+Récupère un challenge encore valide : non expiré et sous la limite de tentatives.
+Retourne sql.ErrNoRows sinon.
+*/
+func (s Service) GetLoginChallenge(tokenHash string) (*LoginChallenge, error) {
+	var challenge LoginChallenge
+	err := s.DB.QueryRow(
+		`SELECT id, user_id, remember_me, attempts, expires_at FROM login_challenges
+		 WHERE token_hash = ? AND expires_at > NOW() AND attempts < ?`,
+		tokenHash, MaxLoginChallengeAttempts,
+	).Scan(&challenge.ID, &challenge.UserID, &challenge.RememberMe, &challenge.Attempts, &challenge.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+/*
+Réserve une tentative sur un challenge avant de vérifier le code.
+Retourne false si la limite est atteinte ou si le challenge a expiré ou été consommé entre-temps.
+*/
+func (s Service) ReserveLoginChallengeAttempt(id int64) (bool, error) {
+	res, err := s.DB.Exec(
+		"UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ? AND attempts < ? AND expires_at > NOW()",
+		id, MaxLoginChallengeAttempts,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+/*
+Consomme un challenge après un code valide.
+Retourne false si une autre requête l’a déjà consommé : une seule session est créée par challenge.
+*/
+func (s Service) ConsumeLoginChallenge(id int64) (bool, error) {
+	res, err := s.DB.Exec("DELETE FROM login_challenges WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,177 @@
+/*
+Ce fichier gère les codes de vérification d’email (table verification_codes).
+
+Le code à 6 chiffres est tiré avec crypto/rand ; seule son empreinte HMAC-SHA256
+(clé VERIFICATION_CODE_KEY, liée à l’email) est stockée, et la comparaison se fait en temps constant.
+Un email n’a qu’un code actif : en émettre un nouveau remplace les précédents.
+*/
+
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"auth/internal/mailer"
+)
+
+// Nombre de mauvais codes acceptés avant que le code en cours d’un email soit invalidé.
+const MaxVerificationAttempts = 5
+
+// Délai minimum entre deux envois de code au même email.
+const VerificationResendCooldown = time.Minute
+
+/*
+Clé HMAC configurée (VERIFICATION_CODE_KEY, longueur vérifiée au chargement de la configuration).
+Sans clé, une clé aléatoire est générée au démarrage : les codes en cours ne survivent pas
+à un redémarrage et ne sont pas reconnus par les autres instances du service.
+*/
+func VerificationCodeKey(configured string) []byte {
+	if configured != "" {
+		return []byte(configured)
+	}
+
+	log.Println("Warning: VERIFICATION_CODE_KEY is not set, using a random key (pending codes are lost on restart)")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal("Failed to generate verification code key:", err)
+	}
+	return key
+}
+
+// Génère un code à 6 chiffres (100000 à 999999) avec crypto/rand.
+func GenerateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(900000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %v", err)
+	}
+	return fmt.Sprintf("%d", n.Int64()+100000), nil
+}
+
+// Empreinte HMAC-SHA256 (hex) d’un code, liée à l’email pour lequel il a été émis.
+func hashVerificationCode(key []byte, email, code string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email)) + ":" + strings.TrimSpace(code)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+Sauvegarde un nouveau code pour vérification (il remplace les précédents)
+et met en file l’e-mail qui le contient, dans la même transaction.
+*/
+func (s Service) SaveVerificationCode(email, code string, expiresAt time.Time, verificationEmail mailer.Message) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.saveVerificationCode(tx, email, code, expiresAt); err != nil {
+		return err
+	}
+	if err := enqueueEmail(tx, verificationEmail); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s Service) saveVerificationCode(exec execer, email, code string, expiresAt time.Time) error {
+	if _, err := exec.Exec("DELETE FROM verification_codes WHERE email = ?", email); err != nil {
+		return err
+	}
+	_, err := exec.Exec(
+		"INSERT INTO verification_codes (email, code_hash, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		email, hashVerificationCode(s.CodeKey, email, code), expiresAt.UTC(), time.Now().UTC(),
+	)
+	return err
+}
+
+/*
+Vérifie le code actif de l’email :
+
+# S’il existe et n’a pas déjà été utilisé
+
+# S’il n’est pas expiré
+
+# S’il n’a pas atteint MaxVerificationAttempts essais
+
+# Si l’empreinte correspond (comparaison en temps constant)
+
+L’essai est réservé par un UPDATE conditionnel avant la comparaison :
+des requêtes simultanées ne peuvent pas dépasser la limite.
+*/
+func (s Service) VerifyCode(email, code string) (bool, error) {
+	var (
+		id        int
+		codeHash  string
+		used      bool
+		attempts  int
+		expiresAt time.Time
+	)
+	err := s.DB.QueryRow(
+		`SELECT id, code_hash, used, attempts, expires_at FROM verification_codes
+		 WHERE email = ? ORDER BY created_at DESC, id DESC LIMIT 1`,
+		email,
+	).Scan(&id, &codeHash, &used, &attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if used || attempts >= MaxVerificationAttempts || time.Now().After(expiresAt) {
+		return false, nil
+	}
+
+	res, err := s.DB.Exec(
+		"UPDATE verification_codes SET attempts = attempts + 1 WHERE id = ? AND used = FALSE AND attempts < ?",
+		id, MaxVerificationAttempts,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+
+	if !hmac.Equal([]byte(codeHash), []byte(hashVerificationCode(s.CodeKey, email, code))) {
+		return false, nil
+	}
+
+	// used = FALSE : deux vérifications simultanées ne peuvent pas consommer le même code
+	res, err = s.DB.Exec("UPDATE verification_codes SET used = TRUE WHERE id = ? AND used = FALSE", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Temps restant avant de pouvoir envoyer un nouveau code à cet email (0 : envoi autorisé).
+func (s Service) VerificationCodeCooldown(email string) (time.Duration, error) {
+	var createdAt time.Time
+	err := s.DB.QueryRow(
+		"SELECT created_at FROM verification_codes WHERE email = ? ORDER BY created_at DESC LIMIT 1",
+		email,
+	).Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if wait := createdAt.Add(VerificationResendCooldown).Sub(time.Now()); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,113 @@
+/*
+Ce fichier gère les wallets Ethereum vérifiés (Sign-In with Ethereum) :
+
+user_wallets : adresses dont l’utilisateur a prouvé la possession par signature.
+Une adresse n’appartient qu’à un seul utilisateur ; elle est stockée en minuscules.
+
+siwe_nonces : nonces à usage unique inclus dans les messages SIWE (anti-rejeu).
+*/
+
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"auth/internal/database/dialect"
+)
+
+type Wallet struct {
+	Address    string    `json:"address"`
+	ChainID    int64     `json:"chain_id"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// Enregistre un nonce SIWE valable jusqu’à expiresAt.
+func (s Service) SaveSIWENonce(nonce string, expiresAt time.Time) error {
+	if _, err := s.DB.Exec("DELETE FROM siwe_nonces WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec("INSERT INTO siwe_nonces (nonce, expires_at) VALUES (?, ?)", nonce, expiresAt)
+	return err
+}
+
+// Consomme un nonce : retourne false s’il est inconnu, expiré ou déjà utilisé.
+func (s Service) ConsumeSIWENonce(nonce string) (bool, error) {
+	res, err := s.DB.Exec("DELETE FROM siwe_nonces WHERE nonce = ? AND expires_at > NOW()", nonce)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Retrouve l’utilisateur propriétaire d’une adresse vérifiée (sql.ErrNoRows sinon).
+func (s Service) FindUserByWallet(address string) (int, error) {
+	var userID int
+	err := s.DB.QueryRow(
+		"SELECT user_id FROM user_wallets WHERE address = ?",
+		strings.ToLower(address),
+	).Scan(&userID)
+	return userID, err
+}
+
+/*
+Lie une adresse vérifiée à un utilisateur.
+Si l’adresse est déjà liée au même utilisateur, seule la date de vérification est mise à jour.
+L’unicité sur address empêche de lier un wallet à deux comptes.
+*/
+func (s Service) LinkWallet(userID int, address string, chainID int64) error {
+	query := `INSERT INTO user_wallets (user_id, address, chain_id, verified_at) VALUES (?, ?, ?, NOW())
+		 ON DUPLICATE KEY UPDATE verified_at = IF(user_id = VALUES(user_id), NOW(), verified_at)`
+	if s.DB.Dialect == dialect.Postgres {
+		query = `INSERT INTO user_wallets (user_id, address, chain_id, verified_at) VALUES (?, ?, ?, NOW())
+		 ON CONFLICT (address) DO UPDATE
+		   SET verified_at = CASE WHEN user_wallets.user_id = EXCLUDED.user_id THEN NOW() ELSE user_wallets.verified_at END`
+	}
+	_, err := s.DB.Exec(query, userID, strings.ToLower(address), chainID)
+	return err
+}
+
+// Liste les wallets vérifiés d’un utilisateur.
+func (s Service) ListUserWallets(userID int) ([]Wallet, error) {
+	rows, err := s.DB.Query(
+		"SELECT address, chain_id, verified_at FROM user_wallets WHERE user_id = ? ORDER BY verified_at ASC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := []Wallet{}
+	for rows.Next() {
+		var wallet Wallet
+		var chainID sql.NullInt64
+		if err := rows.Scan(&wallet.Address, &chainID, &wallet.VerifiedAt); err != nil {
+			return nil, err
+		}
+		wallet.ChainID = chainID.Int64
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// Supprime le lien entre un utilisateur et une adresse.
+func (s Service) UnlinkWallet(userID int, address string) (bool, error) {
+	res, err := s.DB.Exec(
+		"DELETE FROM user_wallets WHERE user_id = ? AND address = ?",
+		userID, strings.ToLower(address),
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
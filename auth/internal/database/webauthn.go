@@ -0,0 +1,130 @@
+/*
+Ce fichier gère le stockage des passkeys (WebAuthn) :
+
+webauthn_credentials : credentials enregistrés, liés à users.
+Le credential complet (clé publique, compteur de signatures, flags) est stocké en JSON ;
+credential_id est dupliqué dans sa propre colonne pour l’unicité et la recherche.
+
+webauthn_ceremonies : données de session entre l’étape begin et l’étape finish
+(challenge), stockées en base pour fonctionner avec plusieurs instances du service.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+type WebAuthnCredential struct {
+	ID           int64
+	UserID       int
+	CredentialID []byte
+	Name         string
+	Data         []byte // webauthn.Credential sérialisé en JSON
+	CreatedAt    time.Time
+	LastUsedAt   sql.NullTime
+}
+
+func (s Service) SaveWebAuthnCredential(userID int, credentialID []byte, name string, data []byte) error {
+	_, err := s.DB.Exec(
+		"INSERT INTO webauthn_credentials (user_id, credential_id, name, credential) VALUES (?, ?, ?, ?)",
+		userID, credentialID, truncate(name, 100), data,
+	)
+	return err
+}
+
+// Liste les passkeys d’un utilisateur, la plus ancienne en premier.
+func (s Service) ListWebAuthnCredentials(userID int) ([]WebAuthnCredential, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, user_id, credential_id, name, credential, created_at, last_used_at
+		 FROM webauthn_credentials WHERE user_id = ? ORDER BY created_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := []WebAuthnCredential{}
+	for rows.Next() {
+		var credential WebAuthnCredential
+		var name sql.NullString
+		if err := rows.Scan(&credential.ID, &credential.UserID, &credential.CredentialID, &name,
+			&credential.Data, &credential.CreatedAt, &credential.LastUsedAt); err != nil {
+			return nil, err
+		}
+		credential.Name = name.String
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// Met à jour le credential après une connexion (compteur de signatures, flags) et sa date d’utilisation.
+func (s Service) UpdateWebAuthnCredential(credentialID []byte, data []byte) error {
+	_, err := s.DB.Exec(
+		"UPDATE webauthn_credentials SET credential = ?, last_used_at = NOW() WHERE credential_id = ?",
+		data, credentialID,
+	)
+	return err
+}
+
+// Supprime une passkey de l’utilisateur ; retourne false si elle n’existe pas ou appartient à un autre.
+func (s Service) DeleteWebAuthnCredential(userID int, id int64) (bool, error) {
+	res, err := s.DB.Exec("DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+/*
+Sauvegarde les données d’une cérémonie WebAuthn (registration ou login).
+userID vaut 0 pour une connexion par passkey découvrable (utilisateur encore inconnu).
+*/
+func (s Service) SaveWebAuthnCeremony(idHash string, userID int, ceremony string, data []byte, expiresAt time.Time) error {
+	if _, err := s.DB.Exec("DELETE FROM webauthn_ceremonies WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	var user sql.NullInt64
+	if userID != 0 {
+		user = sql.NullInt64{Int64: int64(userID), Valid: true}
+	}
+
+	_, err := s.DB.Exec(
+		"INSERT INTO webauthn_ceremonies (id_hash, user_id, ceremony, data, expires_at) VALUES (?, ?, ?, ?, ?)",
+		idHash, user, ceremony, data, expiresAt,
+	)
+	return err
+}
+
+/*
+Récupère et supprime les données d’une cérémonie : un challenge ne sert qu’une fois.
+Retourne sql.ErrNoRows si la cérémonie est inconnue, expirée ou d’un autre type.
+*/
+func (s Service) ConsumeWebAuthnCeremony(idHash string, ceremony string) (int, []byte, error) {
+	var user sql.NullInt64
+	var data []byte
+	err := s.DB.QueryRow(
+		"SELECT user_id, data FROM webauthn_ceremonies WHERE id_hash = ? AND ceremony = ? AND expires_at > NOW()",
+		idHash, ceremony,
+	).Scan(&user, &data)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := s.DB.Exec("DELETE FROM webauthn_ceremonies WHERE id_hash = ?", idHash)
+	if err != nil {
+		return 0, nil, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return 0, nil, sql.ErrNoRows
+	}
+
+	return int(user.Int64), data, nil
+}
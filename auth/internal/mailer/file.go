@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message enregistré par le backend fichier.
+type StoredMessage struct {
+	ID     string    `json:"id"`
+	SentAt time.Time `json:"sent_at"`
+	Message
+}
+
+/*
+Backend de développement : chaque e-mail est écrit dans Dir sous forme de fichier JSON,
+aucun message ne quitte la machine. Les messages sont consultables sur /dev/mailbox.
+*/
+type FileSender struct {
+	Dir string
+}
+
+var messageIDPattern = regexp.MustCompile(`^[0-9]+-[0-9a-f]+$`)
+
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mailbox: %v", err)
+	}
+	return &FileSender{Dir: dir}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	stored := StoredMessage{
+		ID:      fmt.Sprintf("%d-%s", now.UnixNano(), hex.EncodeToString(random)),
+		SentAt:  now,
+		Message: msg,
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, stored.ID+".json"), data, 0o600); err != nil {
+		return fmt.Errorf("mailbox: %v", err)
+	}
+	return nil
+}
+
+// Liste les messages, du plus récent au plus ancien.
+func (s *FileSender) List() ([]StoredMessage, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []StoredMessage{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !messageIDPattern.MatchString(id) {
+			continue
+		}
+		msg, err := s.Get(id)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, *msg)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].SentAt.After(messages[j].SentAt)
+	})
+	return messages, nil
+}
+
+// Lit un message par son identifiant (os.ErrNotExist s’il est inconnu).
+func (s *FileSender) Get(id string) (*StoredMessage, error) {
+	if !messageIDPattern.MatchString(id) {
+		return nil, os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.Dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var msg StoredMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestFileSender(t *testing.T) {
+	sender, err := NewFileSender(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := Message{From: "SmartEther <no-reply@smartether.app>", To: []string{"alice@example.com"}, Subject: "Hello", HTML: "<p>Hi</p>"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := sender.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].Subject != "Hello" || messages[0].To[0] != "alice@example.com" {
+		t.Fatalf("unexpected mailbox %+v", messages)
+	}
+
+	if _, err := sender.Get("../../etc/passwd"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected invalid id to be rejected, got %v", err)
+	}
+}
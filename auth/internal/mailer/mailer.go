@@ -0,0 +1,69 @@
+/*
+Ce package définit l’envoi des e-mails du service d’authentification derrière une interface,
+pour pouvoir changer de fournisseur sans toucher aux handlers :
+
+	EMAIL_BACKEND=resend : API Resend (clé ResendAPI)
+	EMAIL_BACKEND=smtp   : serveur SMTP (SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD)
+	EMAIL_BACKEND=file   : développement, messages écrits dans MAILBOX_DIR et visibles sur /dev/mailbox
+
+Sans EMAIL_BACKEND, Resend est utilisé si ResendAPI est définie, sinon le backend fichier.
+*/
+
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"auth/internal/config"
+)
+
+// Un e-mail prêt à être envoyé (HTML, avec une version texte optionnelle).
+type Message struct {
+	Template string   `json:"template,omitempty"` // modèle d’origine, pour le suivi des envois
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"`
+	HTML     string   `json:"html"`
+	Text     string   `json:"text,omitempty"`
+}
+
+type EmailSender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Construit le backend choisi par la configuration (EMAIL_BACKEND, déjà validé).
+func New(cfg config.Email) (EmailSender, error) {
+	switch cfg.Backend {
+	case "resend":
+		return NewResendSender(cfg.ResendAPIKey), nil
+	case "smtp":
+		return NewSMTPSender(cfg.SMTP), nil
+	case "file":
+		log.Printf("Email backend: messages are written to %s (see /dev/mailbox)", cfg.MailboxDir)
+		return NewFileSender(cfg.MailboxDir)
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_BACKEND %q (resend, smtp or file)", cfg.Backend)
+	}
+}
+
+/*
+Délai avant la tentative suivante après attempt échecs : 30s, 1min, 2min, 4min…
+plafonné à une heure.
+*/
+func Backoff(attempt int) time.Duration {
+	const base, max = 30 * time.Second, time.Hour
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/resend/resend-go/v2"
+)
+
+// Envoi via l’API Resend.
+type ResendSender struct {
+	Client *resend.Client
+}
+
+func NewResendSender(apiKey string) *ResendSender {
+	return &ResendSender{Client: resend.NewClient(apiKey)}
+}
+
+func (s *ResendSender) Send(ctx context.Context, msg Message) error {
+	sent, err := s.Client.Emails.SendWithContext(ctx, &resend.SendEmailRequest{
+		From:    msg.From,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("resend: %v", err)
+	}
+
+	log.Println("Resend email ID:", sent.Id)
+	return nil
+}
@@ -0,0 +1,100 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"auth/internal/config"
+)
+
+/*
+Envoi via un serveur SMTP classique.
+net/smtp passe en STARTTLS dès que le serveur le propose ;
+l’authentification PLAIN n’est utilisée que si SMTP_USERNAME est défini.
+*/
+type SMTPSender struct {
+	Addr string
+	Auth smtp.Auth
+}
+
+func NewSMTPSender(cfg config.SMTP) *SMTPSender {
+	sender := &SMTPSender{Addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))}
+	if cfg.Username != "" {
+		sender.Auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return sender
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("smtp: invalid sender %q: %v", msg.From, err)
+	}
+
+	body, err := buildMIMEMessage(msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("smtp: %v", err)
+	}
+
+	// net/smtp ne gère pas de contexte : on respecte au moins une annulation préalable
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(s.Addr, s.Auth, from.Address, msg.To, body); err != nil {
+		return fmt.Errorf("smtp: %v", err)
+	}
+	return nil
+}
+
+// Message MIME multipart/alternative (texte puis HTML), encodé en quoted-printable.
+func buildMIMEMessage(msg Message, date time.Time) ([]byte, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	boundary := hex.EncodeToString(random)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", msg.From)
+	for _, to := range msg.To {
+		fmt.Fprintf(&buf, "To: %s\r\n", to)
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	parts := []struct{ contentType, content string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	}
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
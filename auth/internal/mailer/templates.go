@@ -0,0 +1,199 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+/*
+Modèles des e-mails transactionnels, embarqués dans le binaire :
+
+	templates/layout.html         : mise en page HTML commune
+	templates/<locale>/<nom>.html : définit "subject" et "content"
+	templates/<locale>/<nom>.txt  : définit "subject" puis le corps texte
+
+Le sujet est rendu avec text/template (pas d’échappement HTML dans l’en-tête Subject).
+*/
+
+//go:embed templates
+var templateFS embed.FS
+
+const (
+	TemplateVerification   = "verification"
+	TemplatePasswordReset  = "password_reset"
+	TemplateNewDeviceLogin = "new_device_login"
+	TemplateReportReceived = "report_received"
+)
+
+var TemplateNames = []string{TemplateVerification, TemplatePasswordReset, TemplateNewDeviceLogin, TemplateReportReceived}
+
+const DefaultLocale = "en"
+
+var Locales = []string{"en", "fr"}
+
+// Données attendues par chaque modèle.
+type VerificationData struct {
+	Code             string
+	ExpiresInMinutes int
+}
+
+type PasswordResetData struct {
+	ResetURL string
+}
+
+type NewDeviceLoginData struct {
+	Device      string
+	IPAddress   string
+	Time        time.Time
+	SessionsURL string
+}
+
+type ReportReceivedData struct {
+	Type          string
+	Target        string
+	Description   string
+	ReporterEmail string
+}
+
+type localizedTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var templates = parseTemplates()
+
+func parseTemplates() map[string]localizedTemplate {
+	parsed := map[string]localizedTemplate{}
+	for _, locale := range Locales {
+		locale := locale
+		funcs := map[string]interface{}{"locale": func() string { return locale }}
+		for _, name := range TemplateNames {
+			parsed[locale+"/"+name] = localizedTemplate{
+				html: htmltemplate.Must(htmltemplate.New("layout.html").Funcs(funcs).ParseFS(templateFS,
+					"templates/layout.html", "templates/"+locale+"/"+name+".html")),
+				text: texttemplate.Must(texttemplate.New(name+".txt").ParseFS(templateFS,
+					"templates/"+locale+"/"+name+".txt")),
+			}
+		}
+	}
+	return parsed
+}
+
+/*
+Rend un modèle dans la langue demandée (langue par défaut si elle n’est pas traduite).
+Le message retourné contient Template, Subject, HTML et Text ; From et To restent à remplir.
+*/
+func Render(name, locale string, data interface{}) (Message, error) {
+	tmpl, ok := templates[SupportedLocale(locale)+"/"+name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout.html", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Template: name,
+		Subject:  strings.TrimSpace(subject.String()),
+		HTML:     html.String(),
+		Text:     strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+// Retourne la langue si elle est traduite, DefaultLocale sinon ("fr-FR" -> "fr").
+func SupportedLocale(locale string) string {
+	if l, ok := matchLocale(locale); ok {
+		return l
+	}
+	return DefaultLocale
+}
+
+func matchLocale(tag string) (string, bool) {
+	base := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(base, "-_"); i >= 0 {
+		base = base[:i]
+	}
+	for _, l := range Locales {
+		if l == base {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+/*
+Choisit la langue d’un e-mail : celle enregistrée pour l’utilisateur si elle est traduite,
+sinon la préférence la plus forte de l’en-tête Accept-Language, sinon DefaultLocale.
+*/
+func NegotiateLocale(stored, acceptLanguage string) string {
+	if stored != "" {
+		return SupportedLocale(stored)
+	}
+
+	type preference struct {
+		locale string
+		q      float64
+	}
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			preferences = append(preferences, preference{tag, q})
+		}
+	}
+	sort.SliceStable(preferences, func(i, j int) bool { return preferences[i].q > preferences[j].q })
+
+	for _, p := range preferences {
+		if locale, ok := matchLocale(p.locale); ok {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Données d’exemple pour l’aperçu des modèles (/dev/emails).
+func PreviewData(name string) interface{} {
+	switch name {
+	case TemplateVerification:
+		return VerificationData{Code: "123456", ExpiresInMinutes: 10}
+	case TemplatePasswordReset:
+		return PasswordResetData{ResetURL: "http://localhost:3000/reset-password?token=preview"}
+	case TemplateNewDeviceLogin:
+		return NewDeviceLoginData{
+			Device:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Firefox/130.0",
+			IPAddress:   "203.0.113.42",
+			Time:        time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC),
+			SessionsURL: "http://localhost:3000/dashboard",
+		}
+	case TemplateReportReceived:
+		return ReportReceivedData{Type: "contract", Target: "42", Description: "This contract looks fraudulent.", ReporterEmail: "alice@example.com"}
+	}
+	return nil
+}
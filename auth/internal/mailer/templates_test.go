@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	cases := []struct {
+		stored, acceptLanguage, want string
+	}{
+		{"", "fr-FR,fr;q=0.9,en;q=0.8", "fr"},
+		{"", "de-DE,en;q=0.5,fr;q=0.7", "fr"},
+		{"", "de-DE", "en"},
+		{"", "", "en"},
+		{"fr", "en-US", "fr"},
+		{"es", "fr", "en"},
+	}
+	for _, c := range cases {
+		if got := NegotiateLocale(c.stored, c.acceptLanguage); got != c.want {
+			t.Errorf("NegotiateLocale(%q, %q) = %q, want %q", c.stored, c.acceptLanguage, got, c.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, locale := range Locales {
+		for _, name := range TemplateNames {
+			if _, err := Render(name, locale, PreviewData(name)); err != nil {
+				t.Errorf("Render(%q, %q): %v", name, locale, err)
+			}
+		}
+	}
+
+	msg, err := Render(TemplateReportReceived, "fr", ReportReceivedData{Type: "user", Target: "bob", Description: "<script>", ReporterEmail: "a@b.c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Nouveau signalement : user - bob" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if strings.Contains(msg.HTML, "<script>") || !strings.Contains(msg.Text, "<script>") {
+		t.Errorf("expected HTML body to be escaped and text body to be raw")
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Ce fichier gère les jetons d’accès signés (JWT EdDSA) et leur publication.
+
+GET  /.well-known/jwks.json : clés publiques en cours de validité (JWKS)
+POST /auth/token            : échange la session (cookie session_token) contre un jeton d’accès court
+
+Rotation : une nouvelle clé est créée quand la plus récente dépasse ACCESS_TOKEN_KEY_ROTATION.
+Les anciennes clés restent publiées jusqu’à expiration des jetons qu’elles ont signés,
+puis sont supprimées. La session opaque reste le seul moyen d’obtenir un nouveau jeton.
+*/
+
+package server
+
+import (
+	"crypto/ed25519"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+)
+
+// Durée pendant laquelle les clés lues en base sont gardées en mémoire
+const signingKeysCacheTTL = time.Minute
+
+// Clés de signature partagées entre les instances, mises en cache localement.
+type accessTokenKeys struct {
+	mu       sync.Mutex
+	signing  auth.SigningKey
+	keys     []auth.SigningKey
+	loadedAt time.Time
+}
+
+/*
+Retourne la clé de signature courante et les clés encore publiées.
+Crée une nouvelle clé si aucune n’existe ou si la plus récente a dépassé la période de rotation.
+*/
+func (s *Server) signingKeys() (auth.SigningKey, []auth.SigningKey, error) {
+	s.accessKeys.mu.Lock()
+	defer s.accessKeys.mu.Unlock()
+
+	now := time.Now()
+	if len(s.accessKeys.keys) > 0 && now.Sub(s.accessKeys.loadedAt) < signingKeysCacheTTL {
+		return s.accessKeys.signing, s.accessKeys.keys, nil
+	}
+
+	rotation := s.config.AccessTokens.KeyRotation
+	// Une clé reste publiée pendant sa période de signature plus la durée de vie des jetons
+	publishedSince := now.Add(-rotation - s.config.AccessTokens.TTL)
+
+	stored, err := s.db.ListSigningKeys(publishedSince)
+	if err != nil {
+		return auth.SigningKey{}, nil, err
+	}
+
+	if len(stored) == 0 || now.Sub(stored[0].CreatedAt) >= rotation {
+		key, err := auth.GenerateSigningKey()
+		if err != nil {
+			return auth.SigningKey{}, nil, err
+		}
+		if err := s.db.SaveSigningKey(key); err != nil {
+			return auth.SigningKey{}, nil, err
+		}
+		log.Printf("Generated new access token signing key %s", key.ID)
+		stored = append([]database.StoredSigningKey{{Key: key, CreatedAt: now}}, stored...)
+	}
+
+	if err := s.db.DeleteSigningKeysBefore(publishedSince); err != nil {
+		log.Printf("signingKeys: failed to delete expired keys: %v", err)
+	}
+
+	keys := make([]auth.SigningKey, 0, len(stored))
+	for _, k := range stored {
+		keys = append(keys, k.Key)
+	}
+
+	s.accessKeys.signing = keys[0]
+	s.accessKeys.keys = keys
+	s.accessKeys.loadedAt = now
+	return s.accessKeys.signing, s.accessKeys.keys, nil
+}
+
+/*
+Signe un jeton d’accès pour l’utilisateur.
+sessionToken lie le jeton interne à la session qui l’obtient (revendication sid) ;
+audience et scope ne sont renseignés que pour les clients OpenID Connect.
+*/
+func (s *Server) issueAccessToken(user *database.User, sessionToken, audience, scope string) (string, time.Time, error) {
+	key, _, err := s.signingKeys()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// Les rôles ne sont pas exposés aux clients OpenID Connect
+	var roles []string
+	if audience == "" {
+		if roles, err = s.db.GetUserRoles(int(user.ID)); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+
+	var sessionID string
+	if sessionToken != "" {
+		sessionID = auth.SessionID(sessionToken)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.config.AccessTokens.TTL)
+	token, err := auth.SignAccessToken(key, auth.AccessTokenClaims{
+		Issuer:        s.config.AccessTokens.Issuer,
+		Subject:       strconv.FormatInt(user.ID, 10),
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     expiresAt.Unix(),
+		Email:         user.Email,
+		EmailVerified: user.IsVerified,
+		Roles:         roles,
+		SessionID:     sessionID,
+		Audience:      audience,
+		Scope:         scope,
+	})
+	return token, expiresAt, err
+}
+
+// Vérifie un jeton d’accès émis par ce service.
+func (s *Server) verifyAccessToken(token string) (*auth.AccessTokenClaims, error) {
+	_, keys, err := s.signingKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeys := make(map[string]ed25519.PublicKey, len(keys))
+	for _, k := range keys {
+		publicKeys[k.ID] = k.PrivateKey.Public().(ed25519.PublicKey)
+	}
+	return auth.VerifyAccessToken(token, publicKeys, s.config.AccessTokens.Issuer, time.Now())
+}
+
+func (s *Server) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	_, keys, err := s.signingKeys()
+	if err != nil {
+		log.Printf("jwksHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load signing keys")
+		return
+	}
+
+	jwks := make([]auth.JWK, 0, len(keys))
+	for _, k := range keys {
+		jwks = append(jwks, k.JWK())
+	}
+
+	// Les vérificateurs rechargent aussi le JWKS lorsqu’ils rencontrent un kid inconnu
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": jwks,
+	})
+}
+
+func (s *Server) accessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user, sessionToken, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	token, expiresAt, err := s.issueAccessToken(user, sessionToken, "", "")
+	if err != nil {
+		log.Printf("accessTokenHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to issue access token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(time.Until(expiresAt).Seconds()),
+		"expires_at":   expiresAt.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,187 @@
+/*
+Contrôle d’accès par rôles aux routes /api/admin/... :
+
+les routes sont déclarées avec s.requirePermission(<permission>), qui résout l’utilisateur
+de la session (401 sans session valide) puis vérifie que l’un de ses rôles accorde la permission
+(403 sinon, ou si l’email n’est pas vérifié). Le handler retrouve l’utilisateur avec sessionUser(r).
+
+Premier administrateur : au démarrage et à chaque connexion, tant qu’aucun administrateur n’existe,
+le compte vérifié dont l’email vaut ADMIN_BOOTSTRAP_EMAIL reçoit le rôle admin.
+
+GET    /api/admin/roles                     : rôles, permissions et membres
+POST   /api/admin/users/{id}/roles          : {"role": "moderator"}
+DELETE /api/admin/users/{id}/roles/{role}   : le dernier administrateur ne peut pas être retiré
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type sessionUserKey struct{}
+
+// Utilisateur résolu par requirePermission.
+func sessionUser(r *http.Request) *database.User {
+	user, _ := r.Context().Value(sessionUserKey{}).(*database.User)
+	return user
+}
+
+func (s *Server) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _, err := s.currentSession(r)
+			if err != nil {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			if !user.IsVerified {
+				respondWithError(w, http.StatusForbidden, "Forbidden")
+				return
+			}
+
+			allowed, err := s.db.UserHasPermission(int(user.ID), permission)
+			if err != nil {
+				log.Printf("requirePermission error: %v", err)
+				respondWithError(w, http.StatusInternalServerError, "Failed to check permissions")
+				return
+			}
+			if !allowed {
+				respondWithError(w, http.StatusForbidden, "Forbidden")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionUserKey{}, user)))
+		})
+	}
+}
+
+// Accorde le rôle admin au compte ADMIN_BOOTSTRAP_EMAIL tant qu’aucun administrateur n’existe.
+func (s *Server) bootstrapAdmin() {
+	email := strings.TrimSpace(s.config.AdminBootstrapEmail)
+	if email == "" || s.adminBootstrapped.Load() {
+		return
+	}
+
+	granted, err := s.db.BootstrapAdmin(email)
+	if err != nil {
+		log.Printf("bootstrapAdmin error: %v", err)
+		return
+	}
+	if granted {
+		log.Printf("Granted the admin role to %s (ADMIN_BOOTSTRAP_EMAIL)", email)
+	}
+
+	hasAdmin, err := s.db.HasAdmin()
+	if err == nil && hasAdmin {
+		s.adminBootstrapped.Store(true)
+	}
+}
+
+func (s *Server) listRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := s.db.ListRoles()
+	if err != nil {
+		log.Printf("listRolesHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list roles")
+		return
+	}
+
+	type roleWithMembers struct {
+		database.Role
+		Members []database.RoleMember `json:"members"`
+	}
+	result := make([]roleWithMembers, 0, len(roles))
+	for _, role := range roles {
+		members, err := s.db.ListRoleMembers(role.Name)
+		if err != nil {
+			log.Printf("listRolesHandler error: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to list roles")
+			return
+		}
+		result = append(result, roleWithMembers{Role: role, Members: members})
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"roles": result})
+}
+
+func (s *Server) grantRoleHandler(w http.ResponseWriter, r *http.Request) {
+	admin := sessionUser(r)
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if _, err := s.db.GetUserByID(userID); err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	err = s.db.GrantRole(userID, req.Role, int(admin.ID))
+	if errors.Is(err, database.ErrUnknownRole) {
+		respondWithError(w, http.StatusBadRequest, "Unknown role")
+		return
+	}
+	if err != nil {
+		log.Printf("grantRoleHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to grant role")
+		return
+	}
+
+	log.Printf("User %d granted role %s to user %d", admin.ID, req.Role, userID)
+	s.recordAdminEvent(r, admin.ID, int64(userID), database.EventAdminGrantRole, map[string]interface{}{"role": req.Role})
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Role granted",
+	})
+}
+
+func (s *Server) revokeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	admin := sessionUser(r)
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	role := chi.URLParam(r, "role")
+
+	revoked, err := s.db.RevokeRole(userID, role)
+	if err != nil {
+		log.Printf("revokeRoleHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke role")
+		return
+	}
+	if !revoked {
+		if role == database.RoleAdmin {
+			respondWithError(w, http.StatusConflict, "The user does not have this role, or is the last admin")
+			return
+		}
+		respondWithError(w, http.StatusNotFound, "The user does not have this role")
+		return
+	}
+
+	log.Printf("User %d revoked role %s from user %d", admin.ID, role, userID)
+	s.recordAdminEvent(r, admin.ID, int64(userID), database.EventAdminRevokeRole, map[string]interface{}{"role": role})
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Role revoked",
+	})
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"auth/internal/database"
+)
+
+func TestModeratorCannotManageUsers(t *testing.T) {
+	api := newTestAPI(t)
+	moderatorToken := api.signUpWithRole("moderator@example.com", database.RoleModerator)
+	api.signUp("trent@example.com", "password1")
+	base := fmt.Sprintf("/api/admin/users/%d", api.userID("trent@example.com"))
+
+	// users:read et reports:* sont accordés au modérateur
+	api.expect("GET", "/api/admin/users", moderatorToken, nil, http.StatusOK)
+	api.expect("GET", base, moderatorToken, nil, http.StatusOK)
+	api.expect("GET", "/api/admin/reports", moderatorToken, nil, http.StatusOK)
+
+	// users:manage, roles:manage et audit:read ne le sont pas
+	until := time.Now().Add(time.Hour).Format(time.RFC3339)
+	api.expect("POST", base+"/suspend", moderatorToken, map[string]string{"until": until}, http.StatusForbidden)
+	api.expect("POST", base+"/ban", moderatorToken, map[string]string{"reason": "spam"}, http.StatusForbidden)
+	api.expect("POST", base+"/reinstate", moderatorToken, nil, http.StatusForbidden)
+	api.expect("POST", base+"/logout", moderatorToken, nil, http.StatusForbidden)
+	api.expect("POST", base+"/verify-email", moderatorToken, nil, http.StatusForbidden)
+	api.expect("POST", base+"/roles", moderatorToken, map[string]string{"role": database.RoleAdmin}, http.StatusForbidden)
+	api.expect("GET", "/api/admin/auth-events", moderatorToken, nil, http.StatusForbidden)
+
+	user, err := api.store.GetUserByID(api.userID("trent@example.com"))
+	if err != nil || user.IsSuspended(time.Now()) {
+		t.Fatalf("the moderator should not have changed the account, got %+v, %v", user, err)
+	}
+}
+
+func TestBootstrapAdminRequiresVerifiedAccount(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "boss@example.com"
+	api.server.config.AdminBootstrapEmail = email
+
+	// Un compte non vérifié portant l’adresse ne devient pas administrateur
+	api.expect("POST", "/auth/register", "", RegisterRequest{Email: email, Password: "password1", Name: "Boss"}, http.StatusCreated)
+	api.server.bootstrapAdmin()
+	if hasAdmin, _ := api.store.HasAdmin(); hasAdmin {
+		t.Fatal("an unverified account must not be promoted")
+	}
+
+	api.expect("POST", "/auth/verify", "", VerifyRequest{Email: email, Code: api.verificationCode(email)}, http.StatusOK)
+	token := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)["token"].(string)
+	api.expect("GET", "/api/admin/roles", token, nil, http.StatusOK)
+}
+
+func TestBootstrapAdminOnlyWhileNoAdminExists(t *testing.T) {
+	api := newTestAPI(t)
+	api.signUpWithRole("admin@example.com", database.RoleAdmin)
+
+	const email = "boss@example.com"
+	api.server.config.AdminBootstrapEmail = email
+	token := api.signUp(email, "password1")
+	api.expect("GET", "/api/admin/roles", token, nil, http.StatusForbidden)
+
+	members, err := api.store.ListRoleMembers(database.RoleAdmin)
+	if err != nil || len(members) != 1 {
+		t.Fatalf("expected a single admin, got %+v, %v", members, err)
+	}
+}
+
+func TestLastAdminCannotBeRevoked(t *testing.T) {
+	api := newTestAPI(t)
+	firstToken := api.signUpWithRole("admin@example.com", database.RoleAdmin)
+	secondToken := api.signUpWithRole("admin2@example.com", database.RoleAdmin)
+	first := fmt.Sprintf("/api/admin/users/%d/roles/%s", api.userID("admin@example.com"), database.RoleAdmin)
+	second := fmt.Sprintf("/api/admin/users/%d/roles/%s", api.userID("admin2@example.com"), database.RoleAdmin)
+
+	api.expect("DELETE", second, firstToken, nil, http.StatusOK)
+	api.expect("GET", "/api/admin/roles", secondToken, nil, http.StatusForbidden)
+
+	// Le dernier administrateur ne peut pas se retirer le rôle
+	api.expect("DELETE", first, firstToken, nil, http.StatusConflict)
+	api.expect("GET", "/api/admin/roles", firstToken, nil, http.StatusOK)
+}
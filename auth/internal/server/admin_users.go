@@ -0,0 +1,282 @@
+/*
+Gestion des comptes par le support (/api/admin/users...) :
+
+GET  /api/admin/users                      : liste (?query, ?status=active|suspended|banned, ?verified=true|false, ?limit, ?offset)
+GET  /api/admin/users/{id}                 : compte, rôles, sessions actives et identités liées
+POST /api/admin/users/{id}/suspend         : {"until": "2025-01-31T00:00:00Z", "reason": "..."}
+POST /api/admin/users/{id}/ban             : {"reason": "..."}
+POST /api/admin/users/{id}/reinstate       : lève la suspension ou le bannissement
+POST /api/admin/users/{id}/logout          : supprime toutes les sessions
+POST /api/admin/users/{id}/verify-email    : marque l’email comme vérifié
+
+Un compte suspendu ou banni ne peut plus se connecter (mot de passe, OAuth, passkey, SIWE)
+ni obtenir de jeton OpenID Connect (/oauth/token, /oauth/userinfo),
+et ses sessions sont refusées par GetUserBySessionToken.
+*/
+
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxSuspensionReasonLength = 255
+
+// Message renvoyé à un compte suspendu qui tente de se connecter.
+func suspensionMessage(user *database.User) string {
+	if user.Banned || user.SuspendedUntil == nil {
+		return "This account has been banned"
+	}
+	return fmt.Sprintf("This account is suspended until %s", user.SuspendedUntil.UTC().Format(time.RFC3339))
+}
+
+// Lit l’utilisateur {id} ; répond 400/404/500 et retourne nil en cas d’échec.
+func (s *Server) adminUserFromURL(w http.ResponseWriter, r *http.Request, handler string) *database.AdminUser {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return nil
+	}
+
+	user, err := s.db.GetAdminUser(userID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return nil
+	}
+	if err != nil {
+		log.Printf("%s error: %v", handler, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load user")
+		return nil
+	}
+	return user
+}
+
+func (s *Server) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := database.UserFilter{
+		Query:  strings.TrimSpace(query.Get("query")),
+		Status: query.Get("status"),
+	}
+	if filter.Status != "" && !isOneOf(filter.Status, []string{database.UserStatusActive, database.UserStatusSuspended, database.UserStatusBanned}) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+	if verified := query.Get("verified"); verified != "" {
+		value, err := strconv.ParseBool(verified)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid verified filter")
+			return
+		}
+		filter.Verified = &value
+	}
+
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	users, total, err := s.db.ListUsers(filter)
+	if err != nil {
+		log.Printf("listUsersHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"users":  users,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+func (s *Server) getAdminUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := s.adminUserFromURL(w, r, "getAdminUserHandler")
+	if user == nil {
+		return
+	}
+	userID := int(user.ID)
+
+	roles, err := s.db.GetUserRoles(userID)
+	if err != nil {
+		log.Printf("getAdminUserHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+	sessions, err := s.db.ListUserSessions(userID, "")
+	if err != nil {
+		log.Printf("getAdminUserHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+	identities, err := s.db.ListUserIdentities(userID)
+	if err != nil {
+		log.Printf("getAdminUserHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"user":       user,
+		"roles":      roles,
+		"sessions":   sessions,
+		"identities": identities,
+	})
+}
+
+type suspendUserRequest struct {
+	Until  string `json:"until"` // RFC 3339, uniquement pour une suspension
+	Reason string `json:"reason"`
+}
+
+func (s *Server) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.suspendUser(w, r, false)
+}
+
+func (s *Server) banUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.suspendUser(w, r, true)
+}
+
+func (s *Server) suspendUser(w http.ResponseWriter, r *http.Request, ban bool) {
+	admin := sessionUser(r)
+
+	var req suspendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(req.Reason) > maxSuspensionReasonLength {
+		respondWithError(w, http.StatusBadRequest, "Reason is too long")
+		return
+	}
+
+	var until *time.Time
+	if !ban {
+		t, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "until must be an RFC 3339 date")
+			return
+		}
+		if !t.After(time.Now()) {
+			respondWithError(w, http.StatusBadRequest, "until must be in the future")
+			return
+		}
+		t = t.UTC()
+		until = &t
+	}
+
+	user := s.adminUserFromURL(w, r, "suspendUserHandler")
+	if user == nil {
+		return
+	}
+	if user.ID == admin.ID {
+		respondWithError(w, http.StatusBadRequest, "You cannot suspend your own account")
+		return
+	}
+
+	revoked, err := s.db.SuspendUser(int(user.ID), until, ban, req.Reason)
+	if err != nil {
+		log.Printf("suspendUserHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to suspend user")
+		return
+	}
+
+	if ban {
+		log.Printf("User %d banned user %d", admin.ID, user.ID)
+		s.recordAdminEvent(r, admin.ID, user.ID, database.EventAdminBanUser, map[string]interface{}{"reason": req.Reason})
+	} else {
+		log.Printf("User %d suspended user %d until %s", admin.ID, user.ID, until.Format(time.RFC3339))
+		s.recordAdminEvent(r, admin.ID, user.ID, database.EventAdminSuspendUser,
+			map[string]interface{}{"reason": req.Reason, "until": until.Format(time.RFC3339)})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":          "User suspended",
+		"revoked_sessions": revoked,
+	})
+}
+
+func (s *Server) reinstateUserHandler(w http.ResponseWriter, r *http.Request) {
+	admin := sessionUser(r)
+
+	user := s.adminUserFromURL(w, r, "reinstateUserHandler")
+	if user == nil {
+		return
+	}
+
+	if err := s.db.ReinstateUser(int(user.ID)); err != nil {
+		log.Printf("reinstateUserHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reinstate user")
+		return
+	}
+
+	log.Printf("User %d reinstated user %d", admin.ID, user.ID)
+	s.recordAdminEvent(r, admin.ID, user.ID, database.EventAdminReinstateUser, nil)
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "User reinstated",
+	})
+}
+
+func (s *Server) forceLogoutUserHandler(w http.ResponseWriter, r *http.Request) {
+	admin := sessionUser(r)
+
+	user := s.adminUserFromURL(w, r, "forceLogoutUserHandler")
+	if user == nil {
+		return
+	}
+
+	revoked, err := s.db.RevokeAllUserSessions(int(user.ID))
+	if err != nil {
+		log.Printf("forceLogoutUserHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	log.Printf("User %d logged out user %d from %d sessions", admin.ID, user.ID, revoked)
+	s.recordAdminEvent(r, admin.ID, user.ID, database.EventAdminLogoutUser, map[string]interface{}{"revoked": revoked})
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":          "User logged out",
+		"revoked_sessions": revoked,
+	})
+}
+
+func (s *Server) verifyUserEmailHandler(w http.ResponseWriter, r *http.Request) {
+	admin := sessionUser(r)
+
+	user := s.adminUserFromURL(w, r, "verifyUserEmailHandler")
+	if user == nil {
+		return
+	}
+	if user.Verified {
+		respondWithError(w, http.StatusConflict, "Email already verified")
+		return
+	}
+
+	if err := s.db.MarkUserVerifiedByID(int(user.ID)); err != nil {
+		log.Printf("verifyUserEmailHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	log.Printf("User %d marked the email of user %d as verified", admin.ID, user.ID)
+	s.recordAdminEvent(r, admin.ID, user.ID, database.EventAdminVerifyEmail, nil)
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Email verified",
+	})
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+
+	"auth/internal/database"
+)
+
+// Inscrit un compte et lui donne le rôle role ; retourne le jeton de session.
+func (api *testAPI) signUpWithRole(email, role string) string {
+	api.t.Helper()
+
+	token := api.signUp(email, "password1")
+	if err := api.store.GrantRole(api.userID(email), role, 0); err != nil {
+		api.t.Fatal(err)
+	}
+	return token
+}
+
+func (api *testAPI) userID(email string) int {
+	api.t.Helper()
+
+	user, err := api.store.FindUserByEmail(email)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	return int(user.ID)
+}
+
+// Connexion Sign-In with Ethereum signée par key, comme personal_sign dans le navigateur.
+func (api *testAPI) siweLogin(key *secp256k1.PrivateKey, address string) (*http.Response, map[string]interface{}) {
+	api.t.Helper()
+	return api.siweVerify("", key, address)
+}
+
+// Message SIWE signé par key, envoyé avec la session token (liaison du wallet) si elle est renseignée.
+func (api *testAPI) siweVerify(token string, key *secp256k1.PrivateKey, address string) (*http.Response, map[string]interface{}) {
+	api.t.Helper()
+
+	nonce := api.expect("GET", "/auth/siwe/nonce", "", nil, http.StatusOK)["nonce"].(string)
+	message := fmt.Sprintf("localhost:3000 wants you to sign in with your Ethereum account:\n%s\n\n"+
+		"Sign in to SmartEther\n\nURI: http://localhost:3000\nVersion: 1\nChain ID: 31337\nNonce: %s\nIssued At: %s",
+		address, nonce, time.Now().UTC().Format(time.RFC3339))
+
+	hash := sha3.NewLegacyKeccak256()
+	fmt.Fprintf(hash, "\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	compact := ecdsa.SignCompact(key, hash.Sum(nil), false)
+	signature := "0x" + hex.EncodeToString(append(compact[1:], compact[0]))
+
+	return api.do("POST", "/auth/siwe/verify", token, SIWEVerifyRequest{Message: message, Signature: signature})
+}
+
+// Obtient un code d’autorisation OpenID Connect pour la session token (consentement déjà donné).
+func (api *testAPI) authorizationCode(token, clientID, redirectURI, verifier string) string {
+	api.t.Helper()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {"openid email"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	resp, _ := api.send("GET", "/oauth/authorize?"+query.Encode(), nil, sessionCookie(token))
+	location, err := resp.Location()
+	if err != nil || location.Query().Get("code") == "" {
+		api.t.Fatalf("expected a redirect with an authorization code, got %d %v", resp.StatusCode, location)
+	}
+	return location.Query().Get("code")
+}
+
+func (api *testAPI) exchangeAuthorizationCode(clientID, redirectURI, code, verifier string) (*http.Response, map[string]interface{}) {
+	api.t.Helper()
+
+	resp, err := api.client.PostForm(api.http.URL+"/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientID},
+		"redirect_uri":  {redirectURI},
+		"code":          {code},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var payload map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&payload)
+	return resp, payload
+}
+
+func TestSuspendedAccountCannotLogIn(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.signUpWithRole("admin@example.com", database.RoleAdmin)
+	const email = "mallory@example.com"
+	userToken := api.signUp(email, "password1")
+	userID := api.userID(email)
+
+	passkey := newTestAuthenticator(api)
+	api.registerPasskey(userToken, passkey)
+
+	var one [32]byte
+	one[31] = 1
+	walletKey := secp256k1.PrivKeyFromBytes(one[:])
+	const wallet = "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"
+	if err := api.store.LinkWallet(userID, wallet, 31337); err != nil {
+		t.Fatal(err)
+	}
+
+	const clientID, redirectURI, verifier = "test-app", "https://app.example.com/callback", "test-code-verifier-with-at-least-forty-three-characters"
+	if err := api.store.CreateOAuthClient(database.OAuthClient{ID: clientID, Name: "Test App", RedirectURIs: []string{redirectURI}, Scopes: "openid email"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.store.SaveOAuthConsent(userID, clientID, "openid email"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Avant la suspension, chaque méthode ouvre une session
+	if resp, payload := api.passkeyLogin(passkey); resp.StatusCode != http.StatusOK {
+		t.Fatalf("passkey login before suspension: got %d (%v)", resp.StatusCode, payload)
+	}
+	if resp, payload := api.siweLogin(walletKey, wallet); resp.StatusCode != http.StatusOK {
+		t.Fatalf("SIWE login before suspension: got %d (%v)", resp.StatusCode, payload)
+	}
+	code := api.authorizationCode(userToken, clientID, redirectURI, verifier)
+	if resp, payload := api.exchangeAuthorizationCode(clientID, redirectURI, code, verifier); resp.StatusCode != http.StatusOK {
+		t.Fatalf("token exchange before suspension: got %d (%v)", resp.StatusCode, payload)
+	}
+
+	// Code obtenu juste avant la suspension
+	pendingCode := api.authorizationCode(userToken, clientID, redirectURI, verifier)
+
+	until := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	suspend := fmt.Sprintf("/api/admin/users/%d/suspend", userID)
+	api.expect("POST", suspend, adminToken, map[string]string{"until": until, "reason": "abuse"}, http.StatusOK)
+	api.expect("GET", "/api/me", userToken, nil, http.StatusUnauthorized)
+
+	login := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusForbidden)
+	if login["error"] != "This account is suspended until "+until {
+		t.Errorf("unexpected suspension message: %v", login)
+	}
+	if resp, payload := api.passkeyLogin(passkey); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("passkey login while suspended: expected 403, got %d (%v)", resp.StatusCode, payload)
+	}
+	if resp, payload := api.siweLogin(walletKey, wallet); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("SIWE login while suspended: expected 403, got %d (%v)", resp.StatusCode, payload)
+	}
+	resp, payload := api.exchangeAuthorizationCode(clientID, redirectURI, pendingCode, verifier)
+	if resp.StatusCode != http.StatusBadRequest || payload["error"] != "invalid_grant" {
+		t.Errorf("token exchange while suspended: expected invalid_grant, got %d (%v)", resp.StatusCode, payload)
+	}
+}
+
+func TestSuspensionExpires(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "niaj@example.com"
+	api.signUp(email, "password1")
+	userID := api.userID(email)
+
+	soon := time.Now().Add(time.Minute)
+	if _, err := api.store.SuspendUser(userID, &soon, false, "cooldown"); err != nil {
+		t.Fatal(err)
+	}
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusForbidden)
+
+	// La date de fin est passée : le compte se reconnecte sans intervention
+	past := time.Now().Add(-time.Second)
+	if _, err := api.store.SuspendUser(userID, &past, false, "cooldown"); err != nil {
+		t.Fatal(err)
+	}
+	token := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)["token"].(string)
+	api.expect("GET", "/api/me", token, nil, http.StatusOK)
+}
+
+func TestSuspendRequiresFutureDate(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.signUpWithRole("admin@example.com", database.RoleAdmin)
+	api.signUp("olivia@example.com", "password1")
+
+	path := fmt.Sprintf("/api/admin/users/%d/suspend", api.userID("olivia@example.com"))
+	api.expect("POST", path, adminToken, map[string]string{"until": "tomorrow"}, http.StatusBadRequest)
+	api.expect("POST", path, adminToken, map[string]string{"until": time.Now().Add(-time.Hour).Format(time.RFC3339)}, http.StatusBadRequest)
+
+	self := fmt.Sprintf("/api/admin/users/%d/suspend", api.userID("admin@example.com"))
+	api.expect("POST", self, adminToken, map[string]string{"until": time.Now().Add(time.Hour).Format(time.RFC3339)}, http.StatusBadRequest)
+}
+
+func TestReinstateLiftsBan(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.signUpWithRole("admin@example.com", database.RoleAdmin)
+	const email = "peggy@example.com"
+	api.signUp(email, "password1")
+
+	base := fmt.Sprintf("/api/admin/users/%d", api.userID(email))
+	api.expect("POST", base+"/ban", adminToken, map[string]string{"reason": "spam"}, http.StatusOK)
+	login := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusForbidden)
+	if login["error"] != "This account has been banned" {
+		t.Errorf("unexpected ban message: %v", login)
+	}
+
+	api.expect("POST", base+"/reinstate", adminToken, nil, http.StatusOK)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)
+
+	events, err := api.store.ListAuthEvents(database.AuthEventFilter{Event: database.EventAdminReinstateUser, Limit: 10})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("expected the reinstatement in the audit log, got %+v, %v", events, err)
+	}
+}
+
+func TestForceLogoutRevokesEverySession(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.signUpWithRole("admin@example.com", database.RoleAdmin)
+	const email = "rupert@example.com"
+	first := api.signUp(email, "password1")
+	second := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)["token"].(string)
+
+	path := fmt.Sprintf("/api/admin/users/%d/logout", api.userID(email))
+	api.expect("POST", path, "", nil, http.StatusUnauthorized)
+	logout := api.expect("POST", path, adminToken, nil, http.StatusOK)
+	if logout["revoked_sessions"] != float64(2) {
+		t.Errorf("expected two revoked sessions, got %v", logout)
+	}
+
+	api.expect("GET", "/api/me", first, nil, http.StatusUnauthorized)
+	api.expect("GET", "/api/me", second, nil, http.StatusUnauthorized)
+	api.expect("GET", "/api/me", adminToken, nil, http.StatusOK)
+	// Déconnexion forcée, pas suspension : le compte peut se reconnecter
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)
+}
+
+func TestAdminVerifiesEmail(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.signUpWithRole("admin@example.com", database.RoleAdmin)
+	const email = "sybil@example.com"
+	api.expect("POST", "/auth/register", "", RegisterRequest{Email: email, Password: "password1", Name: "Sybil"}, http.StatusCreated)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusForbidden)
+
+	path := fmt.Sprintf("/api/admin/users/%d/verify-email", api.userID(email))
+	api.expect("POST", path, adminToken, nil, http.StatusOK)
+	api.expect("POST", path, adminToken, nil, http.StatusConflict)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)
+
+	detail := api.expect("GET", strings.TrimSuffix(path, "/verify-email"), adminToken, nil, http.StatusOK)
+	if user, _ := detail["user"].(map[string]interface{}); user["verified"] != true {
+		t.Errorf("expected the account to be verified, got %v", detail)
+	}
+}
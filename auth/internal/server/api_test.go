@@ -0,0 +1,264 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"auth/internal/auth"
+	"auth/internal/config"
+	"auth/internal/database"
+	"auth/internal/mailer"
+)
+
+// Capture les e-mails envoyés par le worker de la file.
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (s *recordingSender) Send(ctx context.Context, msg mailer.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+type testAPI struct {
+	t      *testing.T
+	server *Server
+	store  *database.MemoryStore
+	sender *recordingSender
+	http   *httptest.Server
+	// Ne suit pas les redirections (flux OAuth)
+	client *http.Client
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	cfg := config.Defaults()
+	webAuthn, err := auth.NewWebAuthn(cfg.WebAuthn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := &testAPI{t: t, store: database.NewMemoryStore(), sender: &recordingSender{}}
+	api.server = &Server{
+		config:     cfg,
+		db:         api.store,
+		webAuthn:   webAuthn,
+		accessKeys: &accessTokenKeys{},
+		email:      database.NewEmailService(cfg.Email),
+		sender:     api.sender,
+		outboxWake: make(chan struct{}, 1),
+	}
+	api.http = httptest.NewServer(api.server.RegisterRoutes())
+	t.Cleanup(api.http.Close)
+	api.client = &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return api
+}
+
+// Envoie une requête JSON, avec le cookie de session si token est renseigné.
+func (api *testAPI) do(method, path, token string, body interface{}) (*http.Response, map[string]interface{}) {
+	api.t.Helper()
+
+	var cookies []*http.Cookie
+	if token != "" {
+		cookies = append(cookies, &http.Cookie{Name: "session_token", Value: token})
+	}
+	return api.send(method, path, body, cookies...)
+}
+
+// Envoie une requête JSON avec les cookies donnés.
+func (api *testAPI) send(method, path string, body interface{}, cookies ...*http.Cookie) (*http.Response, map[string]interface{}) {
+	api.t.Helper()
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			api.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, api.http.URL+path, reader)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		api.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var payload map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&payload)
+	return resp, payload
+}
+
+func (api *testAPI) expect(method, path, token string, body interface{}, status int) map[string]interface{} {
+	api.t.Helper()
+
+	resp, payload := api.do(method, path, token, body)
+	if resp.StatusCode != status {
+		api.t.Fatalf("%s %s: expected status %d, got %d (%v)", method, path, status, resp.StatusCode, payload)
+	}
+	return payload
+}
+
+var verificationCodePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// Vide la file d’envoi et retourne le dernier e-mail du modèle template reçu par email.
+func (api *testAPI) lastEmail(email, template string) (mailer.Message, bool) {
+	api.t.Helper()
+
+	api.server.drainEmailOutbox()
+	api.sender.mu.Lock()
+	defer api.sender.mu.Unlock()
+	for i := len(api.sender.messages) - 1; i >= 0; i-- {
+		msg := api.sender.messages[i]
+		if len(msg.To) == 1 && msg.To[0] == email && msg.Template == template {
+			return msg, true
+		}
+	}
+	return mailer.Message{}, false
+}
+
+// Retourne le dernier code de vérification reçu par email.
+func (api *testAPI) verificationCode(email string) string {
+	api.t.Helper()
+
+	msg, ok := api.lastEmail(email, mailer.TemplateVerification)
+	code := verificationCodePattern.FindString(msg.Text)
+	if !ok || code == "" {
+		api.t.Fatalf("no verification code sent to %s", email)
+	}
+	return code
+}
+
+// Inscrit et vérifie un compte, puis ouvre une session ; retourne le jeton de session.
+func (api *testAPI) signUp(email, password string) string {
+	api.t.Helper()
+
+	api.expect("POST", "/auth/register", "", RegisterRequest{Email: email, Password: password, Name: "Test User"}, http.StatusCreated)
+	api.expect("POST", "/auth/verify", "", VerifyRequest{Email: email, Code: api.verificationCode(email)}, http.StatusOK)
+	login := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: password}, http.StatusOK)
+	return login["token"].(string)
+}
+
+func TestRegisterVerifyLoginLogout(t *testing.T) {
+	api := newTestAPI(t)
+	const email, password = "alice@example.com", "correct-horse"
+
+	api.expect("POST", "/auth/register", "", RegisterRequest{Email: email, Password: password, Name: "Alice"}, http.StatusCreated)
+	api.expect("POST", "/auth/register", "", RegisterRequest{Email: "ALICE@example.com", Password: password, Name: "Alice"}, http.StatusConflict)
+
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: password}, http.StatusForbidden)
+
+	code := api.verificationCode(email)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	api.expect("POST", "/auth/verify", "", VerifyRequest{Email: email, Code: wrong}, http.StatusBadRequest)
+	api.expect("POST", "/auth/verify", "", VerifyRequest{Email: email, Code: code}, http.StatusOK)
+	api.expect("POST", "/auth/verify", "", VerifyRequest{Email: email, Code: code}, http.StatusBadRequest)
+
+	login := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: password}, http.StatusOK)
+	token := login["token"].(string)
+
+	me := api.expect("GET", "/api/me", token, nil, http.StatusOK)
+	if me["email"] != email {
+		t.Fatalf("expected /api/me to return %s, got %v", email, me["email"])
+	}
+
+	sessions := api.expect("GET", "/api/sessions", token, nil, http.StatusOK)
+	list, _ := sessions["sessions"].([]interface{})
+	if len(list) != 1 || list[0].(map[string]interface{})["current"] != true {
+		t.Fatalf("expected one current session, got %v", sessions)
+	}
+
+	api.expect("POST", "/auth/logout", token, nil, http.StatusOK)
+	api.expect("GET", "/api/me", token, nil, http.StatusUnauthorized)
+}
+
+func TestMalformedSessionCookieIsRejected(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("bob@example.com", "password1")
+
+	api.expect("GET", "/api/me", token[:len(token)-1]+"x", nil, http.StatusUnauthorized)
+	api.expect("GET", "/api/me", "", nil, http.StatusUnauthorized)
+}
+
+func TestLoginIsThrottledAfterRepeatedFailures(t *testing.T) {
+	api := newTestAPI(t)
+	api.signUp("carol@example.com", "password1")
+
+	bad := LoginRequest{Email: "carol@example.com", Password: "wrong-password"}
+	for i := 0; i <= 3; i++ {
+		api.expect("POST", "/auth/login", "", bad, http.StatusUnauthorized)
+	}
+
+	resp, payload := api.do("POST", "/auth/login", "", LoginRequest{Email: "carol@example.com", Password: "password1"})
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after repeated failures, got %d (%v)", resp.StatusCode, payload)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Fatal("expected a Retry-After header")
+	}
+
+	events, err := api.store.ListAuthEvents(database.AuthEventFilter{Event: database.EventLogin, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Metadata["reason"] != "throttled" {
+		t.Fatalf("expected the throttled attempt in the audit log, got %+v", events)
+	}
+}
+
+func TestAdminRoutesRequirePermission(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.signUp("admin@example.com", "password1")
+	userToken := api.signUp("dave@example.com", "password1")
+
+	api.expect("GET", "/api/admin/users", "", nil, http.StatusUnauthorized)
+	api.expect("GET", "/api/admin/users", adminToken, nil, http.StatusForbidden)
+
+	admin, err := api.store.FindUserByEmail("admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.store.GrantRole(int(admin.ID), database.RoleAdmin, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	list := api.expect("GET", "/api/admin/users?query=dave", adminToken, nil, http.StatusOK)
+	users, _ := list["users"].([]interface{})
+	if len(users) != 1 {
+		t.Fatalf("expected one matching user, got %v", list)
+	}
+	dave := users[0].(map[string]interface{})
+
+	// La suspension déconnecte le compte de tous ses appareils
+	path := fmt.Sprintf("/api/admin/users/%d/ban", int(dave["id"].(float64)))
+	api.expect("POST", path, adminToken, map[string]string{"reason": "spam"}, http.StatusOK)
+	api.expect("GET", "/api/me", userToken, nil, http.StatusUnauthorized)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: "dave@example.com", Password: "password1"}, http.StatusForbidden)
+
+	// Le dernier administrateur ne peut pas perdre son rôle
+	path = fmt.Sprintf("/api/admin/users/%d/roles/%s", admin.ID, database.RoleAdmin)
+	api.expect("DELETE", path, adminToken, nil, http.StatusConflict)
+}
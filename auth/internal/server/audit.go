@@ -0,0 +1,164 @@
+/*
+Journal d’audit de l’authentification (table auth_events) :
+
+GET /api/admin/auth-events : recherche (?user_id, ?actor_id, ?event, ?outcome, ?ip, ?since, ?until (RFC 3339), ?limit, ?offset)
+GET /api/me/activity       : historique de sécurité de l’utilisateur connecté (?limit, ?offset)
+
+Les handlers enregistrent leurs événements avec s.recordEvent ; un échec d’écriture
+dans le journal est tracé dans les logs mais ne fait pas échouer la requête.
+*/
+
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth/internal/database"
+)
+
+// Complète l’événement avec l’adresse IP et le user agent de la requête, puis l’enregistre.
+func (s *Server) recordEvent(r *http.Request, event database.AuthEvent) {
+	event.IPAddress = s.clientIP(r)
+	event.UserAgent = r.UserAgent()
+	if err := s.db.RecordAuthEvent(event); err != nil {
+		log.Printf("recordEvent error (%s): %v", event.Event, err)
+	}
+}
+
+// Raccourci pour un événement dont l’utilisateur est à la fois l’auteur et le compte concerné.
+func (s *Server) recordUserEvent(r *http.Request, userID int64, name, outcome string, metadata map[string]interface{}) {
+	s.recordEvent(r, database.AuthEvent{
+		ActorID:  &userID,
+		UserID:   &userID,
+		Event:    name,
+		Outcome:  outcome,
+		Metadata: metadata,
+	})
+}
+
+// Événement anonyme portant sur un email : rattaché au compte s’il existe.
+func (s *Server) recordEmailEvent(r *http.Request, email, name, outcome string, metadata map[string]interface{}) {
+	event := database.AuthEvent{Email: email, Event: name, Outcome: outcome, Metadata: metadata}
+	if user, err := s.db.FindUserByEmail(email); err == nil {
+		event.UserID = &user.ID
+	}
+	s.recordEvent(r, event)
+}
+
+// Action d’un administrateur sur le compte targetID.
+func (s *Server) recordAdminEvent(r *http.Request, adminID, targetID int64, name string, metadata map[string]interface{}) {
+	s.recordEvent(r, database.AuthEvent{
+		ActorID:  &adminID,
+		UserID:   &targetID,
+		Event:    name,
+		Outcome:  database.OutcomeSuccess,
+		Metadata: metadata,
+	})
+}
+
+func pagination(r *http.Request) (limit, offset int) {
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func (s *Server) listAuthEventsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := database.AuthEventFilter{
+		Event:     query.Get("event"),
+		Outcome:   query.Get("outcome"),
+		IPAddress: query.Get("ip"),
+	}
+	filter.Limit, filter.Offset = pagination(r)
+
+	if filter.Outcome != "" && filter.Outcome != database.OutcomeSuccess && filter.Outcome != database.OutcomeFailure {
+		respondWithError(w, http.StatusBadRequest, "Invalid outcome")
+		return
+	}
+	for param, target := range map[string]*int64{"user_id": &filter.UserID, "actor_id": &filter.ActorID} {
+		if value := query.Get(param); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || id <= 0 {
+				respondWithError(w, http.StatusBadRequest, "Invalid "+param)
+				return
+			}
+			*target = id
+		}
+	}
+	for param, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, param+" must be an RFC 3339 date")
+				return
+			}
+			*target = &t
+		}
+	}
+
+	events, err := s.db.ListAuthEvents(filter)
+	if err != nil {
+		log.Printf("listAuthEventsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list events")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"events": events})
+}
+
+func (s *Server) myActivityHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	filter := database.AuthEventFilter{UserID: user.ID}
+	filter.Limit, filter.Offset = pagination(r)
+
+	events, err := s.db.ListAuthEvents(filter)
+	if err != nil {
+		log.Printf("myActivityHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list activity")
+		return
+	}
+
+	// L’identité des administrateurs n’est pas exposée
+	type activity struct {
+		Event     string                 `json:"event"`
+		Outcome   string                 `json:"outcome"`
+		ByAdmin   bool                   `json:"by_admin,omitempty"`
+		IPAddress string                 `json:"ip_address"`
+		UserAgent string                 `json:"user_agent"`
+		Metadata  map[string]interface{} `json:"metadata,omitempty"`
+		CreatedAt time.Time              `json:"created_at"`
+	}
+	result := make([]activity, 0, len(events))
+	for _, event := range events {
+		byAdmin := event.ActorID != nil && *event.ActorID != user.ID
+		item := activity{
+			Event:     event.Event,
+			Outcome:   event.Outcome,
+			ByAdmin:   byAdmin,
+			IPAddress: event.IPAddress,
+			UserAgent: event.UserAgent,
+			Metadata:  event.Metadata,
+			CreatedAt: event.CreatedAt,
+		}
+		if byAdmin {
+			item.IPAddress, item.UserAgent = "", ""
+		}
+		result = append(result, item)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"activity": result})
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"auth/internal/database"
+)
+
+var consentRequestIDPattern = regexp.MustCompile(`name="request_id" value="([^"]+)"`)
+
+// Dernier événement name du journal pour le compte userID.
+func (api *testAPI) lastEvent(userID int, name string) database.AuthEvent {
+	api.t.Helper()
+
+	events, err := api.store.ListAuthEvents(database.AuthEventFilter{UserID: int64(userID), Event: name, Limit: 1})
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	if len(events) != 1 {
+		api.t.Fatalf("expected a %s event for user %d", name, userID)
+	}
+	return events[0]
+}
+
+// Accepte l’écran de consentement OpenID Connect de clientID pour la session token.
+func (api *testAPI) grantConsent(token, clientID, redirectURI string) {
+	api.t.Helper()
+
+	challenge := sha256.Sum256([]byte("test-code-verifier-with-at-least-forty-three-characters"))
+	query := url.Values{
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {"openid email"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	req, _ := http.NewRequest("GET", api.http.URL+"/oauth/authorize?"+query.Encode(), nil)
+	req.AddCookie(sessionCookie(token))
+	resp, err := api.client.Do(req)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	match := consentRequestIDPattern.FindSubmatch(page)
+	if match == nil {
+		api.t.Fatalf("expected the consent page, got %d %s", resp.StatusCode, page)
+	}
+
+	form := url.Values{"request_id": {string(match[1])}, "decision": {"allow"}}
+	req, _ = http.NewRequest("POST", api.http.URL+"/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(sessionCookie(token))
+	resp, err = api.client.Do(req)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	resp.Body.Close()
+	if location, err := resp.Location(); err != nil || location.Query().Get("code") == "" {
+		api.t.Fatalf("expected a redirect with an authorization code, got %d %v", resp.StatusCode, location)
+	}
+}
+
+func TestLoginMethodAndConsentChangesAreAudited(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "olivia@example.com"
+	token := api.signUp(email, "password1")
+	userID := api.userID(email)
+
+	// Passkey
+	api.registerPasskey(token, newTestAuthenticator(api))
+	if event := api.lastEvent(userID, database.EventPasskeyRegister); event.Outcome != database.OutcomeSuccess {
+		t.Fatalf("unexpected passkey registration event %+v", event)
+	}
+	credentials, err := api.store.ListWebAuthnCredentials(userID)
+	if err != nil || len(credentials) != 1 {
+		t.Fatalf("expected one passkey, got %v, %v", credentials, err)
+	}
+	api.expect("DELETE", fmt.Sprintf("/api/webauthn/credentials/%d", credentials[0].ID), token, nil, http.StatusOK)
+	if event := api.lastEvent(userID, database.EventPasskeyDelete); event.Metadata["credential_id"] != float64(credentials[0].ID) {
+		t.Fatalf("unexpected passkey deletion event %+v", event)
+	}
+
+	// Wallet
+	var one [32]byte
+	one[31] = 1
+	const wallet = "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"
+	if resp, payload := api.siweVerify(token, secp256k1.PrivKeyFromBytes(one[:]), wallet); resp.StatusCode != http.StatusOK {
+		t.Fatalf("wallet link: got %d (%v)", resp.StatusCode, payload)
+	}
+	if event := api.lastEvent(userID, database.EventWalletLink); !strings.EqualFold(fmt.Sprint(event.Metadata["address"]), wallet) {
+		t.Fatalf("unexpected wallet link event %+v", event)
+	}
+	api.expect("DELETE", "/api/wallets/"+wallet, token, nil, http.StatusOK)
+	if event := api.lastEvent(userID, database.EventWalletUnlink); !strings.EqualFold(fmt.Sprint(event.Metadata["address"]), wallet) {
+		t.Fatalf("unexpected wallet unlink event %+v", event)
+	}
+
+	// Consentement OpenID Connect
+	const clientID, redirectURI = "test-app", "https://app.example.com/callback"
+	if err := api.store.CreateOAuthClient(database.OAuthClient{ID: clientID, Name: "Test App", RedirectURIs: []string{redirectURI}, Scopes: "openid email"}); err != nil {
+		t.Fatal(err)
+	}
+	api.grantConsent(token, clientID, redirectURI)
+	if event := api.lastEvent(userID, database.EventOAuthConsentGrant); event.Metadata["client_id"] != clientID || event.Metadata["scope"] != "openid email" {
+		t.Fatalf("unexpected consent grant event %+v", event)
+	}
+	api.expect("DELETE", "/api/oauth/consents/"+clientID, token, nil, http.StatusOK)
+	if event := api.lastEvent(userID, database.EventOAuthConsentRevoke); event.Metadata["client_id"] != clientID {
+		t.Fatalf("unexpected consent revocation event %+v", event)
+	}
+}
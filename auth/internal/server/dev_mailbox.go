@@ -0,0 +1,116 @@
+/*
+Boîte de réception locale du backend e-mail "file" (EMAIL_BACKEND=file) :
+
+GET /dev/mailbox           : liste des e-mails envoyés, du plus récent au plus ancien
+GET /dev/mailbox/{id}      : un e-mail (en-têtes, version texte, aperçu HTML)
+GET /dev/mailbox/{id}/html : corps HTML brut, affiché dans une iframe isolée (sandbox)
+
+Les routes n’existent qu’en développement, lorsque le backend fichier est actif.
+*/
+
+package server
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"auth/internal/mailer"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var mailboxTemplate = template.Must(template.New("mailbox").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Dev mailbox - SmartEther</title>
+  <style>
+    body { font-family: system-ui, sans-serif; background: #0f172a; color: #e2e8f0; margin: 0; padding: 32px; }
+    a { color: #a5b4fc; }
+    table { width: 100%; border-collapse: collapse; }
+    th, td { text-align: left; padding: 8px; border-bottom: 1px solid #334155; }
+    .meta { color: #94a3b8; font-size: 0.9rem; }
+    pre { background: #1e293b; padding: 16px; border-radius: 8px; white-space: pre-wrap; }
+    iframe { width: 100%; height: 70vh; border: 0; background: white; border-radius: 8px; }
+  </style>
+</head>
+<body>
+{{if .Message}}
+  <p><a href="/dev/mailbox">&larr; Mailbox</a></p>
+  {{with .Message}}
+  <h1>{{.Subject}}</h1>
+  <p class="meta">From {{.From}} to {{range $i, $to := .To}}{{if $i}}, {{end}}{{$to}}{{end}} &middot; {{.SentAt.Format "2006-01-02 15:04:05"}} UTC</p>
+  {{if .HTML}}<iframe sandbox src="/dev/mailbox/{{.ID}}/html"></iframe>{{end}}
+  {{if .Text}}<h2>Text version</h2><pre>{{.Text}}</pre>{{end}}
+  {{end}}
+{{else}}
+  <h1>Dev mailbox</h1>
+  <p class="meta">Messages written by the file email backend. Nothing here was actually sent.</p>
+  <table>
+    <tr><th>Sent</th><th>To</th><th>Subject</th></tr>
+    {{range .Messages}}
+    <tr>
+      <td class="meta">{{.SentAt.Format "2006-01-02 15:04:05"}}</td>
+      <td>{{range $i, $to := .To}}{{if $i}}, {{end}}{{$to}}{{end}}</td>
+      <td><a href="/dev/mailbox/{{.ID}}">{{.Subject}}</a></td>
+    </tr>
+    {{else}}
+    <tr><td colspan="3" class="meta">No messages yet.</td></tr>
+    {{end}}
+  </table>
+{{end}}
+</body>
+</html>
+`))
+
+type mailboxPage struct {
+	Messages []mailer.StoredMessage
+	Message  *mailer.StoredMessage
+}
+
+// Enregistre les routes /dev/mailbox si les e-mails sont écrits sur disque (jamais en production).
+func (s *Server) registerDevMailboxRoutes(r chi.Router) {
+	mailbox, ok := s.sender.(*mailer.FileSender)
+	if !ok || s.config.Production() {
+		return
+	}
+
+	r.Get("/dev/mailbox", func(w http.ResponseWriter, r *http.Request) {
+		messages, err := mailbox.List()
+		if err != nil {
+			log.Printf("devMailboxHandler error: %v", err)
+			http.Error(w, "Failed to read mailbox", http.StatusInternalServerError)
+			return
+		}
+		renderMailbox(w, mailboxPage{Messages: messages})
+	})
+
+	r.Get("/dev/mailbox/{id}", func(w http.ResponseWriter, r *http.Request) {
+		msg, err := mailbox.Get(chi.URLParam(r, "id"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		renderMailbox(w, mailboxPage{Message: msg})
+	})
+
+	r.Get("/dev/mailbox/{id}/html", func(w http.ResponseWriter, r *http.Request) {
+		msg, err := mailbox.Get(chi.URLParam(r, "id"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'; img-src * data:; style-src 'unsafe-inline'")
+		_, _ = w.Write([]byte(msg.HTML))
+	})
+}
+
+func renderMailbox(w http.ResponseWriter, page mailboxPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := mailboxTemplate.Execute(w, page); err != nil {
+		log.Printf("renderMailbox error: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"auth/internal/config"
+	"auth/internal/database"
+	"auth/internal/mailer"
+)
+
+func TestDevMailboxIsNotServedInProduction(t *testing.T) {
+	sender, err := mailer.NewFileSender(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for env, status := range map[string]int{"development": http.StatusOK, "production": http.StatusNotFound} {
+		cfg := config.Defaults()
+		cfg.Env = env
+		cfg.Email.Preview = true
+		s := &Server{config: cfg, db: database.NewMemoryStore(), sender: sender}
+		handler := s.RegisterRoutes()
+
+		for _, path := range []string{"/dev/mailbox", "/dev/emails"} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Code != status {
+				t.Errorf("%s %s: expected %d, got %d", env, path, status, rec.Code)
+			}
+		}
+	}
+}
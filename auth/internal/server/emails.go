@@ -0,0 +1,163 @@
+/*
+Ce fichier regroupe ce qui concerne la langue et l’aperçu des e-mails transactionnels :
+
+PUT /api/me/locale           : langue des e-mails de l’utilisateur courant ("" pour suivre le navigateur)
+GET /dev/emails              : liste des modèles (aperçu pour les designers)
+GET /dev/emails/{name}       : rendu d’un modèle avec des données d’exemple
+                               (?locale=fr, ?format=text pour la version texte)
+
+L’aperçu n’est disponible qu’avec le backend fichier ou EMAIL_PREVIEW=true.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"auth/internal/mailer"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Langue d’un e-mail : celle de l’utilisateur, sinon celle du navigateur à l’origine de la requête.
+func emailLocale(r *http.Request, stored string) string {
+	return mailer.NegotiateLocale(stored, r.Header.Get("Accept-Language"))
+}
+
+/*
+Prévient l’utilisateur par e-mail quand il se connecte depuis un appareil encore jamais vu.
+À appeler avant de créer la nouvelle session ; l’e-mail passe par la file d’envoi.
+*/
+func (s *Server) notifyIfNewDevice(userID int, r *http.Request) {
+	newDevice, err := s.db.IsNewDevice(userID, r.UserAgent())
+	if err != nil {
+		log.Printf("notifyIfNewDevice error: %v", err)
+		return
+	}
+	if !newDevice {
+		return
+	}
+
+	user, err := s.db.GetUserByID(userID)
+	if err != nil {
+		log.Printf("notifyIfNewDevice error: %v", err)
+		return
+	}
+
+	locale := emailLocale(r, user.Locale)
+	data := mailer.NewDeviceLoginData{
+		Device:      r.UserAgent(),
+		IPAddress:   s.clientIP(r),
+		Time:        time.Now().UTC(),
+		SessionsURL: s.config.Server.FrontendURL + "/dashboard",
+	}
+	msg, err := s.email.NewDeviceLoginEmail(user.Email, locale, data)
+	if err == nil {
+		err = s.enqueueEmail(msg)
+	}
+	if err != nil {
+		log.Printf("notifyIfNewDevice error: %v", err)
+	}
+}
+
+type SetLocaleRequest struct {
+	Locale string `json:"locale"`
+}
+
+func (s *Server) setLocaleHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SetLocaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Locale != "" && !containsString(mailer.Locales, req.Locale) {
+		respondWithError(w, http.StatusBadRequest, "Unsupported locale")
+		return
+	}
+
+	if err := s.db.SetUserLocale(int(user.ID), req.Locale); err != nil {
+		log.Printf("setLocaleHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update locale")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"locale": req.Locale,
+	})
+}
+
+var emailPreviewIndexTemplate = template.Must(template.New("emails").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Email templates - SmartEther</title>
+  <style>
+    body { font-family: system-ui, sans-serif; background: #0f172a; color: #e2e8f0; padding: 32px; }
+    a { color: #a5b4fc; margin-right: 12px; }
+    li { margin: 8px 0; }
+  </style>
+</head>
+<body>
+  <h1>Email templates</h1>
+  <ul>
+    {{range $name := .Names}}
+    <li><strong>{{$name}}</strong>
+      {{range $.Locales}}<a href="/dev/emails/{{$name}}?locale={{.}}">{{.}}</a><a href="/dev/emails/{{$name}}?locale={{.}}&amp;format=text">{{.}} (text)</a>{{end}}
+    </li>
+    {{end}}
+  </ul>
+</body>
+</html>
+`))
+
+// Enregistre les routes /dev/emails quand l’aperçu des modèles est autorisé (jamais en production).
+func (s *Server) registerEmailPreviewRoutes(r chi.Router) {
+	if _, ok := s.sender.(*mailer.FileSender); (!ok && !s.config.Email.Preview) || s.config.Production() {
+		return
+	}
+
+	r.Get("/dev/emails", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := emailPreviewIndexTemplate.Execute(w, map[string]interface{}{
+			"Names":   mailer.TemplateNames,
+			"Locales": mailer.Locales,
+		})
+		if err != nil {
+			log.Printf("emailPreviewHandler error: %v", err)
+		}
+	})
+
+	r.Get("/dev/emails/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		if !containsString(mailer.TemplateNames, name) {
+			http.NotFound(w, r)
+			return
+		}
+
+		msg, err := mailer.Render(name, r.URL.Query().Get("locale"), mailer.PreviewData(name))
+		if err != nil {
+			log.Printf("emailPreviewHandler error: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("Subject: " + msg.Subject + "\n\n" + msg.Text))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(msg.HTML))
+	})
+}
@@ -0,0 +1,261 @@
+/*
+Ce fichier gère la liaison de comptes entre fournisseurs OAuth et email / mot de passe :
+
+GET    /auth/{provider}?link=true : relie le fournisseur au compte connecté (au lieu de se connecter)
+GET    /api/identities            : identités externes de l’utilisateur courant
+DELETE /api/identities/{id}       : délie une identité, tant qu’un autre moyen de connexion reste
+POST   /api/me/password           : définit (compte OAuth uniquement) ou change le mot de passe
+
+À la connexion, une identité inconnue dont l’email correspond à un compte existant
+n’est fusionnée que si le fournisseur garantit la possession de l’adresse.
+*/
+
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markbates/goth"
+)
+
+// Cookie temporaire qui indique, pendant le flux OAuth, le fournisseur à relier au compte connecté
+const linkIdentityCookie = "oauth_link"
+
+var (
+	errMissingEmail  = errors.New("provider did not return an email address")
+	errAccountExists = errors.New("email already used by another account")
+)
+
+/*
+Première connexion avec une identité externe :
+crée un compte, ou relie l’identité au compte existant de même email si le fournisseur l’a vérifié.
+Un compte existant jamais vérifié est repris par le titulaire de l’adresse :
+ses moyens de connexion et sessions sont supprimés (voir database.ClaimUnverifiedUser).
+*/
+func (s *Server) resolveProviderUser(provider string, user goth.User) (int, error) {
+	if user.Email == "" {
+		return 0, errMissingEmail
+	}
+	verified := auth.ProviderEmailVerified(provider, user.RawData)
+
+	existing, err := s.db.FindUserByEmail(user.Email)
+	if err == sql.ErrNoRows {
+		return s.db.CreateProviderUser(provider, user.UserID, user.Email, user.Name, user.AvatarURL, verified)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !verified {
+		return 0, errAccountExists
+	}
+
+	userID := int(existing.ID)
+	if !existing.IsVerified {
+		// Tout ce que le créateur du compte y a attaché disparaît avec lui
+		if err := s.db.ClaimUnverifiedUser(userID, provider, user.UserID, user.Email); err != nil {
+			return 0, err
+		}
+		log.Printf("User %d claimed by the verified owner of its email via %s", userID, provider)
+		return userID, nil
+	}
+	if err := s.db.LinkIdentity(userID, provider, user.UserID, user.Email); err != nil {
+		return 0, err
+	}
+
+	log.Printf("Linked %s identity to existing user %d (verified email)", provider, userID)
+	return userID, nil
+}
+
+// Fin du flux ?link=true : relie l’identité au compte de la session courante.
+func (s *Server) linkIdentityCallback(w http.ResponseWriter, r *http.Request, provider string, user goth.User) {
+	redirect := func(param string) {
+		http.Redirect(w, r, s.config.Server.FrontendURL+"/dashboard?"+param+"="+url.QueryEscape(provider), http.StatusFound)
+	}
+
+	current, _, err := s.currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, s.config.Server.FrontendURL+"/login", http.StatusFound)
+		return
+	}
+
+	ownerID, err := s.db.FindUserByIdentity(provider, user.UserID)
+	switch {
+	case err == nil && ownerID == int(current.ID):
+		redirect("linked")
+		return
+	case err == nil:
+		// L’identité appartient déjà à un autre compte
+		redirect("link_error")
+		return
+	case err != sql.ErrNoRows:
+		log.Printf("linkIdentityCallback error: %v", err)
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.db.LinkIdentity(int(current.ID), provider, user.UserID, user.Email); err != nil {
+		log.Printf("linkIdentityCallback error: %v", err)
+		http.Error(w, "Failed to link identity", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Linked %s identity to user %d", provider, current.ID)
+	s.recordUserEvent(r, current.ID, database.EventIdentityLink, database.OutcomeSuccess, map[string]interface{}{"provider": provider})
+	redirect("linked")
+}
+
+func (s *Server) listIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	identities, err := s.db.ListUserIdentities(int(user.ID))
+	if err != nil {
+		log.Printf("listIdentitiesHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list identities")
+		return
+	}
+
+	account, err := s.db.FindUserByEmail(user.Email)
+	if err != nil {
+		log.Printf("listIdentitiesHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list identities")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"identities":   identities,
+		"has_password": account.Password.Valid,
+	})
+}
+
+func (s *Server) unlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid identity ID")
+		return
+	}
+
+	if !s.checkRemainingLoginMethod(w, int(user.ID)) {
+		return
+	}
+
+	deleted, err := s.db.UnlinkIdentity(int(user.ID), id)
+	if err != nil {
+		log.Printf("unlinkIdentityHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlink identity")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Identity not found")
+		return
+	}
+
+	s.recordUserEvent(r, user.ID, database.EventIdentityUnlink, database.OutcomeSuccess, map[string]interface{}{"identity_id": id})
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Identity unlinked",
+	})
+}
+
+/*
+Vérifie qu’il restera au moins un moyen de connexion après la suppression d’un d’entre eux ;
+sinon répond 409 et retourne false.
+*/
+func (s *Server) checkRemainingLoginMethod(w http.ResponseWriter, userID int) bool {
+	count, err := s.db.CountLoginMethods(userID)
+	if err != nil {
+		log.Printf("CountLoginMethods error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to check login methods")
+		return false
+	}
+	if count <= 1 {
+		respondWithError(w, http.StatusConflict, "Cannot remove your last login method")
+		return false
+	}
+	return true
+}
+
+type SetPasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+/*
+Définit un mot de passe sur un compte créé via un fournisseur OAuth,
+ou change le mot de passe existant (le mot de passe actuel est alors exigé).
+Les autres sessions sont révoquées.
+*/
+func (s *Server) setPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	user, token, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.NewPassword) < 6 {
+		respondWithError(w, http.StatusBadRequest, "Password must be at least 6 characters")
+		return
+	}
+
+	account, err := s.db.FindUserByEmail(user.Email)
+	if err != nil {
+		log.Printf("setPasswordHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update password")
+		return
+	}
+	if account.Password.Valid {
+		// Même compteur que la connexion : une session volée ne sert pas à deviner le mot de passe
+		throttle := s.throttleCounters("login", account.Email, r)
+		if !s.checkThrottle(w, throttle) {
+			s.recordUserEvent(r, user.ID, database.EventPasswordChange, database.OutcomeFailure, map[string]interface{}{"reason": "throttled"})
+			return
+		}
+		if !s.db.VerifyPassword(account.Password.String, req.CurrentPassword) {
+			s.recordUserEvent(r, user.ID, database.EventPasswordChange, database.OutcomeFailure, map[string]interface{}{"reason": "invalid_password"})
+			respondWithError(w, http.StatusUnauthorized, "Current password is incorrect")
+			return
+		}
+		s.recordThrottleSuccess(throttle)
+	}
+
+	if err := s.db.UpdateUserPassword(int(user.ID), req.NewPassword); err != nil {
+		log.Printf("setPasswordHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update password")
+		return
+	}
+
+	if _, err := s.db.DeleteOtherUserSessions(int(user.ID), token); err != nil {
+		log.Printf("setPasswordHandler error: %v", err)
+	}
+
+	s.recordUserEvent(r, user.ID, database.EventPasswordChange, database.OutcomeSuccess, nil)
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Password updated",
+	})
+}
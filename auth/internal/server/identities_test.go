@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/markbates/goth"
+	"github.com/markbates/goth/gothic"
+	"golang.org/x/oauth2"
+
+	"auth/internal/auth"
+)
+
+// Fournisseur OAuth de test : l’autorisation réussit toujours et retourne user.
+type testProvider struct {
+	name string
+	user goth.User
+}
+
+type testProviderSession struct {
+	AuthURL string
+}
+
+func (p *testProvider) Name() string        { return p.name }
+func (p *testProvider) SetName(name string) { p.name = name }
+func (p *testProvider) Debug(bool)          {}
+
+func (p *testProvider) BeginAuth(state string) (goth.Session, error) {
+	return &testProviderSession{AuthURL: "https://provider.example/authorize?state=" + url.QueryEscape(state)}, nil
+}
+
+func (p *testProvider) UnmarshalSession(data string) (goth.Session, error) {
+	session := &testProviderSession{}
+	err := json.Unmarshal([]byte(data), session)
+	return session, err
+}
+
+func (p *testProvider) FetchUser(goth.Session) (goth.User, error) {
+	return p.user, nil
+}
+
+func (p *testProvider) RefreshToken(string) (*oauth2.Token, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *testProvider) RefreshTokenAvailable() bool { return false }
+
+func (s *testProviderSession) GetAuthURL() (string, error) { return s.AuthURL, nil }
+
+func (s *testProviderSession) Marshal() string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func (s *testProviderSession) Authorize(goth.Provider, goth.Params) (string, error) {
+	return "", nil
+}
+
+// Enregistre les fournisseurs de test auprès de goth (état global du paquet gothic).
+func useTestProviders(api *testAPI, providers ...*testProvider) {
+	gothic.Store = auth.NewOAuthStateStore(api.server.config.Cookies)
+	list := make([]goth.Provider, len(providers))
+	for i, p := range providers {
+		list[i] = p
+	}
+	goth.ClearProviders()
+	goth.UseProviders(list...)
+	api.t.Cleanup(goth.ClearProviders)
+}
+
+/*
+Déroule /auth/{provider} puis le callback, en renvoyant les cookies reçus entre les deux ;
+retourne la redirection finale du callback.
+*/
+func (api *testAPI) oauthLogin(provider string, cookies ...*http.Cookie) *url.URL {
+	api.t.Helper()
+
+	resp, _ := api.send("GET", "/auth/"+provider, nil, cookies...)
+	location, err := resp.Location()
+	if err != nil {
+		api.t.Fatalf("/auth/%s: expected a redirect to the provider, got %d", provider, resp.StatusCode)
+	}
+	for _, cookie := range resp.Cookies() {
+		// Le cookie d’état est d’abord effacé puis reposé : seul le dernier compte
+		if cookie.MaxAge >= 0 {
+			cookies = append(cookies, cookie)
+		}
+	}
+
+	resp, _ = api.send("GET", "/auth/"+provider+"/callback?state="+url.QueryEscape(location.Query().Get("state")), nil, cookies...)
+	location, err = resp.Location()
+	if err != nil {
+		api.t.Fatalf("/auth/%s/callback: expected a redirect, got %d", provider, resp.StatusCode)
+	}
+	return location
+}
+
+// Connexion OAuth qui doit aboutir à une session ; retourne le jeton transmis au gateway.
+func (api *testAPI) oauthSession(provider string) string {
+	api.t.Helper()
+
+	location := api.oauthLogin(provider)
+	token := location.Query().Get("token")
+	if location.Path != "/auth/callback" || token == "" {
+		api.t.Fatalf("%s login did not open a session: %s", provider, location)
+	}
+	return token
+}
+
+func TestClaimingUnverifiedAccountRemovesAttackerCredentials(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "victim@example.com"
+
+	// Microsoft ne garantit pas l’adresse : le compte créé n’est pas vérifié
+	microsoft := &testProvider{name: "microsoft", user: goth.User{UserID: "attacker", Email: email, Name: "Attacker"}}
+	google := &testProvider{name: "google", user: goth.User{UserID: "victim", Email: email, Name: "Victim", RawData: map[string]interface{}{"email_verified": true}}}
+	useTestProviders(api, microsoft, google)
+
+	attackerToken := api.oauthSession("microsoft")
+	attacker, err := api.store.GetUserBySessionToken(attackerToken)
+	if err != nil || attacker.IsVerified {
+		t.Fatalf("expected an unverified account, got %+v, %v", attacker, err)
+	}
+
+	// L’attaquant ajoute ses propres moyens de connexion
+	passkey := newTestAuthenticator(api)
+	api.registerPasskey(attackerToken, passkey)
+	api.expect("POST", "/api/me/password", attackerToken, SetPasswordRequest{NewPassword: "attacker-password"}, http.StatusOK)
+	if err := api.store.SaveTOTPSecret(int(attacker.ID), "JBSWY3DPEHPK3PXP"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.store.ConfirmTOTP(int(attacker.ID), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Le titulaire de l’adresse se connecte avec Google (email vérifié) : pas de challenge TOTP
+	victimToken := api.oauthSession("google")
+	victim, err := api.store.GetUserBySessionToken(victimToken)
+	if err != nil || victim.ID != attacker.ID || !victim.IsVerified {
+		t.Fatalf("expected the account to be claimed and verified, got %+v, %v", victim, err)
+	}
+
+	api.expect("GET", "/api/me", attackerToken, nil, http.StatusUnauthorized)
+	if resp, _ := api.passkeyLogin(passkey); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("the attacker passkey should be removed, got %d", resp.StatusCode)
+	}
+	// Le mot de passe a été supprimé : le compte n’accepte plus que Google
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "attacker-password"}, http.StatusBadRequest)
+	if location := api.oauthLogin("microsoft"); location.Query().Get("error") != "account_exists" {
+		t.Errorf("the attacker Microsoft identity should be removed, got %s", location)
+	}
+
+	identities := api.expect("GET", "/api/identities", victimToken, nil, http.StatusOK)["identities"].([]interface{})
+	if len(identities) != 1 || identities[0].(map[string]interface{})["provider"] != "google" {
+		t.Errorf("expected only the Google identity, got %v", identities)
+	}
+	status := api.expect("GET", "/api/2fa", victimToken, nil, http.StatusOK)
+	if status["totp_enabled"] != false {
+		t.Errorf("the attacker TOTP should be removed, got %v", status)
+	}
+	if count, _ := api.store.CountLoginMethods(int(victim.ID)); count != 1 {
+		t.Errorf("expected a single login method, got %d", count)
+	}
+}
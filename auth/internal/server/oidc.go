@@ -0,0 +1,548 @@
+/*
+Ce fichier fait du service d’authentification un fournisseur OpenID Connect ("Log in with SmartEther").
+
+GET  /.well-known/openid-configuration : document de découverte
+GET  /oauth/authorize                  : flux authorization code + PKCE (S256) ;
+                                         utilisateur non connecté -> page de connexion du frontend,
+                                         connecté sans consentement -> écran de consentement
+POST /oauth/authorize                  : décision de l’écran de consentement (allow / deny)
+POST /oauth/token                      : échange du code contre access_token + id_token
+GET  /oauth/userinfo                   : informations de l’utilisateur selon les scopes accordés
+GET  /.well-known/jwks.json            : clés publiques (voir access_tokens.go)
+
+GET    /api/oauth/consents             : applications autorisées par l’utilisateur courant
+DELETE /api/oauth/consents/{client_id} : retire l’autorisation d’une application
+
+Les clients sont enregistrés en base (table oauth_clients, voir cmd/oauth-client).
+L’émetteur est ACCESS_TOKEN_ISSUER, c’est-à-dire l’URL publique du gateway.
+*/
+
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	oauthAuthorizationRequestTTL = 10 * time.Minute
+	oauthAuthorizationCodeTTL    = time.Minute
+)
+
+var errInvalidOAuthClient = errors.New("invalid OAuth client")
+
+// Libellés affichés sur l’écran de consentement.
+var scopeDescriptions = map[string]string{
+	"openid":  "Sign you in with your SmartEther account",
+	"profile": "Read your name and profile picture",
+	"email":   "Read your email address",
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Authorize {{.ClientName}} - SmartEther</title>
+  <style>
+    body { font-family: system-ui, sans-serif; background: #0f172a; color: #e2e8f0; display: flex; justify-content: center; padding-top: 10vh; }
+    main { background: #1e293b; border-radius: 12px; padding: 32px; max-width: 420px; width: 100%; }
+    h1 { font-size: 1.25rem; margin-top: 0; }
+    ul { padding-left: 20px; }
+    .actions { display: flex; gap: 12px; margin-top: 24px; }
+    button { flex: 1; padding: 10px; border-radius: 8px; border: 0; font-size: 1rem; cursor: pointer; }
+    .allow { background: #6366f1; color: white; }
+    .deny { background: #334155; color: #e2e8f0; }
+    .account { color: #94a3b8; font-size: 0.9rem; }
+  </style>
+</head>
+<body>
+  <main>
+    <h1>{{.ClientName}} wants to access your SmartEther account</h1>
+    <p class="account">Signed in as {{.UserEmail}}</p>
+    <p>This application will be able to:</p>
+    <ul>
+      {{range .Scopes}}<li>{{.}}</li>{{end}}
+    </ul>
+    <form method="POST" action="/oauth/authorize">
+      <input type="hidden" name="request_id" value="{{.RequestID}}">
+      <div class="actions">
+        <button class="deny" type="submit" name="decision" value="deny">Deny</button>
+        <button class="allow" type="submit" name="decision" value="allow">Allow</button>
+      </div>
+    </form>
+  </main>
+</body>
+</html>
+`))
+
+type consentPage struct {
+	ClientName string
+	UserEmail  string
+	Scopes     []string
+	RequestID  string
+}
+
+func (s *Server) openIDConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	issuer := s.config.AccessTokens.Issuer
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                         issuer,
+		"authorization_endpoint":                         issuer + "/oauth/authorize",
+		"token_endpoint":                                 issuer + "/oauth/token",
+		"userinfo_endpoint":                              issuer + "/oauth/userinfo",
+		"jwks_uri":                                       issuer + "/.well-known/jwks.json",
+		"response_types_supported":                       []string{"code"},
+		"grant_types_supported":                          []string{"authorization_code"},
+		"subject_types_supported":                        []string{"public"},
+		"id_token_signing_alg_values_supported":          []string{auth.AccessTokenAlgorithm},
+		"scopes_supported":                               auth.SupportedScopes,
+		"token_endpoint_auth_methods_supported":          []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":               []string{"S256"},
+		"claims_supported":                               []string{"sub", "iss", "aud", "exp", "iat", "nonce", "email", "email_verified", "name", "picture"},
+		"authorization_response_iss_parameter_supported": true,
+	})
+}
+
+func (s *Server) oauthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	// Client ou redirect_uri invalide : on ne redirige surtout pas vers une URL non enregistrée
+	client, err := s.db.GetOAuthClient(q.Get("client_id"))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("oauthAuthorizeHandler error: %v", err)
+		}
+		respondWithError(w, http.StatusBadRequest, "Unknown client")
+		return
+	}
+	redirectURI := q.Get("redirect_uri")
+	if !containsString(client.RedirectURIs, redirectURI) {
+		respondWithError(w, http.StatusBadRequest, "Invalid redirect_uri")
+		return
+	}
+
+	state := q.Get("state")
+	fail := func(code, description string) {
+		redirectWithOAuthError(w, r, redirectURI, state, code, description)
+	}
+
+	if q.Get("response_type") != "code" {
+		fail("unsupported_response_type", "Only the authorization code flow is supported")
+		return
+	}
+	scope := strings.Join(auth.ParseScopes(q.Get("scope")), " ")
+	if !auth.HasScope(scope, "openid") {
+		fail("invalid_scope", "The openid scope is required")
+		return
+	}
+	if !auth.ScopesCovered(scope, client.Scopes) {
+		fail("invalid_scope", "This client is not allowed to request these scopes")
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		fail("invalid_request", "PKCE with the S256 method is required")
+		return
+	}
+
+	prompt := q.Get("prompt")
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		if prompt == "none" {
+			fail("login_required", "The user is not logged in")
+			return
+		}
+		// Connexion sur le frontend, qui revient ensuite sur cette même URL
+		returnTo := "/oauth/authorize?" + r.URL.RawQuery
+		http.Redirect(w, r, s.config.Server.FrontendURL+"/login?return_to="+url.QueryEscape(returnTo), http.StatusFound)
+		return
+	}
+
+	authorization := database.OAuthAuthorization{
+		UserID:        int(user.ID),
+		ClientID:      client.ID,
+		RedirectURI:   redirectURI,
+		Scope:         scope,
+		State:         state,
+		Nonce:         q.Get("nonce"),
+		CodeChallenge: q.Get("code_challenge"),
+	}
+
+	// Consentement déjà donné pour ces scopes : pas d’écran intermédiaire
+	granted, err := s.db.GetOAuthConsent(int(user.ID), client.ID)
+	if err != nil {
+		log.Printf("oauthAuthorizeHandler error: %v", err)
+		fail("server_error", "")
+		return
+	}
+	if granted != "" && auth.ScopesCovered(scope, granted) && prompt != "consent" {
+		s.redirectWithAuthorizationCode(w, r, authorization)
+		return
+	}
+	if prompt == "none" {
+		fail("consent_required", "The user has not authorized this application")
+		return
+	}
+
+	requestID := generateSessionToken()
+	if err := s.db.SaveOAuthAuthorizationRequest(database.HashToken(requestID), authorization, time.Now().Add(oauthAuthorizationRequestTTL)); err != nil {
+		log.Printf("oauthAuthorizeHandler error: %v", err)
+		fail("server_error", "")
+		return
+	}
+
+	page := consentPage{
+		ClientName: client.Name,
+		UserEmail:  user.Email,
+		RequestID:  requestID,
+	}
+	for _, name := range strings.Fields(scope) {
+		page.Scopes = append(page.Scopes, scopeDescriptions[name])
+	}
+
+	// L’écran de consentement ne doit pas pouvoir être intégré dans une autre page (clickjacking)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := consentTemplate.Execute(w, page); err != nil {
+		log.Printf("oauthAuthorizeHandler: failed to render consent page: %v", err)
+	}
+}
+
+// Décision de l’utilisateur sur l’écran de consentement.
+func (s *Server) oauthConsentHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid form body")
+		return
+	}
+
+	authorization, err := s.db.ConsumeOAuthAuthorizationRequest(database.HashToken(r.PostForm.Get("request_id")))
+	if err != nil || authorization.UserID != int(user.ID) {
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("oauthConsentHandler error: %v", err)
+		}
+		respondWithError(w, http.StatusBadRequest, "Authorization request expired, please try again")
+		return
+	}
+
+	if r.PostForm.Get("decision") != "allow" {
+		redirectWithOAuthError(w, r, authorization.RedirectURI, authorization.State, "access_denied", "The user denied the request")
+		return
+	}
+
+	if err := s.db.SaveOAuthConsent(int(user.ID), authorization.ClientID, authorization.Scope); err != nil {
+		log.Printf("oauthConsentHandler error: %v", err)
+		redirectWithOAuthError(w, r, authorization.RedirectURI, authorization.State, "server_error", "")
+		return
+	}
+	s.recordUserEvent(r, user.ID, database.EventOAuthConsentGrant, database.OutcomeSuccess,
+		map[string]interface{}{"client_id": authorization.ClientID, "scope": authorization.Scope})
+
+	s.redirectWithAuthorizationCode(w, r, *authorization)
+}
+
+// Génère un code d’autorisation et renvoie l’utilisateur vers le client.
+func (s *Server) redirectWithAuthorizationCode(w http.ResponseWriter, r *http.Request, authorization database.OAuthAuthorization) {
+	code := generateSessionToken()
+	if err := s.db.SaveOAuthAuthorizationCode(database.HashToken(code), authorization, time.Now().Add(oauthAuthorizationCodeTTL)); err != nil {
+		log.Printf("redirectWithAuthorizationCode error: %v", err)
+		redirectWithOAuthError(w, r, authorization.RedirectURI, authorization.State, "server_error", "")
+		return
+	}
+
+	params := url.Values{
+		"code": {code},
+		"iss":  {s.config.AccessTokens.Issuer},
+	}
+	if authorization.State != "" {
+		params.Set("state", authorization.State)
+	}
+	redirectWithParams(w, r, authorization.RedirectURI, params)
+}
+
+func (s *Server) oauthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+
+	client, err := s.authenticateOAuthClient(r)
+	if err != nil {
+		if err == errInvalidOAuthClient {
+			w.Header().Set("WWW-Authenticate", `Basic realm="SmartEther"`)
+			respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+			return
+		}
+		log.Printf("oauthTokenHandler error: %v", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		respondWithOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Only authorization_code is supported")
+		return
+	}
+
+	authorization, err := s.db.ConsumeOAuthAuthorizationCode(database.HashToken(r.PostForm.Get("code")))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("oauthTokenHandler error: %v", err)
+			respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+		return
+	}
+
+	if authorization.ClientID != client.ID || authorization.RedirectURI != r.PostForm.Get("redirect_uri") {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Authorization code was issued to another client or redirect_uri")
+		return
+	}
+	if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), authorization.CodeChallenge) {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	user, err := s.db.GetUserByID(authorization.UserID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Unknown user")
+		return
+	}
+	// Code obtenu avant une suspension : aucun jeton n’est délivré
+	if user.IsSuspended(time.Now()) {
+		s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "oidc", "client_id": client.ID, "reason": "suspended"})
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", suspensionMessage(user))
+		return
+	}
+
+	accessToken, expiresAt, err := s.issueAccessToken(user, "", client.ID, authorization.Scope)
+	if err != nil {
+		log.Printf("oauthTokenHandler error: %v", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	idToken, err := s.issueIDToken(user, *authorization)
+	if err != nil {
+		log.Printf("oauthTokenHandler error: %v", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(time.Until(expiresAt).Seconds()),
+		"scope":        authorization.Scope,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) oauthUserInfoHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="SmartEther"`)
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_token", "Missing bearer token")
+		return
+	}
+
+	// Seuls les jetons délivrés à un client OpenID Connect avec le scope openid sont acceptés
+	claims, err := s.verifyAccessToken(token)
+	if err != nil || claims.Audience == "" || !auth.HasScope(claims.Scope, "openid") {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired access token")
+		return
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired access token")
+		return
+	}
+	user, err := s.db.GetUserByID(userID)
+	if err != nil || user.IsSuspended(time.Now()) {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired access token")
+		return
+	}
+
+	info := map[string]interface{}{"sub": claims.Subject}
+	if auth.HasScope(claims.Scope, "email") {
+		info["email"] = user.Email
+		info["email_verified"] = user.IsVerified
+	}
+	if auth.HasScope(claims.Scope, "profile") {
+		info["name"] = user.Name
+		info["picture"] = user.AvatarURL
+	}
+
+	respondWithJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) listOAuthConsentsHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	consents, err := s.db.ListOAuthConsents(int(user.ID))
+	if err != nil {
+		log.Printf("listOAuthConsentsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list authorized applications")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"applications": consents,
+	})
+}
+
+func (s *Server) revokeOAuthConsentHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	clientID := chi.URLParam(r, "clientID")
+	deleted, err := s.db.DeleteOAuthConsent(int(user.ID), clientID)
+	if err != nil {
+		log.Printf("revokeOAuthConsentHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke application")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Application not found")
+		return
+	}
+	s.recordUserEvent(r, user.ID, database.EventOAuthConsentRevoke, database.OutcomeSuccess, map[string]interface{}{"client_id": clientID})
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Application access revoked",
+	})
+}
+
+// Signe le jeton d’identité remis au client ; les revendications dépendent des scopes accordés.
+func (s *Server) issueIDToken(user *database.User, authorization database.OAuthAuthorization) (string, error) {
+	key, _, err := s.signingKeys()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := auth.IDTokenClaims{
+		Issuer:    s.config.AccessTokens.Issuer,
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Audience:  authorization.ClientID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.config.AccessTokens.TTL).Unix(),
+		Nonce:     authorization.Nonce,
+	}
+	if auth.HasScope(authorization.Scope, "email") {
+		verified := user.IsVerified
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	if auth.HasScope(authorization.Scope, "profile") {
+		claims.Name = user.Name
+		claims.Picture = user.AvatarURL
+	}
+
+	return auth.SignIDToken(key, claims)
+}
+
+/*
+Authentifie le client sur /oauth/token : client_secret_basic, client_secret_post,
+ou aucun secret pour les clients publics (protégés par PKCE).
+*/
+func (s *Server) authenticateOAuthClient(r *http.Request) (*database.OAuthClient, error) {
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 §2.3.1 : identifiants encodés en application/x-www-form-urlencoded
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client, err := s.db.GetOAuthClient(clientID)
+	if err == sql.ErrNoRows {
+		return nil, errInvalidOAuthClient
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if client.SecretHash.Valid {
+		hash := database.HashToken(secret)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash.String)) != 1 {
+			return nil, errInvalidOAuthClient
+		}
+	}
+	return client, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Redirige vers une redirect URI enregistrée en ajoutant params à sa query string.
+func redirectWithParams(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid redirect_uri")
+		return
+	}
+	q := u.Query()
+	for key, values := range params {
+		q[key] = values
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func redirectWithOAuthError(w http.ResponseWriter, r *http.Request, redirectURI, state, code, description string) {
+	params := url.Values{"error": {code}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	redirectWithParams(w, r, redirectURI, params)
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code int, errorCode, description string) {
+	payload := map[string]string{"error": errorCode}
+	if description != "" {
+		payload["error_description"] = description
+	}
+	respondWithJSON(w, code, payload)
+}
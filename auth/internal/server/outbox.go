@@ -0,0 +1,165 @@
+/*
+Ce fichier contient le worker de la file d’envoi des e-mails (table email_outbox)
+et son suivi par les administrateurs :
+
+GET  /api/admin/emails             : statut de livraison par message (?status=pending|sent|dead, ?limit, ?offset)
+POST /api/admin/emails/{id}/retry  : remet un message en dead-letter dans la file
+
+Le worker relève la file toutes les 5 secondes, ou immédiatement après une mise en file
+par cette instance. Après un échec, la tentative suivante suit un backoff exponentiel
+(mailer.Backoff) ; au-delà de EMAIL_MAX_ATTEMPTS (8 par défaut) le message passe en dead-letter.
+*/
+
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth/internal/database"
+	"auth/internal/mailer"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	outboxPollInterval = 5 * time.Second
+	outboxBatchSize    = 10
+	outboxLease        = 2 * time.Minute // délai avant reprise d’un message réservé par un worker arrêté
+	outboxSendTimeout  = 30 * time.Second
+)
+
+// Boucle du worker, lancée une fois au démarrage du serveur.
+func (s *Server) runEmailOutbox() {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drainEmailOutbox()
+		select {
+		case <-ticker.C:
+		case <-s.outboxWake:
+		}
+	}
+}
+
+// Réveille le worker après une mise en file (sans bloquer si un réveil est déjà en attente).
+func (s *Server) wakeEmailOutbox() {
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) drainEmailOutbox() {
+	for {
+		emails, err := s.db.ClaimOutboxEmails(outboxBatchSize, outboxLease)
+		if err != nil {
+			log.Printf("drainEmailOutbox error: %v", err)
+			return
+		}
+		for _, email := range emails {
+			s.deliverEmail(email)
+		}
+		if len(emails) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (s *Server) deliverEmail(email database.QueuedEmail) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+	defer cancel()
+
+	sendErr := s.sender.Send(ctx, email.Message)
+	if sendErr == nil {
+		if err := s.db.MarkOutboxEmailSent(email.ID); err != nil {
+			log.Printf("deliverEmail error: %v", err)
+		}
+		log.Printf("Email %d (%s) sent to %v", email.ID, email.Message.Template, email.Message.To)
+		return
+	}
+
+	dead := email.Attempts >= s.config.Email.MaxAttempts
+	nextAttemptAt := time.Now().UTC().Add(mailer.Backoff(email.Attempts))
+	if err := s.db.MarkOutboxEmailFailed(email.ID, sendErr.Error(), nextAttemptAt, dead); err != nil {
+		log.Printf("deliverEmail error: %v", err)
+	}
+	if dead {
+		log.Printf("Email %d (%s) moved to dead-letter after %d attempts: %v", email.ID, email.Message.Template, email.Attempts, sendErr)
+	} else {
+		log.Printf("Email %d (%s) attempt %d failed, retrying at %s: %v", email.ID, email.Message.Template, email.Attempts, nextAttemptAt.Format(time.RFC3339), sendErr)
+	}
+}
+
+// Met un e-mail en file hors transaction métier et réveille le worker.
+func (s *Server) enqueueEmail(msg mailer.Message) error {
+	if err := s.db.EnqueueEmail(msg); err != nil {
+		return err
+	}
+	s.wakeEmailOutbox()
+	return nil
+}
+
+func (s *Server) listOutboxEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != database.OutboxPending && status != database.OutboxSent && status != database.OutboxDead {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	emails, err := s.db.ListOutboxEmails(status, limit, offset)
+	if err != nil {
+		log.Printf("listOutboxEmailsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list emails")
+		return
+	}
+
+	counts, err := s.db.CountOutboxEmails()
+	if err != nil {
+		log.Printf("listOutboxEmailsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list emails")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"counts": counts,
+		"emails": emails,
+	})
+}
+
+func (s *Server) retryOutboxEmailHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid email ID")
+		return
+	}
+
+	retried, err := s.db.RetryOutboxEmail(id)
+	if err != nil {
+		log.Printf("retryOutboxEmailHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to retry email")
+		return
+	}
+	if !retried {
+		respondWithError(w, http.StatusNotFound, "No dead-letter email with this ID")
+		return
+	}
+
+	s.wakeEmailOutbox()
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Email queued for delivery",
+	})
+}
@@ -0,0 +1,139 @@
+/*
+Ce fichier contient le flux « mot de passe oublié » pour les comptes email/mot de passe :
+
+POST /auth/forgot-password : envoie un lien contenant un jeton à usage unique.
+POST /auth/reset-password  : consomme le jeton, change le mot de passe et ferme toutes les sessions.
+*/
+
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"auth/internal/database"
+)
+
+// Durée de validité d’un lien de réinitialisation
+const passwordResetTTL = time.Hour
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+/*
+Demande de réinitialisation.
+La réponse est toujours la même, que l’email existe ou non,
+pour ne pas permettre de deviner quels comptes sont inscrits.
+Les comptes Google sans mot de passe sont ignorés.
+*/
+func (s *Server) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Email == "" {
+		respondWithError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	genericResponse := map[string]string{
+		"message": "If an account exists for this email, a password reset link has been sent.",
+	}
+
+	user, err := s.db.FindUserByEmail(req.Email)
+	if err != nil || !user.Password.Valid {
+		respondWithJSON(w, http.StatusOK, genericResponse)
+		return
+	}
+
+	token := generateSessionToken()
+	resetEmail, err := s.email.PasswordResetEmail(user.Email, emailLocale(r, user.Locale), s.passwordResetURL(token))
+	if err != nil {
+		log.Println("Failed to build password reset email:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process request")
+		return
+	}
+
+	err = s.db.SavePasswordResetToken(int(user.ID), database.HashToken(token), time.Now().Add(passwordResetTTL), resetEmail)
+	if err != nil {
+		log.Println("Failed to save password reset token:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process request")
+		return
+	}
+	s.wakeEmailOutbox()
+	s.recordUserEvent(r, user.ID, database.EventPasswordResetRequest, database.OutcomeSuccess, nil)
+
+	respondWithJSON(w, http.StatusOK, genericResponse)
+}
+
+// Réinitialise le mot de passe puis supprime toutes les sessions existantes de l’utilisateur.
+func (s *Server) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Token and password are required")
+		return
+	}
+
+	if len(req.Password) < 6 {
+		respondWithError(w, http.StatusBadRequest, "Password must be at least 6 characters")
+		return
+	}
+
+	userID, err := s.db.ConsumePasswordResetToken(database.HashToken(req.Token))
+	if err == sql.ErrNoRows {
+		s.recordEvent(r, database.AuthEvent{
+			Event:    database.EventPasswordReset,
+			Outcome:  database.OutcomeFailure,
+			Metadata: map[string]interface{}{"reason": "invalid_token"},
+		})
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		log.Println("Failed to consume password reset token:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	err = s.db.UpdateUserPassword(userID, req.Password)
+	if err != nil {
+		log.Println("Failed to update password:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	err = s.db.DeleteUserSessions(userID)
+	if err != nil {
+		log.Println("Failed to delete sessions after password reset:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	s.recordUserEvent(r, int64(userID), database.EventPasswordReset, database.OutcomeSuccess, nil)
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Password reset successfully. You can now log in.",
+	})
+}
+
+// Construit le lien envoyé par e-mail vers la page de réinitialisation du frontend.
+func (s *Server) passwordResetURL(token string) string {
+	return s.config.Server.FrontendURL + "/reset-password?token=" + url.QueryEscape(token)
+}
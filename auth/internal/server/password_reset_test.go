@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"auth/internal/database"
+	"auth/internal/mailer"
+)
+
+var resetTokenPattern = regexp.MustCompile(`token=([0-9a-f]{64})`)
+
+// Demande un lien de réinitialisation et retourne le jeton reçu par email.
+func (api *testAPI) requestPasswordReset(email string) string {
+	api.t.Helper()
+
+	api.expect("POST", "/auth/forgot-password", "", ForgotPasswordRequest{Email: email}, http.StatusOK)
+	msg, ok := api.lastEmail(email, mailer.TemplatePasswordReset)
+	match := resetTokenPattern.FindStringSubmatch(msg.Text)
+	if !ok || match == nil {
+		api.t.Fatalf("no password reset link sent to %s", email)
+	}
+	return match[1]
+}
+
+func TestPasswordResetInvalidatesSessions(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "erin@example.com"
+	token := api.signUp(email, "password1")
+	other := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)["token"].(string)
+
+	reset := api.requestPasswordReset(email)
+	api.expect("POST", "/auth/reset-password", "", ResetPasswordRequest{Token: reset, Password: "password2"}, http.StatusOK)
+
+	api.expect("GET", "/api/me", token, nil, http.StatusUnauthorized)
+	api.expect("GET", "/api/me", other, nil, http.StatusUnauthorized)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusUnauthorized)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password2"}, http.StatusOK)
+}
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "frank@example.com"
+	api.signUp(email, "password1")
+
+	reset := api.requestPasswordReset(email)
+	api.expect("POST", "/auth/reset-password", "", ResetPasswordRequest{Token: reset, Password: "password2"}, http.StatusOK)
+	api.expect("POST", "/auth/reset-password", "", ResetPasswordRequest{Token: reset, Password: "password3"}, http.StatusBadRequest)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password2"}, http.StatusOK)
+
+	// Un nouveau lien invalide le précédent
+	first := api.requestPasswordReset(email)
+	second := api.requestPasswordReset(email)
+	api.expect("POST", "/auth/reset-password", "", ResetPasswordRequest{Token: first, Password: "password4"}, http.StatusBadRequest)
+	api.expect("POST", "/auth/reset-password", "", ResetPasswordRequest{Token: second, Password: "password4"}, http.StatusOK)
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "grace@example.com"
+	api.signUp(email, "password1")
+
+	user, err := api.store.FindUserByEmail(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expired = "expired-reset-token"
+	if err := api.store.SavePasswordResetToken(int(user.ID), database.HashToken(expired), time.Now().Add(-time.Minute), mailer.Message{}); err != nil {
+		t.Fatal(err)
+	}
+
+	api.expect("POST", "/auth/reset-password", "", ResetPasswordRequest{Token: expired, Password: "password2"}, http.StatusBadRequest)
+	api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)
+}
+
+func TestForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	api := newTestAPI(t)
+
+	api.signUp("heidi@example.com", "password1")
+	unknown := api.expect("POST", "/auth/forgot-password", "", ForgotPasswordRequest{Email: "nobody@example.com"}, http.StatusOK)
+	existing := api.expect("POST", "/auth/forgot-password", "", ForgotPasswordRequest{Email: "heidi@example.com"}, http.StatusOK)
+	if unknown["message"] != existing["message"] {
+		t.Errorf("responses should not depend on the account, got %q and %q", unknown["message"], existing["message"])
+	}
+	if _, ok := api.lastEmail("nobody@example.com", mailer.TemplatePasswordReset); ok {
+		t.Error("no email should be sent to an unknown address")
+	}
+}
@@ -0,0 +1,352 @@
+/*
+Ce fichier contient les signalements de contrats / utilisateurs et leur modération :
+
+POST  /api/report                        : dépôt d’un signalement (409 si la cible est déjà signalée par l’utilisateur)
+GET   /api/reports/mine                  : signalements de l’utilisateur connecté et leur statut
+
+GET   /api/admin/reports                 : liste de modération (?status, ?type, ?assignee=<id>|none, ?limit, ?offset)
+GET   /api/admin/reports/{id}            : détail avec les commentaires internes
+PATCH /api/admin/reports/{id}            : {"status": "...", "assignee_id": n} (assignee_id 0 : désassigner ;
+                                           l’assigné doit avoir la permission reports:moderate)
+POST  /api/admin/reports/{id}/comments   : {"body": "..."}
+*/
+
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxReportTargetLength      = 255
+	maxReportDescriptionLength = 5000
+	maxReportCommentLength     = 5000
+)
+
+type ReportRequest struct {
+	Type        string `json:"type"`   // "contract" or "user"
+	Target      string `json:"target"` // Contract ID or User Name/ID
+	Description string `json:"description"`
+}
+
+func isOneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Forme canonique d’une cible, pour que le même contrat ou le même compte ne puisse pas être signalé
+deux fois sous deux écritures : espaces réduits, adresses Ethereum et emails en minuscules
+(comparés sans tenir compte de la casse, comme les adresses des wallets et les emails des comptes).
+*/
+func normalizeReportTarget(target string) string {
+	target = strings.Join(strings.Fields(target), " ")
+	if lower := strings.ToLower(target); auth.IsEthereumAddress(lower) || strings.Contains(lower, "@") {
+		return lower
+	}
+	return target
+}
+
+func (s *Server) reportHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "You must be logged in to report")
+		return
+	}
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Target = normalizeReportTarget(req.Target)
+	req.Description = strings.TrimSpace(req.Description)
+
+	if req.Type == "" || req.Target == "" || req.Description == "" {
+		respondWithError(w, http.StatusBadRequest, "All fields are required")
+		return
+	}
+	if !isOneOf(req.Type, database.ReportTypes) {
+		respondWithError(w, http.StatusBadRequest, "Invalid report type")
+		return
+	}
+	if utf8.RuneCountInString(req.Target) > maxReportTargetLength || utf8.RuneCountInString(req.Description) > maxReportDescriptionLength {
+		respondWithError(w, http.StatusBadRequest, "Report is too long")
+		return
+	}
+
+	reportEmail, err := s.email.ReportEmail(req.Type, req.Target, req.Description, user.Email)
+	if err != nil {
+		log.Printf("reportHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to submit report")
+		return
+	}
+
+	id, err := s.db.CreateReport(int(user.ID), req.Type, req.Target, req.Description, reportEmail)
+	if errors.Is(err, database.ErrDuplicateReport) {
+		s.recordUserEvent(r, user.ID, database.EventReportSubmit, database.OutcomeFailure,
+			map[string]interface{}{"type": req.Type, "report_id": id, "reason": "duplicate"})
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":     "You have already reported this target",
+			"report_id": id,
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("reportHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to submit report")
+		return
+	}
+	s.wakeEmailOutbox()
+	s.recordUserEvent(r, user.ID, database.EventReportSubmit, database.OutcomeSuccess, map[string]interface{}{"type": req.Type, "report_id": id})
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"message":   "Report submitted successfully",
+		"report_id": id,
+	})
+}
+
+// Les signalements du demandeur, sans les commentaires internes ni l’assignation.
+func (s *Server) listMyReportsHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	reports, err := s.db.ListReportsByReporter(int(user.ID))
+	if err != nil {
+		log.Printf("listMyReportsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list reports")
+		return
+	}
+
+	type myReport struct {
+		ID          int64      `json:"id"`
+		Type        string     `json:"type"`
+		Target      string     `json:"target"`
+		Description string     `json:"description"`
+		Status      string     `json:"status"`
+		CreatedAt   time.Time  `json:"created_at"`
+		UpdatedAt   time.Time  `json:"updated_at"`
+		ResolvedAt  *time.Time `json:"resolved_at"`
+	}
+	result := make([]myReport, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, myReport{
+			ID:          report.ID,
+			Type:        report.Type,
+			Target:      report.Target,
+			Description: report.Description,
+			Status:      report.Status,
+			CreatedAt:   report.CreatedAt,
+			UpdatedAt:   report.UpdatedAt,
+			ResolvedAt:  report.ResolvedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"reports": result})
+}
+
+func (s *Server) listReportsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := database.ReportFilter{
+		Status: query.Get("status"),
+		Type:   query.Get("type"),
+	}
+	if filter.Status != "" && !isOneOf(filter.Status, database.ReportStatuses) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+	if filter.Type != "" && !isOneOf(filter.Type, database.ReportTypes) {
+		respondWithError(w, http.StatusBadRequest, "Invalid report type")
+		return
+	}
+	switch assignee := query.Get("assignee"); assignee {
+	case "":
+	case "none":
+		filter.AssigneeID = -1
+	default:
+		id, err := strconv.ParseInt(assignee, 10, 64)
+		if err != nil || id <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid assignee")
+			return
+		}
+		filter.AssigneeID = id
+	}
+
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	reports, err := s.db.ListReports(filter)
+	if err != nil {
+		log.Printf("listReportsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list reports")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"reports": reports})
+}
+
+// Lit le signalement {id} ; répond 400/404/500 et retourne nil en cas d’échec.
+func (s *Server) reportFromURL(w http.ResponseWriter, r *http.Request, handler string) *database.Report {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID")
+		return nil
+	}
+
+	report, err := s.db.GetReport(id)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Report not found")
+		return nil
+	}
+	if err != nil {
+		log.Printf("%s error: %v", handler, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load report")
+		return nil
+	}
+	return report
+}
+
+func (s *Server) getReportHandler(w http.ResponseWriter, r *http.Request) {
+	report := s.reportFromURL(w, r, "getReportHandler")
+	if report == nil {
+		return
+	}
+
+	comments, err := s.db.ListReportComments(report.ID)
+	if err != nil {
+		log.Printf("getReportHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load report")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"report":   report,
+		"comments": comments,
+	})
+}
+
+func (s *Server) updateReportHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status     *string `json:"status"`
+		AssigneeID *int64  `json:"assignee_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Status == nil && req.AssigneeID == nil {
+		respondWithError(w, http.StatusBadRequest, "Nothing to update")
+		return
+	}
+	if req.Status != nil && !isOneOf(*req.Status, database.ReportStatuses) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	report := s.reportFromURL(w, r, "updateReportHandler")
+	if report == nil {
+		return
+	}
+
+	if req.AssigneeID != nil {
+		var assignee *int64
+		if *req.AssigneeID != 0 {
+			if _, err := s.db.GetUserByID(int(*req.AssigneeID)); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Unknown assignee")
+				return
+			}
+			// Seul un compte qui peut modérer les signalements peut en être chargé
+			allowed, err := s.db.UserHasPermission(int(*req.AssigneeID), database.PermissionReportsModerate)
+			if err != nil {
+				log.Printf("updateReportHandler error: %v", err)
+				respondWithError(w, http.StatusInternalServerError, "Failed to update report")
+				return
+			}
+			if !allowed {
+				respondWithError(w, http.StatusBadRequest, "Assignee cannot moderate reports")
+				return
+			}
+			assignee = req.AssigneeID
+		}
+		if err := s.db.AssignReport(report.ID, assignee); err != nil {
+			log.Printf("updateReportHandler error: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to update report")
+			return
+		}
+	}
+	if req.Status != nil {
+		if err := s.db.UpdateReportStatus(report.ID, *req.Status); err != nil {
+			log.Printf("updateReportHandler error: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to update report")
+			return
+		}
+	}
+
+	updated, err := s.db.GetReport(report.ID)
+	if err != nil {
+		log.Printf("updateReportHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update report")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"report": updated})
+}
+
+func (s *Server) addReportCommentHandler(w http.ResponseWriter, r *http.Request) {
+	admin := sessionUser(r)
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" || utf8.RuneCountInString(req.Body) > maxReportCommentLength {
+		respondWithError(w, http.StatusBadRequest, "Comment must be between 1 and 5000 characters")
+		return
+	}
+
+	report := s.reportFromURL(w, r, "addReportCommentHandler")
+	if report == nil {
+		return
+	}
+
+	id, err := s.db.AddReportComment(report.ID, int(admin.ID), req.Body)
+	if err != nil {
+		log.Printf("addReportCommentHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to add comment")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"message":    "Comment added",
+		"comment_id": id,
+	})
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"auth/internal/database"
+)
+
+func TestReportTargetIsNormalizedBeforeTheDuplicateCheck(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("paul@example.com", "password1")
+
+	const contract = "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"
+	created := api.expect("POST", "/api/report", token, ReportRequest{Type: "contract", Target: contract, Description: "Drains approvals"}, http.StatusCreated)
+	duplicate := api.expect("POST", "/api/report", token, ReportRequest{Type: "contract", Target: "  0x7e5f4552091a69125d5dfcb7b8c2659029395bdf ", Description: "Same contract"}, http.StatusConflict)
+	if duplicate["report_id"] != created["report_id"] {
+		t.Fatalf("expected the existing report %v, got %v", created["report_id"], duplicate["report_id"])
+	}
+
+	api.expect("POST", "/api/report", token, ReportRequest{Type: "user", Target: "Spammer@Example.com", Description: "Spam"}, http.StatusCreated)
+	api.expect("POST", "/api/report", token, ReportRequest{Type: "user", Target: "spammer@example.com", Description: "Spam"}, http.StatusConflict)
+
+	reports, err := api.store.ListReportsByReporter(api.userID("paul@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range reports {
+		if report.Type == "contract" && report.Target != "0x7e5f4552091a69125d5dfcb7b8c2659029395bdf" {
+			t.Fatalf("expected the contract address in lowercase, got %q", report.Target)
+		}
+	}
+}
+
+func TestReportAssigneeMustModerateReports(t *testing.T) {
+	api := newTestAPI(t)
+	moderatorToken := api.signUpWithRole("quinn@example.com", database.RoleModerator)
+	reporterToken := api.signUp("rose@example.com", "password1")
+
+	created := api.expect("POST", "/api/report", reporterToken, ReportRequest{Type: "user", Target: "42", Description: "Harassment"}, http.StatusCreated)
+	path := fmt.Sprintf("/api/admin/reports/%d", int(created["report_id"].(float64)))
+
+	// Un compte sans reports:moderate ne peut pas être chargé du signalement
+	reporterID := int64(api.userID("rose@example.com"))
+	api.expect("PATCH", path, moderatorToken, map[string]int64{"assignee_id": reporterID}, http.StatusBadRequest)
+
+	moderatorID := int64(api.userID("quinn@example.com"))
+	updated := api.expect("PATCH", path, moderatorToken, map[string]int64{"assignee_id": moderatorID}, http.StatusOK)
+	report := updated["report"].(map[string]interface{})
+	if report["assignee_id"] != float64(moderatorID) {
+		t.Fatalf("expected the report to be assigned to the moderator, got %v", report)
+	}
+}
@@ -13,15 +13,18 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"auth/internal/auth"
 	"auth/internal/database"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
 )
 
@@ -29,28 +32,28 @@ import (
 Elle crée et configure un routeur Chi (github.com/go-chi/chi)
 
 pour définir toutes les routes et middlewares de sécurité
-Le frontend appelle /auth/google.
+Le frontend appelle /auth/{provider} (google, github, microsoft, gitlab ou un fournisseur OIDC configuré).
 
-Le backend redirige vers Google pour la connexion.
+Le backend redirige vers le fournisseur pour la connexion.
 
-Google renvoie un token + infos utilisateur à /auth/google/callback.
+Le fournisseur renvoie un token + infos utilisateur à /auth/{provider}/callback.
 
 Le serveur :
 
-Récupère les infos (email, name, googleID).
+Récupère les infos (email, name, identifiant chez le fournisseur).
 
 Crée ou retrouve l’utilisateur dans la base.
 
 Crée une session locale (token).
 
-Redirige vers le gateway :
-http://localhost:8000/auth/callback?token=xxxxx
+Redirige vers le gateway (GATEWAY_URL) :
+<GATEWAY_URL>/auth/callback?token=xxxxx
 */
 func (s *Server) RegisterRoutes() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:8000"}, // autoriser le gateway
+		AllowedOrigins:   []string{s.config.Server.GatewayURL}, // autoriser le gateway
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Cookie"},
 		AllowCredentials: true,
@@ -60,25 +63,56 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Get("/", s.HelloWorldHandler)
 	r.Get("/health", s.healthHandler)
 
-	// --- GOOGLE AUTH ROUTES ---
+	// --- OAUTH PROVIDER ROUTES ---
+	r.Get("/auth/providers", s.listProvidersHandler)
 	r.Get("/auth/{provider}", func(w http.ResponseWriter, r *http.Request) {
 		provider := chi.URLParam(r, "provider")
+		if _, err := goth.GetProvider(provider); err != nil {
+			respondWithError(w, http.StatusNotFound, "Unknown OAuth provider")
+			return
+		}
 
 		//Enlever les sessions gothic existantes pour une flux fraiche de OAuth
 		session, _ := gothic.Store.Get(r, gothic.SessionName)
 		session.Options.MaxAge = -1
 		session.Save(r, w)
 
-		// Verifier si les requests viennt des gateway
-		if r.Header.Get("X-Forwarded-Host") != "" {
-			r.URL.Scheme = "http"
-			r.URL.Host = r.Header.Get("X-Forwarded-Host")
-		} else {
-			// url de gateway
-			r.URL.Scheme = "http"
-			r.URL.Host = "localhost:8000"
+		// url de gateway, ou l’hôte transmis par le gateway
+		gateway, _ := url.Parse(s.config.Server.GatewayURL)
+		r.URL.Scheme = gateway.Scheme
+		r.URL.Host = gateway.Host
+		if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+			r.URL.Host = forwardedHost
+		}
+
+		// ?link=true : relier le fournisseur au compte connecté au lieu de se connecter
+		if r.URL.Query().Get("link") == "true" {
+			if _, _, err := s.currentSession(r); err != nil {
+				respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			http.SetCookie(w, &http.Cookie{
+				Name:     linkIdentityCookie,
+				Value:    provider,
+				Path:     "/",
+				MaxAge:   600,
+				HttpOnly: true,
+				Secure:   s.config.Cookies.Secure,
+				SameSite: s.config.Cookies.OAuthState.SameSiteMode(),
+			})
 		}
 
+		// Mémorise le choix "remember me" jusqu’au callback OAuth (mêmes attributs que le cookie d’état)
+		http.SetCookie(w, &http.Cookie{
+			Name:     rememberMeCookie,
+			Value:    strconv.FormatBool(r.URL.Query().Get("remember_me") == "true"),
+			Path:     "/",
+			MaxAge:   600,
+			HttpOnly: true,
+			Secure:   s.config.Cookies.Secure,
+			SameSite: s.config.Cookies.OAuthState.SameSiteMode(),
+		})
+
 		log.Printf("🔐 Starting OAuth for provider: %s", provider)
 		log.Printf("🔐 Request URL: %s://%s%s", r.URL.Scheme, r.URL.Host, r.URL.Path)
 		log.Printf("🔐 Request Host header: %s", r.Host)
@@ -95,18 +129,116 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Post("/auth/register", s.registerHandler)
 	r.Post("/auth/verify", s.verifyEmailHandler)
 	r.Post("/auth/login", s.loginHandler)
+	r.Post("/auth/login/2fa", s.loginTwoFactorHandler)
 	r.Post("/auth/resend-code", s.resendCodeHandler)
+	r.Post("/auth/forgot-password", s.forgotPasswordHandler)
+	r.Post("/auth/reset-password", s.resetPasswordHandler)
+
+	// --- PASSKEY (WEBAUTHN) ROUTES ---
+	r.Post("/auth/webauthn/register/begin", s.webAuthnRegisterBeginHandler)
+	r.Post("/auth/webauthn/register/finish", s.webAuthnRegisterFinishHandler)
+	r.Post("/auth/webauthn/login/begin", s.webAuthnLoginBeginHandler)
+	r.Post("/auth/webauthn/login/finish", s.webAuthnLoginFinishHandler)
+
+	// --- SIGN-IN WITH ETHEREUM ---
+	r.Get("/auth/siwe/nonce", s.siweNonceHandler)
+	r.Post("/auth/siwe/verify", s.siweVerifyHandler)
+
+	// --- SIGNED ACCESS TOKENS (JWT) ---
+	r.Get("/.well-known/jwks.json", s.jwksHandler)
+	r.Post("/auth/token", s.accessTokenHandler)
+
+	// --- OPENID CONNECT PROVIDER ---
+	r.Get("/.well-known/openid-configuration", s.openIDConfigurationHandler)
+	r.Get("/oauth/authorize", s.oauthAuthorizeHandler)
+	r.Post("/oauth/authorize", s.oauthConsentHandler)
+	r.Post("/oauth/token", s.oauthTokenHandler)
+	r.Get("/oauth/userinfo", s.oauthUserInfoHandler)
+	r.Post("/oauth/userinfo", s.oauthUserInfoHandler)
+	r.Get("/api/oauth/consents", s.listOAuthConsentsHandler)
+	r.Delete("/api/oauth/consents/{clientID}", s.revokeOAuthConsentHandler)
 
 	// --- COMMON ROUTES ---
 	r.Post("/auth/logout", s.logoutHandler)
 	r.Get("/api/me", s.getCurrentUser)
 	r.Get("/api/users/search", s.searchUsersHandler)
 	r.Get("/api/users/{id}", s.getUserByIdHandler)
+
+	// --- DEVICE / SESSION MANAGEMENT ---
+	r.Get("/api/sessions", s.listSessionsHandler)
+	r.Delete("/api/sessions", s.revokeOtherSessionsHandler)
+	r.Delete("/api/sessions/{id}", s.revokeSessionHandler)
+
+	// --- TWO-FACTOR AUTHENTICATION (TOTP) ---
+	r.Get("/api/2fa", s.twoFactorStatusHandler)
+	r.Post("/api/2fa/totp/setup", s.totpSetupHandler)
+	r.Post("/api/2fa/totp/confirm", s.totpConfirmHandler)
+	r.Post("/api/2fa/totp/disable", s.totpDisableHandler)
+	r.Post("/api/2fa/recovery-codes", s.regenerateRecoveryCodesHandler)
+
+	// --- PASSKEY MANAGEMENT ---
+	r.Get("/api/webauthn/credentials", s.listWebAuthnCredentialsHandler)
+	r.Delete("/api/webauthn/credentials/{id}", s.deleteWebAuthnCredentialHandler)
+
+	// --- VERIFIED WALLETS ---
+	r.Get("/api/wallets", s.listWalletsHandler)
+	r.Delete("/api/wallets/{address}", s.unlinkWalletHandler)
+
+	// --- LINKED IDENTITIES / PASSWORD ---
+	r.Get("/api/identities", s.listIdentitiesHandler)
+	r.Delete("/api/identities/{id}", s.unlinkIdentityHandler)
+	r.Post("/api/me/password", s.setPasswordHandler)
+
+	r.Put("/api/me/locale", s.setLocaleHandler)
+	r.Get("/api/me/activity", s.myActivityHandler)
+
+	// --- ABUSE REPORTS ---
 	r.Post("/api/report", s.reportHandler)
+	r.Get("/api/reports/mine", s.listMyReportsHandler)
+
+	// --- DEV MAILBOX (EMAIL_BACKEND=file) / EMAIL TEMPLATE PREVIEW ---
+	s.registerDevMailboxRoutes(r)
+	s.registerEmailPreviewRoutes(r)
+
+	// --- ADMIN: ROLES ---
+	r.With(s.requirePermission(database.PermissionRolesManage)).Get("/api/admin/roles", s.listRolesHandler)
+	r.With(s.requirePermission(database.PermissionRolesManage)).Post("/api/admin/users/{id}/roles", s.grantRoleHandler)
+	r.With(s.requirePermission(database.PermissionRolesManage)).Delete("/api/admin/users/{id}/roles/{role}", s.revokeRoleHandler)
+
+	// --- ADMIN: USER MANAGEMENT ---
+	r.With(s.requirePermission(database.PermissionUsersRead)).Get("/api/admin/users", s.listUsersHandler)
+	r.With(s.requirePermission(database.PermissionUsersRead)).Get("/api/admin/users/{id}", s.getAdminUserHandler)
+	r.With(s.requirePermission(database.PermissionUsersManage)).Post("/api/admin/users/{id}/suspend", s.suspendUserHandler)
+	r.With(s.requirePermission(database.PermissionUsersManage)).Post("/api/admin/users/{id}/ban", s.banUserHandler)
+	r.With(s.requirePermission(database.PermissionUsersManage)).Post("/api/admin/users/{id}/reinstate", s.reinstateUserHandler)
+	r.With(s.requirePermission(database.PermissionUsersManage)).Post("/api/admin/users/{id}/logout", s.forceLogoutUserHandler)
+	r.With(s.requirePermission(database.PermissionUsersManage)).Post("/api/admin/users/{id}/verify-email", s.verifyUserEmailHandler)
+
+	// --- ADMIN: AUDIT LOG ---
+	r.With(s.requirePermission(database.PermissionAuditRead)).Get("/api/admin/auth-events", s.listAuthEventsHandler)
+
+	// --- ADMIN: EMAIL DELIVERY ---
+	r.With(s.requirePermission(database.PermissionEmailsManage)).Get("/api/admin/emails", s.listOutboxEmailsHandler)
+	r.With(s.requirePermission(database.PermissionEmailsManage)).Post("/api/admin/emails/{id}/retry", s.retryOutboxEmailHandler)
+
+	// --- ADMIN: REPORT MODERATION ---
+	r.With(s.requirePermission(database.PermissionReportsRead)).Get("/api/admin/reports", s.listReportsHandler)
+	r.With(s.requirePermission(database.PermissionReportsRead)).Get("/api/admin/reports/{id}", s.getReportHandler)
+	r.With(s.requirePermission(database.PermissionReportsModerate)).Patch("/api/admin/reports/{id}", s.updateReportHandler)
+	r.With(s.requirePermission(database.PermissionReportsModerate)).Post("/api/admin/reports/{id}/comments", s.addReportCommentHandler)
 
 	return r
 }
 
+// Liste des fournisseurs OAuth activés, pour afficher les boutons de connexion
+func (s *Server) listProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	providers := auth.Providers()
+	if providers == nil {
+		providers = []auth.Provider{}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"providers": providers})
+}
+
 func (s *Server) HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
 	resp := map[string]string{"message": "Hello World"}
 	jsonResp, err := json.Marshal(resp)
@@ -133,19 +265,41 @@ func (s *Server) getAuthCallBackFunction(w http.ResponseWriter, r *http.Request)
 
 	user, err := gothic.CompleteUserAuth(w, r)
 	if err != nil {
+		s.recordEvent(r, database.AuthEvent{
+			Event:    database.EventLogin,
+			Outcome:  database.OutcomeFailure,
+			Metadata: map[string]interface{}{"method": "oauth", "provider": provider, "reason": "provider_error"},
+		})
 		fmt.Fprintln(w, "Auth error:", err)
 		return
 	}
 
-	fmt.Println("Google User Info:")
-	fmt.Println("Name:", user.Name)
-	fmt.Println("Email:", user.Email)
-	fmt.Println("GoogleID:", user.UserID)
+	if cookie, err := r.Cookie(linkIdentityCookie); err == nil {
+		http.SetCookie(w, &http.Cookie{Name: linkIdentityCookie, Value: "", Path: "/", MaxAge: -1})
+		if cookie.Value == provider {
+			s.linkIdentityCallback(w, r, provider, user)
+			return
+		}
+	}
+
+	log.Printf("OAuth login via %s for provider user %s", provider, user.UserID)
 
-	userID, err := s.db.FindUserByGoogleID(user.UserID)
+	userID, err := s.db.FindUserByIdentity(provider, user.UserID)
 	if err == sql.ErrNoRows {
-		userID, err = s.db.CreateUser(user.UserID, user.Email, user.Name, user.AvatarURL)
+		userID, err = s.resolveProviderUser(provider, user)
+		if err == errAccountExists {
+			s.recordEmailEvent(r, user.Email, database.EventLogin, database.OutcomeFailure,
+				map[string]interface{}{"method": "oauth", "provider": provider, "reason": "account_exists"})
+			// Adresse déjà utilisée par un compte et non garantie par le fournisseur : pas de fusion
+			http.Redirect(w, r, s.config.Server.FrontendURL+"/login?error=account_exists&provider="+url.QueryEscape(provider), http.StatusFound)
+			return
+		}
+		if err == errMissingEmail {
+			http.Error(w, "The provider did not return an email address", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
+			log.Printf("getAuthCallBackFunction error: %v", err)
 			http.Error(w, "Failed to create user in DB", http.StatusInternalServerError)
 			return
 		}
@@ -154,20 +308,58 @@ func (s *Server) getAuthCallBackFunction(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	sessionToken := generateSessionToken()
-	err = s.db.DeleteUserSessions(userID)
+	account, err := s.db.GetUserByID(userID)
 	if err != nil {
-		log.Println("Warning: Failed to delete old sessions:", err)
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if account.IsSuspended(time.Now()) {
+		s.recordUserEvent(r, account.ID, database.EventLogin, database.OutcomeFailure,
+			map[string]interface{}{"method": "oauth", "provider": provider, "reason": "suspended"})
+		http.Redirect(w, r, s.config.Server.FrontendURL+"/login?error=account_suspended", http.StatusFound)
+		return
 	}
 
-	expiresAt := "2030-01-01 00:00:00" //faut changer dans la production
-	err = s.db.CreateSession(userID, sessionToken, expiresAt)
+	rememberMe := false
+	if cookie, err := r.Cookie(rememberMeCookie); err == nil {
+		rememberMe = cookie.Value == "true"
+	}
+	http.SetCookie(w, &http.Cookie{Name: rememberMeCookie, Value: "", Path: "/", MaxAge: -1})
+
+	twoFactor, err := s.db.HasTOTPEnabled(userID)
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if twoFactor {
+		challengeToken, err := s.createLoginChallenge(userID, rememberMe)
+		if err != nil {
+			http.Error(w, "Failed to create login challenge", http.StatusInternalServerError)
+			return
+		}
+		// Le gateway redirige vers le frontend qui demande le code TOTP
+		http.Redirect(w, r, s.config.Server.GatewayURL+"/auth/callback?challenge_token="+challengeToken, http.StatusFound)
+		return
+	}
+
+	s.notifyIfNewDevice(userID, r)
+	s.bootstrapAdmin()
+
+	sessionToken, err := auth.NewSessionToken()
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	expiresAt, err := s.db.CreateSession(userID, sessionToken, rememberMe, r.UserAgent(), s.clientIP(r))
 	if err != nil {
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
+	s.recordUserEvent(r, account.ID, database.EventLogin, database.OutcomeSuccess,
+		map[string]interface{}{"method": "oauth", "provider": provider})
 
-	gatewayURL := fmt.Sprintf("http://localhost:8000/auth/callback?token=%s", sessionToken)
+	// expires permet au gateway d’aligner l’expiration du cookie sur celle de la session
+	gatewayURL := fmt.Sprintf("%s/auth/callback?token=%s&expires=%d", s.config.Server.GatewayURL, sessionToken, expiresAt.Unix())
 	http.Redirect(w, r, gatewayURL, http.StatusFound)
 }
 
@@ -185,8 +377,9 @@ type VerifyRequest struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	RememberMe bool   `json:"remember_me"`
 }
 
 type ResendCodeRequest struct {
@@ -213,34 +406,44 @@ func (s *Server) registerHandler(w http.ResponseWriter, r *http.Request) {
 
 	existingUser, _ := s.db.FindUserByEmail(req.Email)
 	if existingUser != nil {
+		s.recordEvent(r, database.AuthEvent{
+			UserID:   &existingUser.ID,
+			Email:    req.Email,
+			Event:    database.EventRegister,
+			Outcome:  database.OutcomeFailure,
+			Metadata: map[string]interface{}{"reason": "email_exists"},
+		})
 		respondWithError(w, http.StatusConflict, "Email already registered")
 		return
 	}
 
-	userID, err := s.db.CreateEmailUser(req.Email, req.Password, req.Name)
+	// Langue des e-mails : celle du navigateur au moment de l’inscription
+	locale := emailLocale(r, "")
+
+	code, err := database.GenerateVerificationCode()
 	if err != nil {
-		log.Println("Failed to create user:", err)
+		log.Println(err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
-
-	code := database.GenerateVerificationCode()
 	expiresAt := time.Now().Add(10 * time.Minute)
 
-	err = s.db.SaveVerificationCode(req.Email, code, expiresAt)
+	verificationEmail, err := s.email.VerificationEmail(req.Email, locale, code)
 	if err != nil {
-		log.Println("Failed to save verification code:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to save verification code")
+		log.Println("Failed to build verification email:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
 
-	emailService := database.NewEmailService()
-	err = emailService.SendVerificationEmail(req.Email, code)
+	// Compte, code et e-mail de vérification sont enregistrés ensemble (ou pas du tout)
+	userID, err := s.db.CreateEmailUser(req.Email, req.Password, req.Name, locale, code, expiresAt, verificationEmail)
 	if err != nil {
-		log.Println("Failed to send verification email:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+		log.Println("Failed to create user:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
+	s.wakeEmailOutbox()
+	s.recordUserEvent(r, int64(userID), database.EventRegister, database.OutcomeSuccess, map[string]interface{}{"method": "password"})
 
 	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
 		"message": "Registration successful. Please check your email for verification code.",
@@ -265,6 +468,12 @@ func (s *Server) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	throttle := s.throttleCounters("verify", req.Email, r)
+	if !s.checkThrottle(w, throttle) {
+		s.recordEmailEvent(r, req.Email, database.EventEmailVerify, database.OutcomeFailure, map[string]interface{}{"reason": "throttled"})
+		return
+	}
+
 	valid, err := s.db.VerifyCode(req.Email, req.Code)
 	if err != nil {
 		log.Println("Error verifying code:", err)
@@ -273,6 +482,7 @@ func (s *Server) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !valid {
+		s.recordEmailEvent(r, req.Email, database.EventEmailVerify, database.OutcomeFailure, map[string]interface{}{"reason": "invalid_code"})
 		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification code")
 		return
 	}
@@ -285,6 +495,9 @@ func (s *Server) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.recordThrottleSuccess(throttle)
+	s.recordEmailEvent(r, req.Email, database.EventEmailVerify, database.OutcomeSuccess, nil)
+
 	respondWithJSON(w, http.StatusOK, map[string]string{
 		"message": "Email verified successfully! You can now log in.",
 	})
@@ -305,43 +518,99 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Protection contre la force brute : 429 tant qu’un délai ou un verrouillage est en cours
+	throttle := s.throttleCounters("login", req.Email, r)
+	if !s.checkThrottle(w, throttle) {
+		s.recordEmailEvent(r, req.Email, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "password", "reason": "throttled"})
+		return
+	}
+
 	user, err := s.db.FindUserByEmail(req.Email)
 	if err != nil {
 		log.Printf("FindUserByEmail error: %v", err) // Debug log
+		s.recordEvent(r, database.AuthEvent{
+			Email:    req.Email,
+			Event:    database.EventLogin,
+			Outcome:  database.OutcomeFailure,
+			Metadata: map[string]interface{}{"method": "password", "reason": "unknown_email"},
+		})
 		respondWithError(w, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
 	log.Printf("User found: ID=%d, Email=%s, IsVerified=%v", user.ID, user.Email, user.IsVerified) // Debug log
 
-	if user.GoogleID.Valid && user.GoogleID.String != "" {
-		respondWithError(w, http.StatusBadRequest, "This email is registered with Google. Please use Google sign-in.")
-		return
+	// Compte créé via un fournisseur OAuth, sans mot de passe
+	if !user.Password.Valid {
+		identities, err := s.db.ListUserIdentities(int(user.ID))
+		if err == nil && len(identities) > 0 {
+			s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "password", "reason": "no_password"})
+			name := auth.ProviderDisplayName(identities[0].Provider)
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("This email is registered with %s. Please use %s sign-in.", name, name))
+			return
+		}
 	}
 
 	if !user.Password.Valid || !s.db.VerifyPassword(user.Password.String, req.Password) {
 		log.Println("Password verification failed") // Debug log
+		s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "password", "reason": "invalid_password"})
 		respondWithError(w, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
 	log.Println("Password verified successfully") // Debug log
+	s.recordThrottleSuccess(throttle)
 
 	if !user.IsVerified {
 		log.Println("Email not verified") // Debug log
+		s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "password", "reason": "email_not_verified"})
 		respondWithError(w, http.StatusForbidden, "Please verify your email before logging in")
 		return
 	}
 
-	// Create session
-	sessionToken := generateSessionToken()
-	err = s.db.DeleteUserSessions(int(user.ID))
+	if user.IsSuspended(time.Now()) {
+		s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "password", "reason": "suspended"})
+		respondWithError(w, http.StatusForbidden, suspensionMessage(user))
+		return
+	}
+
+	// Deuxième facteur : pas de session tant que le code TOTP n’est pas vérifié
+	twoFactor, err := s.db.HasTOTPEnabled(int(user.ID))
 	if err != nil {
-		log.Println("Warning: Failed to delete old sessions:", err)
+		log.Println("Failed to check two-factor status:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
 	}
+	if twoFactor {
+		s.respondWithLoginChallenge(w, int(user.ID), req.RememberMe)
+		return
+	}
+
+	s.respondWithNewSession(w, r, user, "password", req.RememberMe)
+}
 
-	expiresAt := "2030-01-01 00:00:00"
-	err = s.db.CreateSession(int(user.ID), sessionToken, expiresAt)
+/*
+Crée une session (les sessions des autres appareils restent actives)
+et renvoie la réponse de connexion standard : le gateway en extrait le token pour poser le cookie.
+method (password, passkey, siwe, totp) est enregistré dans le journal d’audit.
+*/
+func (s *Server) respondWithNewSession(w http.ResponseWriter, r *http.Request, user *database.User, method string, rememberMe bool) {
+	if user.IsSuspended(time.Now()) {
+		s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": method, "reason": "suspended"})
+		respondWithError(w, http.StatusForbidden, suspensionMessage(user))
+		return
+	}
+
+	s.notifyIfNewDevice(int(user.ID), r)
+	s.bootstrapAdmin()
+
+	sessionToken, err := auth.NewSessionToken()
+	if err != nil {
+		log.Println("Failed to create session:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	expiresAt, err := s.db.CreateSession(int(user.ID), sessionToken, rememberMe, r.UserAgent(), s.clientIP(r))
 	if err != nil {
 		log.Println("Failed to create session:", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
@@ -349,10 +618,12 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Println("Login successful for:", user.Email) // Debug log
+	s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeSuccess, map[string]interface{}{"method": method})
 
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Login successful",
-		"token":   sessionToken,
+		"message":    "Login successful",
+		"token":      sessionToken,
+		"expires_at": expiresAt.Format(time.RFC3339),
 		"user": map[string]interface{}{
 			"id":     user.ID,
 			"email":  user.Email,
@@ -381,30 +652,46 @@ func (s *Server) resendCodeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if user.GoogleID.Valid && user.GoogleID.String == "1" {
+	if user.IsVerified {
 		respondWithError(w, http.StatusBadRequest, "Email already verified")
 		return
 	}
 
-	s.db.DeleteOldVerificationCodes(req.Email)
+	// Un seul envoi par VerificationResendCooldown, quelle que soit l’instance qui reçoit la requête
+	wait, err := s.db.VerificationCodeCooldown(req.Email)
+	if err != nil {
+		log.Println("Failed to check verification code cooldown:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+	if wait > 0 {
+		respondWithRetryAfter(w, wait, "Please wait %d seconds before requesting a new code.")
+		return
+	}
 
-	code := database.GenerateVerificationCode()
+	code, err := database.GenerateVerificationCode()
+	if err != nil {
+		log.Println(err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
 	expiresAt := time.Now().Add(10 * time.Minute)
 
-	err = s.db.SaveVerificationCode(req.Email, code, expiresAt)
+	verificationEmail, err := s.email.VerificationEmail(req.Email, emailLocale(r, user.Locale), code)
 	if err != nil {
-		log.Println("Failed to save verification code:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to save verification code")
+		log.Println("Failed to build verification email:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
 		return
 	}
 
-	emailService := database.NewEmailService()
-	err = emailService.SendVerificationEmail(req.Email, code)
+	err = s.db.SaveVerificationCode(req.Email, code, expiresAt, verificationEmail)
 	if err != nil {
-		log.Println("Failed to send verification email:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+		log.Println("Failed to save verification code:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save verification code")
 		return
 	}
+	s.wakeEmailOutbox()
+	s.recordUserEvent(r, user.ID, database.EventVerificationResend, database.OutcomeSuccess, nil)
 
 	respondWithJSON(w, http.StatusOK, map[string]string{
 		"message": "Verification code sent successfully",
@@ -412,16 +699,14 @@ func (s *Server) resendCodeHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) getCurrentUser(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session_token")
+	token, err := sessionTokenFromCookie(r)
 	if err != nil {
-		log.Println("getCurrentUser: No cookie found in request")
+		log.Printf("getCurrentUser: %v", err)
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	log.Printf("getCurrentUser: Cookie value: %s", cookie.Value[:10]+"...")
-
-	user, err := s.db.GetUserBySessionToken(cookie.Value)
+	user, err := s.db.GetUserBySessionToken(token)
 	if err != nil {
 		log.Printf("getCurrentUser: Database error: %v", err)
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
@@ -430,11 +715,36 @@ func (s *Server) getCurrentUser(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("getCurrentUser: User found: ID=%d, Email=%s", user.ID, user.Email)
 
+	wallets, err := s.walletAddresses(int(user.ID))
+	if err != nil {
+		log.Printf("getCurrentUser: Failed to list wallets: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	roles, err := s.db.GetUserRoles(int(user.ID))
+	if err != nil {
+		log.Printf("getCurrentUser: Failed to load roles: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	permissions, err := s.db.GetUserPermissions(int(user.ID))
+	if err != nil {
+		log.Printf("getCurrentUser: Failed to load permissions: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"id":     user.ID,
-		"email":  user.Email,
-		"name":   user.Name,
-		"avatar": user.AvatarURL,
+		"id":          user.ID,
+		"email":       user.Email,
+		"name":        user.Name,
+		"avatar":      user.AvatarURL,
+		"wallets":     wallets,
+		"locale":      user.Locale,
+		"roles":       roles,
+		"permissions": permissions,
 	})
 }
 
@@ -488,32 +798,50 @@ func (s *Server) getUserByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Adresses vérifiées : permet de faire le lien avec les parties de ContractRegistry
+	wallets, err := s.walletAddresses(userID)
+	if err != nil {
+		log.Printf("getUserByIdHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"id":     user.ID,
-		"email":  user.Email,
-		"name":   user.Name,
-		"avatar": user.AvatarURL,
+		"id":      user.ID,
+		"email":   user.Email,
+		"name":    user.Name,
+		"avatar":  user.AvatarURL,
+		"wallets": wallets,
+		"locale":  user.Locale,
 	})
 }
 
 func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session_token")
+	token, err := sessionTokenFromCookie(r)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "No session found")
 		return
 	}
 
-	err = s.db.DeleteSession(cookie.Value)
+	user, _, _ := s.currentSession(r)
+
+	err = s.db.DeleteSession(token)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
+	if user != nil {
+		s.recordUserEvent(r, user.ID, database.EventLogout, database.OutcomeSuccess, nil)
+	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{
 		"message": "Logged out successfully",
 	})
 }
 
+// Cookie temporaire qui transporte le choix "remember me" pendant le flux OAuth
+const rememberMeCookie = "oauth_remember_me"
+
 func generateSessionToken() string {
 	b := make([]byte, 32)
 	rand.Read(b)
@@ -530,47 +858,3 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	w.Write(response)
 }
-
-type ReportRequest struct {
-	Type        string `json:"type"`   // "contract" or "user"
-	Target      string `json:"target"` // Contract ID or User Name/ID
-	Description string `json:"description"`
-}
-
-func (s *Server) reportHandler(w http.ResponseWriter, r *http.Request) {
-	// Verify user session
-	cookie, err := r.Cookie("session_token")
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "You must be logged in to report")
-		return
-	}
-
-	user, err := s.db.GetUserBySessionToken(cookie.Value)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid session")
-		return
-	}
-
-	var req ReportRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	if req.Type == "" || req.Target == "" || req.Description == "" {
-		respondWithError(w, http.StatusBadRequest, "All fields are required")
-		return
-	}
-
-	emailService := database.NewEmailService()
-	err = emailService.SendReportEmail(req.Type, req.Target, req.Description, user.Email)
-	if err != nil {
-		log.Println("Failed to send report email:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to submit report")
-		return
-	}
-
-	respondWithJSON(w, http.StatusOK, map[string]string{
-		"message": "Report submitted successfully",
-	})
-}
@@ -2,30 +2,76 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"strconv"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
+	"github.com/go-webauthn/webauthn/webauthn"
 
+	"auth/internal/auth"
+	"auth/internal/config"
 	"auth/internal/database"
+	"auth/internal/mailer"
 )
 
 type Server struct {
 	port int
 
-	db database.Service
+	config *config.Config
+
+	db database.Store
+
+	webAuthn *webauthn.WebAuthn
+
+	accessKeys *accessTokenKeys
+
+	email      *database.EmailService
+	sender     mailer.EmailSender
+	outboxWake chan struct{}
+
+	adminBootstrapped atomic.Bool
 }
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
+func NewServer(cfg *config.Config) *http.Server {
+	webAuthn, err := auth.NewWebAuthn(cfg.WebAuthn)
+	if err != nil {
+		log.Fatal("Failed to configure WebAuthn:", err)
+	}
+
+	sender, err := mailer.New(cfg.Email)
+	if err != nil {
+		log.Fatal("Failed to configure email delivery:", err)
+	}
+	if len(cfg.Email.ReportRecipients) == 0 {
+		log.Println("Warning: REPORT_RECIPIENTS is not set, reports are not sent by email")
+	}
+
 	NewServer := &Server{
-		port: port,
+		port: cfg.Server.Port,
+
+		config: cfg,
+
+		db: database.New(cfg),
+
+		webAuthn: webAuthn,
 
-		db: database.New(),
+		accessKeys: &accessTokenKeys{},
+
+		email:      database.NewEmailService(cfg.Email),
+		sender:     sender,
+		outboxWake: make(chan struct{}, 1),
 	}
 
+	// Premier administrateur (ADMIN_BOOTSTRAP_EMAIL)
+	NewServer.bootstrapAdmin()
+
+	// Envoi en arrière-plan des e-mails mis en file (table email_outbox)
+	go NewServer.runEmailOutbox()
+
+	// Purge des compteurs de la protection contre la force brute (table auth_throttles)
+	go NewServer.runThrottlePruner()
+
 	// Declare Server config
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", NewServer.port),
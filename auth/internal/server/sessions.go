@@ -0,0 +1,194 @@
+/*
+Ce fichier expose la gestion des appareils connectés :
+
+GET    /api/sessions       : liste les sessions actives de l’utilisateur courant
+DELETE /api/sessions       : révoque toutes les autres sessions (ou toutes avec ?all=true)
+DELETE /api/sessions/{id}  : révoque une session précise
+*/
+
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var errInvalidSessionToken = errors.New("invalid session token format")
+
+/*
+Lit le cookie session_token et vérifie son format (préfixe se_sess_ et somme de contrôle)
+avant toute requête en base ; les anciens jetons sans préfixe sont refusés.
+*/
+func sessionTokenFromCookie(r *http.Request) (string, error) {
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		return "", err
+	}
+	if !auth.ValidSessionToken(cookie.Value) {
+		return "", errInvalidSessionToken
+	}
+	return cookie.Value, nil
+}
+
+/*
+Résout l’utilisateur à partir du cookie session_token.
+Retourne aussi le token pour que l’appelant puisse identifier la session courante.
+*/
+func (s *Server) currentSession(r *http.Request) (*database.User, string, error) {
+	token, err := sessionTokenFromCookie(r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.db.GetUserBySessionToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, token, nil
+}
+
+func (s *Server) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, token, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessions, err := s.db.ListUserSessions(int(user.ID), token)
+	if err != nil {
+		log.Printf("listSessionsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+func (s *Server) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	deleted, err := s.db.DeleteUserSessionByID(int(user.ID), sessionID)
+	if err != nil {
+		log.Printf("revokeSessionHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	s.recordUserEvent(r, user.ID, database.EventSessionRevoke, database.OutcomeSuccess, map[string]interface{}{"session_id": sessionID})
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Session revoked",
+	})
+}
+
+func (s *Server) revokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, token, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if r.URL.Query().Get("all") == "true" {
+		if err := s.db.DeleteUserSessions(int(user.ID)); err != nil {
+			log.Printf("revokeOtherSessionsHandler error: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+			return
+		}
+		s.recordUserEvent(r, user.ID, database.EventSessionRevoke, database.OutcomeSuccess, map[string]interface{}{"scope": "all"})
+		respondWithJSON(w, http.StatusOK, map[string]string{
+			"message": "All sessions revoked",
+		})
+		return
+	}
+
+	revoked, err := s.db.DeleteOtherUserSessions(int(user.ID), token)
+	if err != nil {
+		log.Printf("revokeOtherSessionsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	s.recordUserEvent(r, user.ID, database.EventSessionRevoke, database.OutcomeSuccess, map[string]interface{}{"scope": "others", "revoked": revoked})
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Other sessions revoked",
+		"revoked": revoked,
+	})
+}
+
+/*
+Adresse IP du client.
+X-Forwarded-For n’est lu que si la connexion vient d’un proxy de confiance (TRUSTED_PROXIES),
+sinon n’importe quel client pourrait choisir l’adresse vue par la protection contre la force brute.
+L’en-tête est parcouru depuis la fin : la première adresse qui n’est pas un proxy de confiance
+est le client (le gateway y met r.RemoteAddr, donc potentiellement avec le port).
+*/
+func (s *Server) clientIP(r *http.Request) string {
+	addr := hostOnly(r.RemoteAddr)
+	if !s.trustedProxy(addr) {
+		return addr
+	}
+
+	entries := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := hostOnly(strings.TrimSpace(entries[i]))
+		if entry == "" {
+			continue
+		}
+		addr = entry
+		if !s.trustedProxy(entry) {
+			break
+		}
+	}
+	return addr
+}
+
+func (s *Server) trustedProxy(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	// Liste déjà validée au chargement de la configuration
+	prefixes, _ := s.config.Server.TrustedProxyPrefixes()
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retire le port éventuel d’une adresse host:port.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,232 @@
+/*
+Ce fichier gère Sign-In with Ethereum (EIP-4361) et la liaison de wallets vérifiés.
+
+GET  /auth/siwe/nonce  : nonce à inclure dans le message SIWE
+POST /auth/siwe/verify : vérifie message + signature ;
+                         utilisateur connecté  -> lie l’adresse à son compte,
+                         sinon                 -> connecte le propriétaire du wallet (comme loginHandler)
+
+GET    /api/wallets           : wallets vérifiés de l’utilisateur courant
+DELETE /api/wallets/{address} : retire un wallet
+*/
+
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const siweNonceTTL = 10 * time.Minute
+
+type SIWEVerifyRequest struct {
+	Message    string `json:"message"`
+	Signature  string `json:"signature"`
+	RememberMe bool   `json:"remember_me"`
+}
+
+// Nonce alphanumérique de 16 caractères (EIP-4361 exige au moins 8).
+func generateSIWENonce() string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, 16)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+// Adresses (format EIP-55) des wallets vérifiés d’un utilisateur.
+func (s *Server) walletAddresses(userID int) ([]string, error) {
+	wallets, err := s.db.ListUserWallets(userID)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, len(wallets))
+	for _, wallet := range wallets {
+		addresses = append(addresses, auth.ChecksumAddress(wallet.Address))
+	}
+	return addresses, nil
+}
+
+func (s *Server) siweNonceHandler(w http.ResponseWriter, r *http.Request) {
+	nonce := generateSIWENonce()
+	if err := s.db.SaveSIWENonce(nonce, time.Now().Add(siweNonceTTL)); err != nil {
+		log.Printf("siweNonceHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate nonce")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"nonce":  nonce,
+		"domain": s.config.SIWEDomain,
+	})
+}
+
+func (s *Server) siweVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req SIWEVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Message == "" || req.Signature == "" {
+		respondWithError(w, http.StatusBadRequest, "Message and signature are required")
+		return
+	}
+
+	msg, err := auth.ParseSIWEMessage(req.Message)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid SIWE message")
+		return
+	}
+
+	if err := msg.Validate(s.config.SIWEDomain, time.Now()); err != nil {
+		log.Printf("siweVerifyHandler: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid SIWE message")
+		return
+	}
+
+	signer, err := auth.RecoverPersonalSignAddress(req.Message, req.Signature)
+	if err != nil || !strings.EqualFold(signer, msg.Address) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	// Le nonce n’est consommé qu’après une signature valide, mais avant toute action
+	valid, err := s.db.ConsumeSIWENonce(msg.Nonce)
+	if err != nil {
+		log.Printf("siweVerifyHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify signature")
+		return
+	}
+	if !valid {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired nonce")
+		return
+	}
+
+	ownerID, err := s.db.FindUserByWallet(signer)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("siweVerifyHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify signature")
+		return
+	}
+	linked := err == nil
+
+	// Utilisateur connecté : liaison du wallet à son compte
+	if user, _, err := s.currentSession(r); err == nil {
+		if linked && ownerID != int(user.ID) {
+			respondWithError(w, http.StatusConflict, "This wallet is already linked to another account")
+			return
+		}
+		if err := s.db.LinkWallet(int(user.ID), signer, msg.ChainID); err != nil {
+			log.Printf("siweVerifyHandler error: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to link wallet")
+			return
+		}
+		s.recordUserEvent(r, user.ID, database.EventWalletLink, database.OutcomeSuccess, map[string]interface{}{"address": signer, "chain_id": msg.ChainID})
+		respondWithJSON(w, http.StatusOK, map[string]string{
+			"message": "Wallet linked successfully",
+			"address": signer,
+		})
+		return
+	}
+
+	// Sinon : connexion du propriétaire du wallet
+	if !linked {
+		respondWithError(w, http.StatusNotFound, "No account is linked to this wallet")
+		return
+	}
+
+	user, err := s.db.GetUserByID(ownerID)
+	if err != nil {
+		log.Printf("siweVerifyHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	// Vérifié avant le challenge TOTP, comme pour la connexion par mot de passe
+	if user.IsSuspended(time.Now()) {
+		s.recordUserEvent(r, user.ID, database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "siwe", "reason": "suspended"})
+		respondWithError(w, http.StatusForbidden, suspensionMessage(user))
+		return
+	}
+
+	twoFactor, err := s.db.HasTOTPEnabled(ownerID)
+	if err != nil {
+		log.Printf("siweVerifyHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	if twoFactor {
+		s.respondWithLoginChallenge(w, ownerID, req.RememberMe)
+		return
+	}
+
+	s.respondWithNewSession(w, r, user, "siwe", req.RememberMe)
+}
+
+func (s *Server) listWalletsHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	wallets, err := s.db.ListUserWallets(int(user.ID))
+	if err != nil {
+		log.Printf("listWalletsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list wallets")
+		return
+	}
+	for i := range wallets {
+		wallets[i].Address = auth.ChecksumAddress(wallets[i].Address)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"wallets": wallets,
+	})
+}
+
+func (s *Server) unlinkWalletHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	address := chi.URLParam(r, "address")
+	if !auth.IsEthereumAddress(address) {
+		respondWithError(w, http.StatusBadRequest, "Invalid wallet address")
+		return
+	}
+
+	if !s.checkRemainingLoginMethod(w, int(user.ID)) {
+		return
+	}
+
+	deleted, err := s.db.UnlinkWallet(int(user.ID), address)
+	if err != nil {
+		log.Printf("unlinkWalletHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlink wallet")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Wallet not found")
+		return
+	}
+	s.recordUserEvent(r, user.ID, database.EventWalletUnlink, database.OutcomeSuccess, map[string]interface{}{"address": address})
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Wallet unlinked",
+	})
+}
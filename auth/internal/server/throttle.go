@@ -0,0 +1,137 @@
+/*
+Protection contre la force brute sur la connexion par mot de passe, la vérification d’email
+et les codes de double authentification demandés à un utilisateur déjà connecté.
+
+Chaque tentative est comptée par compte (email) et par adresse IP. Tant qu’un compteur impose
+un délai ou un verrouillage (auth.ThrottlePolicy), la requête est refusée avec 429 et Retry-After,
+sans être comptée comme un nouvel échec. Les autres tentatives sont comptées comme des échecs
+dès leur arrivée ; seule une réussite est décomptée.
+*/
+
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+)
+
+const throttlePruneInterval = time.Hour
+
+type throttleCounter struct {
+	key    string
+	policy auth.ThrottlePolicy
+}
+
+/*
+Compteurs par compte et par IP d’une action (login, verify…).
+L’email est remplacé par son empreinte SHA-256 : la clé garde une longueur fixe (throttle_key varchar(191)),
+sinon un email très long ferait échouer l’écriture du compteur et ne serait jamais limité.
+*/
+func (s *Server) throttleCounters(action, email string, r *http.Request) []throttleCounter {
+	account := database.HashToken(strings.ToLower(strings.TrimSpace(email)))
+	return []throttleCounter{
+		{key: action + ":account:" + account, policy: auth.AccountThrottlePolicy},
+		{key: action + ":ip:" + s.clientIP(r), policy: auth.IPThrottlePolicy},
+	}
+}
+
+// Compteurs par compte et par IP d’une action d’un utilisateur connecté (le compte n’a pas toujours d’email).
+func (s *Server) userThrottleCounters(action string, userID int64, r *http.Request) []throttleCounter {
+	return []throttleCounter{
+		{key: action + ":user:" + strconv.FormatInt(userID, 10), policy: auth.AccountThrottlePolicy},
+		{key: action + ":ip:" + s.clientIP(r), policy: auth.IPThrottlePolicy},
+	}
+}
+
+/*
+Refuse la requête avec 429 si l’un des compteurs impose encore un délai ; retourne false dans ce cas.
+Sinon, la tentative est réservée sur chaque compteur (elle compte d’avance comme un échec)
+avant que l’appelant ne vérifie le secret : des requêtes simultanées ne peuvent pas dépasser
+la politique. Une réussite rend la réservation avec recordThrottleSuccess.
+En cas d’erreur de base de données, la requête est laissée passer.
+*/
+func (s *Server) checkThrottle(w http.ResponseWriter, counters []throttleCounter) bool {
+	for i, c := range counters {
+		for {
+			throttles, err := s.db.GetThrottles(c.key)
+			if err != nil {
+				log.Printf("checkThrottle error: %v", err)
+				return true
+			}
+
+			var seen *database.Throttle
+			if t, ok := throttles[c.key]; ok {
+				if wait := c.policy.RetryAfter(t.Failures, t.LastFailureAt, time.Now().UTC()); wait > 0 {
+					s.releaseThrottleAttempts(counters[:i])
+					respondWithRetryAfter(w, wait, "Too many failed attempts. Try again in %d seconds.")
+					return false
+				}
+				seen = &t
+			}
+
+			// Échec de la réservation : une autre requête a modifié le compteur, il est relu
+			reserved, err := s.db.ReserveThrottleAttempt(c.key, seen, c.policy.Window)
+			if err != nil {
+				log.Printf("checkThrottle error: %v", err)
+				return true
+			}
+			if reserved {
+				break
+			}
+		}
+	}
+	return true
+}
+
+// Réponse 429 avec l’en-tête Retry-After (secondes) ; message reçoit le nombre de secondes.
+func respondWithRetryAfter(w http.ResponseWriter, wait time.Duration, message string) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondWithJSON(w, http.StatusTooManyRequests, map[string]interface{}{
+		"error":       fmt.Sprintf(message, seconds),
+		"retry_after": seconds,
+	})
+}
+
+/*
+Après une réussite : remet à zéro le compteur du compte et rend la tentative réservée
+sur les autres compteurs (le compteur IP conserve les échecs précédents).
+*/
+func (s *Server) recordThrottleSuccess(counters []throttleCounter) {
+	if err := s.db.ResetThrottle(counters[0].key); err != nil {
+		log.Printf("recordThrottleSuccess error: %v", err)
+	}
+	s.releaseThrottleAttempts(counters[1:])
+}
+
+func (s *Server) releaseThrottleAttempts(counters []throttleCounter) {
+	for _, c := range counters {
+		if err := s.db.ReleaseThrottleAttempt(c.key); err != nil {
+			log.Printf("releaseThrottleAttempts error: %v", err)
+		}
+	}
+}
+
+// Supprime régulièrement les compteurs expirés.
+func (s *Server) runThrottlePruner() {
+	window := auth.AccountThrottlePolicy.Window
+	if auth.IPThrottlePolicy.Window > window {
+		window = auth.IPThrottlePolicy.Window
+	}
+
+	ticker := time.NewTicker(throttlePruneInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.db.PruneThrottles(window); err != nil {
+			log.Printf("runThrottlePruner error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"auth/internal/auth"
+	"auth/internal/config"
+	"auth/internal/database"
+)
+
+// Envoie n requêtes POST identiques en parallèle ; retourne le nombre de réponses par message d’erreur.
+func (api *testAPI) postInParallel(n int, path string, body interface{}) map[string]int {
+	api.t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]int)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := api.client.Post(api.http.URL+path, "application/json", bytes.NewReader(data))
+			if err != nil {
+				api.t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+
+			var payload map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&payload)
+			message, _ := payload["error"].(string)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				message = "throttled"
+			}
+			mu.Lock()
+			results[message]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func TestParallelLoginFailuresAreThrottled(t *testing.T) {
+	api := newTestAPI(t)
+	api.signUp("ivan@example.com", "password1")
+
+	results := api.postInParallel(20, "/auth/login", LoginRequest{Email: "ivan@example.com", Password: "wrong-password"})
+	if allowed := auth.AccountThrottlePolicy.FreeFailures + 1; results["Invalid email or password"] != allowed || results["throttled"] != 20-allowed {
+		t.Fatalf("expected %d password checks and the rest throttled, got %v", allowed, results)
+	}
+}
+
+func TestParallelTwoFactorGuessesAreLimited(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "judy@example.com"
+	api.signUp(email, "password1")
+
+	user, err := api.store.FindUserByEmail(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.store.SaveTOTPSecret(int(user.ID), "JBSWY3DPEHPK3PXP"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.store.ConfirmTOTP(int(user.ID), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	login := api.expect("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"}, http.StatusOK)
+	challenge, _ := login["challenge_token"].(string)
+	if login["two_factor_required"] != true || challenge == "" {
+		t.Fatalf("expected a two-factor challenge, got %v", login)
+	}
+
+	results := api.postInParallel(20, "/auth/login/2fa", LoginTwoFactorRequest{ChallengeToken: challenge, RecoveryCode: "wrong-code"})
+	if results["Invalid two-factor code"] != database.MaxLoginChallengeAttempts {
+		t.Fatalf("expected %d code checks, got %v", database.MaxLoginChallengeAttempts, results)
+	}
+}
+
+func TestTwoFactorCodeChecksOfASessionAreThrottled(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "kate@example.com"
+	token := api.signUp(email, "password1")
+	userID := api.userID(email)
+	if err := api.store.SaveTOTPSecret(userID, "JBSWY3DPEHPK3PXP"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.store.ConfirmTOTP(userID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := TwoFactorCodeRequest{RecoveryCode: "wrong-code"}
+	for i := 0; i <= auth.AccountThrottlePolicy.FreeFailures; i++ {
+		api.expect("POST", "/api/2fa/totp/disable", token, wrong, http.StatusBadRequest)
+	}
+	resp, payload := api.do("POST", "/api/2fa/totp/disable", token, wrong)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After after repeated wrong codes, got %d (%v)", resp.StatusCode, payload)
+	}
+
+	// La régénération des codes de secours vérifie le même code : même compteur
+	api.expect("POST", "/api/2fa/recovery-codes", token, TwoFactorCodeRequest{Code: "000000"}, http.StatusTooManyRequests)
+
+	if enabled, err := api.store.HasTOTPEnabled(userID); err != nil || !enabled {
+		t.Fatalf("two-factor authentication should still be enabled, got %v, %v", enabled, err)
+	}
+	if event := api.lastEvent(userID, database.EventTwoFactorDisable); event.Metadata["reason"] != "throttled" {
+		t.Fatalf("expected the throttled attempt in the audit log, got %+v", event)
+	}
+}
+
+func TestPasswordChangeSharesTheLoginThrottle(t *testing.T) {
+	api := newTestAPI(t)
+	const email = "liam@example.com"
+	token := api.signUp(email, "password1")
+
+	wrong := SetPasswordRequest{CurrentPassword: "wrong-password", NewPassword: "password2"}
+	for i := 0; i <= auth.AccountThrottlePolicy.FreeFailures; i++ {
+		api.expect("POST", "/api/me/password", token, wrong, http.StatusUnauthorized)
+	}
+	api.expect("POST", "/api/me/password", token, SetPasswordRequest{CurrentPassword: "password1", NewPassword: "password2"}, http.StatusTooManyRequests)
+
+	// Le compteur est celui de la connexion par mot de passe
+	resp, payload := api.do("POST", "/auth/login", "", LoginRequest{Email: email, Password: "password1"})
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected the login to be throttled too, got %d (%v)", resp.StatusCode, payload)
+	}
+}
+
+func TestThrottleKeysHaveABoundedLength(t *testing.T) {
+	api := newTestAPI(t)
+	r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	r.RemoteAddr = "[2001:db8:ffff:ffff:ffff:ffff:ffff:ffff]:5000"
+
+	long := strings.Repeat("a", 500) + "@example.com"
+	counters := api.server.throttleCounters("verify", long, r)
+	for _, c := range counters {
+		if len(c.key) > 191 {
+			t.Errorf("throttle key of %d characters does not fit throttle_key varchar(191): %s", len(c.key), c.key)
+		}
+	}
+	if counters[0].key != api.server.throttleCounters("verify", "  "+strings.ToUpper(long), r)[0].key {
+		t.Error("the account key should not depend on case or surrounding spaces")
+	}
+}
+
+func TestClientIPTrustsOnlyConfiguredProxies(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.10"}
+	s := &Server{config: cfg}
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"10.1.2.3:5000", "", "10.1.2.3"},
+		{"10.1.2.3:5000", "198.51.100.1:41000", "198.51.100.1"},
+		{"192.0.2.10:5000", "198.51.100.1", "198.51.100.1"},
+		// Seule l’entrée ajoutée par le proxy de confiance compte, pas celle envoyée par le client
+		{"10.1.2.3:5000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"10.1.2.3:5000", "198.51.100.1, 10.9.9.9", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := s.clientIP(r); got != tt.want {
+			t.Errorf("RemoteAddr %s, X-Forwarded-For %q: expected %s, got %s", tt.remoteAddr, tt.forwarded, tt.want, got)
+		}
+	}
+}
+
+func TestForwardedForDoesNotBypassIPThrottle(t *testing.T) {
+	api := newTestAPI(t)
+	api.server.config.Server.TrustedProxies = nil
+
+	// Chaque tentative vise un autre compte et annonce une autre adresse : seul le compteur IP s’applique
+	attempt := func(i int) int {
+		body, _ := json.Marshal(LoginRequest{Email: fmt.Sprintf("user%d@example.com", i), Password: "wrong-password"})
+		req, _ := http.NewRequest(http.MethodPost, api.http.URL+"/auth/login", bytes.NewReader(body))
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		resp, err := api.client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	free := auth.IPThrottlePolicy.FreeFailures
+	for i := 0; i <= free; i++ {
+		if status := attempt(i); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i, status)
+		}
+	}
+	if status := attempt(free + 1); status != http.StatusTooManyRequests {
+		t.Fatalf("a spoofed X-Forwarded-For should not reset the IP counter, got %d", status)
+	}
+}
@@ -0,0 +1,405 @@
+/*
+Ce fichier gère l’authentification à deux facteurs (TOTP, RFC 6238).
+
+Inscription (utilisateur connecté, email/mot de passe ou Google) :
+POST /api/2fa/totp/setup   : génère un secret et l’URI otpauth:// (QR code)
+POST /api/2fa/totp/confirm : vérifie le premier code, active la 2FA et renvoie les codes de récupération
+POST /api/2fa/totp/disable : désactive la 2FA (code TOTP ou code de récupération requis)
+POST /api/2fa/recovery-codes : régénère les codes de récupération
+GET  /api/2fa              : état de la 2FA
+
+Connexion :
+loginHandler et le callback OAuth renvoient un challenge_token au lieu d’une session ;
+POST /auth/login/2fa échange ce challenge + un code contre une session.
+*/
+
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"auth/internal/auth"
+	"auth/internal/database"
+)
+
+const (
+	// Durée de validité d’un challenge de connexion
+	loginChallengeTTL = 5 * time.Minute
+
+	recoveryCodeCount = 10
+)
+
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// Crée un challenge de connexion et retourne le jeton en clair (seul son hash est stocké).
+func (s *Server) createLoginChallenge(userID int, rememberMe bool) (string, error) {
+	token := generateSessionToken()
+	err := s.db.CreateLoginChallenge(userID, database.HashToken(token), rememberMe, time.Now().Add(loginChallengeTTL))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Réponse de loginHandler quand un deuxième facteur est requis.
+func (s *Server) respondWithLoginChallenge(w http.ResponseWriter, userID int, rememberMe bool) {
+	challengeToken, err := s.createLoginChallenge(userID, rememberMe)
+	if err != nil {
+		log.Println("Failed to create login challenge:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":             "Two-factor authentication required",
+		"two_factor_required": true,
+		"challenge_token":     challengeToken,
+		"expires_in":          int(loginChallengeTTL.Seconds()),
+	})
+}
+
+/*
+Vérifie le deuxième facteur : un code TOTP, sinon un code de récupération.
+Un code TOTP accepté est marqué comme utilisé pour empêcher son rejeu.
+*/
+func (s *Server) verifySecondFactor(userID int, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		hash := database.HashToken(auth.NormalizeRecoveryCode(recoveryCode))
+		return s.db.UseRecoveryCode(userID, hash)
+	}
+
+	enrollment, err := s.db.GetTOTPEnrollment(userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !enrollment.Confirmed {
+		return false, nil
+	}
+
+	step, ok := auth.ValidateTOTP(enrollment.Secret, code, time.Now(), enrollment.LastStep)
+	if !ok {
+		return false, nil
+	}
+	return s.db.MarkTOTPStepUsed(userID, step)
+}
+
+// Génère et enregistre de nouveaux codes de récupération ; retourne les codes en clair (affichés une seule fois).
+func (s *Server) issueRecoveryCodes(userID int) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = database.HashToken(auth.NormalizeRecoveryCode(code))
+	}
+
+	if err := s.db.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Deuxième étape de connexion : challenge_token + code TOTP (ou code de récupération).
+func (s *Server) loginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var req LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		respondWithError(w, http.StatusBadRequest, "Challenge token and code are required")
+		return
+	}
+
+	challenge, err := s.db.GetLoginChallenge(database.HashToken(req.ChallengeToken))
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired login challenge")
+		return
+	}
+	if err != nil {
+		log.Println("Failed to load login challenge:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify code")
+		return
+	}
+
+	// La tentative est comptée avant la vérification : pas de dépassement de la limite en parallèle
+	reserved, err := s.db.ReserveLoginChallengeAttempt(challenge.ID)
+	if err != nil {
+		log.Println("Failed to count login challenge attempt:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify code")
+		return
+	}
+	if !reserved {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired login challenge")
+		return
+	}
+
+	valid, err := s.verifySecondFactor(challenge.UserID, req.Code, req.RecoveryCode)
+	if err != nil {
+		log.Println("Failed to verify second factor:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify code")
+		return
+	}
+	if !valid {
+		s.recordUserEvent(r, int64(challenge.UserID), database.EventLogin, database.OutcomeFailure, map[string]interface{}{"method": "totp", "reason": "invalid_code"})
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	}
+
+	consumed, err := s.db.ConsumeLoginChallenge(challenge.ID)
+	if err != nil {
+		log.Println("Failed to consume login challenge:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	if !consumed {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired login challenge")
+		return
+	}
+
+	user, err := s.db.GetUserByID(challenge.UserID)
+	if err != nil {
+		log.Println("Failed to load user after two-factor:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+
+	s.respondWithNewSession(w, r, user, "totp", challenge.RememberMe)
+}
+
+func (s *Server) twoFactorStatusHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	enabled, err := s.db.HasTOTPEnabled(int(user.ID))
+	if err != nil {
+		log.Printf("twoFactorStatusHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load two-factor status")
+		return
+	}
+
+	remaining := 0
+	if enabled {
+		remaining, err = s.db.CountRecoveryCodes(int(user.ID))
+		if err != nil {
+			log.Printf("twoFactorStatusHandler error: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to load two-factor status")
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"totp_enabled":             enabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// Génère un nouveau secret TOTP en attente de confirmation.
+func (s *Server) totpSetupHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	enabled, err := s.db.HasTOTPEnabled(int(user.ID))
+	if err != nil {
+		log.Printf("totpSetupHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start two-factor setup")
+		return
+	}
+	if enabled {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("totpSetupHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start two-factor setup")
+		return
+	}
+
+	if err := s.db.SaveTOTPSecret(int(user.ID), secret); err != nil {
+		log.Printf("totpSetupHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start two-factor setup")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(secret, user.Email),
+	})
+}
+
+// Active la 2FA si le code correspond au secret en attente, puis renvoie les codes de récupération.
+func (s *Server) totpConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		respondWithError(w, http.StatusBadRequest, "Code is required")
+		return
+	}
+
+	enrollment, err := s.db.GetTOTPEnrollment(int(user.ID))
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusBadRequest, "Two-factor setup has not been started")
+		return
+	}
+	if err != nil {
+		log.Printf("totpConfirmHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to confirm two-factor setup")
+		return
+	}
+	if enrollment.Confirmed {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(enrollment.Secret, req.Code, time.Now(), enrollment.LastStep)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid two-factor code")
+		return
+	}
+
+	if err := s.db.ConfirmTOTP(int(user.ID), step); err != nil {
+		log.Printf("totpConfirmHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to confirm two-factor setup")
+		return
+	}
+
+	codes, err := s.issueRecoveryCodes(int(user.ID))
+	if err != nil {
+		log.Printf("totpConfirmHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	s.recordUserEvent(r, user.ID, database.EventTwoFactorEnable, database.OutcomeSuccess, nil)
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+func (s *Server) totpDisableHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		respondWithError(w, http.StatusBadRequest, "Code is required")
+		return
+	}
+
+	// Une session volée ne doit pas permettre d’essayer tous les codes pour désactiver la 2FA
+	throttle := s.userThrottleCounters("2fa", user.ID, r)
+	if !s.checkThrottle(w, throttle) {
+		s.recordUserEvent(r, user.ID, database.EventTwoFactorDisable, database.OutcomeFailure, map[string]interface{}{"reason": "throttled"})
+		return
+	}
+
+	valid, err := s.verifySecondFactor(int(user.ID), req.Code, req.RecoveryCode)
+	if err != nil {
+		log.Printf("totpDisableHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+	if !valid {
+		s.recordUserEvent(r, user.ID, database.EventTwoFactorDisable, database.OutcomeFailure, map[string]interface{}{"reason": "invalid_code"})
+		respondWithError(w, http.StatusBadRequest, "Invalid two-factor code")
+		return
+	}
+
+	s.recordThrottleSuccess(throttle)
+
+	if err := s.db.DeleteTOTP(int(user.ID)); err != nil {
+		log.Printf("totpDisableHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	s.recordUserEvent(r, user.ID, database.EventTwoFactorDisable, database.OutcomeSuccess, nil)
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+func (s *Server) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		respondWithError(w, http.StatusBadRequest, "Code is required")
+		return
+	}
+
+	// Même compteur que la désactivation : les deux vérifient le même code
+	throttle := s.userThrottleCounters("2fa", user.ID, r)
+	if !s.checkThrottle(w, throttle) {
+		s.recordUserEvent(r, user.ID, database.EventRecoveryCodesRegenerate, database.OutcomeFailure, map[string]interface{}{"reason": "throttled"})
+		return
+	}
+
+	valid, err := s.verifySecondFactor(int(user.ID), req.Code, "")
+	if err != nil {
+		log.Printf("regenerateRecoveryCodesHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+	if !valid {
+		s.recordUserEvent(r, user.ID, database.EventRecoveryCodesRegenerate, database.OutcomeFailure, map[string]interface{}{"reason": "invalid_code"})
+		respondWithError(w, http.StatusBadRequest, "Invalid two-factor code")
+		return
+	}
+	s.recordThrottleSuccess(throttle)
+
+	codes, err := s.issueRecoveryCodes(int(user.ID))
+	if err != nil {
+		log.Printf("regenerateRecoveryCodesHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	s.recordUserEvent(r, user.ID, database.EventRecoveryCodesRegenerate, database.OutcomeSuccess, nil)
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
@@ -0,0 +1,347 @@
+/*
+Ce fichier gère les passkeys (WebAuthn).
+
+Enregistrement (utilisateur connecté) :
+POST /auth/webauthn/register/begin  : options de création (challenge) pour navigator.credentials.create
+POST /auth/webauthn/register/finish : vérifie l’attestation et enregistre la passkey (?name=...)
+
+Connexion sans mot de passe :
+POST /auth/webauthn/login/begin  : options d’assertion pour navigator.credentials.get
+POST /auth/webauthn/login/finish : vérifie l’assertion et crée une session comme loginHandler (?remember_me=true)
+
+Gestion :
+GET    /api/webauthn/credentials      : liste des passkeys
+DELETE /api/webauthn/credentials/{id} : suppression d’une passkey
+
+Le challenge est conservé côté serveur (table webauthn_ceremonies) ;
+le navigateur ne garde qu’un identifiant opaque dans le cookie webauthn_ceremony.
+*/
+
+package server
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth/internal/database"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	webAuthnCeremonyCookie = "webauthn_ceremony"
+	webAuthnCeremonyTTL    = 5 * time.Minute
+
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// Adapte un utilisateur de la base à l’interface webauthn.User.
+type webAuthnUser struct {
+	user        *database.User
+	credentials []webauthn.Credential
+}
+
+// Le user handle est l’ID interne sur 8 octets : il ne contient ni email ni nom.
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return webAuthnUserHandle(u.user.ID)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.user.Name != "" {
+		return u.user.Name
+	}
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func webAuthnUserHandle(userID int64) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// Charge un utilisateur avec ses passkeys enregistrées.
+func (s *Server) loadWebAuthnUser(user *database.User) (*webAuthnUser, error) {
+	stored, err := s.db.ListWebAuthnCredentials(int(user.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(c.Data, &credential); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// Conserve les données de la cérémonie en base et pose le cookie qui les identifie.
+func (s *Server) startWebAuthnCeremony(w http.ResponseWriter, userID int, ceremony string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	id := generateSessionToken()
+	if err := s.db.SaveWebAuthnCeremony(database.HashToken(id), userID, ceremony, data, time.Now().Add(webAuthnCeremonyTTL)); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     webAuthnCeremonyCookie,
+		Value:    id,
+		Path:     "/auth/webauthn",
+		MaxAge:   int(webAuthnCeremonyTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   s.config.Cookies.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+// Récupère (une seule fois) les données de la cérémonie en cours et efface le cookie.
+func (s *Server) finishWebAuthnCeremony(w http.ResponseWriter, r *http.Request, ceremony string) (int, *webauthn.SessionData, error) {
+	cookie, err := r.Cookie(webAuthnCeremonyCookie)
+	if err != nil {
+		return 0, nil, sql.ErrNoRows
+	}
+	http.SetCookie(w, &http.Cookie{Name: webAuthnCeremonyCookie, Value: "", Path: "/auth/webauthn", MaxAge: -1})
+
+	userID, data, err := s.db.ConsumeWebAuthnCeremony(database.HashToken(cookie.Value), ceremony)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return 0, nil, err
+	}
+	return userID, &session, nil
+}
+
+func (s *Server) webAuthnRegisterBeginHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	waUser, err := s.loadWebAuthnUser(user)
+	if err != nil {
+		log.Printf("webAuthnRegisterBeginHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start passkey registration")
+		return
+	}
+
+	// Exclut les passkeys déjà enregistrées pour éviter les doublons sur un même authentificateur
+	options, session, err := s.webAuthn.BeginRegistration(waUser,
+		webauthn.WithExclusions(webauthn.Credentials(waUser.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		log.Printf("webAuthnRegisterBeginHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start passkey registration")
+		return
+	}
+
+	if err := s.startWebAuthnCeremony(w, int(user.ID), ceremonyRegistration, session); err != nil {
+		log.Printf("webAuthnRegisterBeginHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start passkey registration")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, options)
+}
+
+func (s *Server) webAuthnRegisterFinishHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ceremonyUserID, session, err := s.finishWebAuthnCeremony(w, r, ceremonyRegistration)
+	if err != nil || ceremonyUserID != int(user.ID) {
+		respondWithError(w, http.StatusBadRequest, "No passkey registration in progress")
+		return
+	}
+
+	waUser, err := s.loadWebAuthnUser(user)
+	if err != nil {
+		log.Printf("webAuthnRegisterFinishHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to register passkey")
+		return
+	}
+
+	credential, err := s.webAuthn.FinishRegistration(waUser, *session, r)
+	if err != nil {
+		log.Printf("webAuthnRegisterFinishHandler: verification failed: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Passkey verification failed")
+		return
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		log.Printf("webAuthnRegisterFinishHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to register passkey")
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "Passkey"
+	}
+
+	if err := s.db.SaveWebAuthnCredential(int(user.ID), credential.ID, name, data); err != nil {
+		log.Printf("webAuthnRegisterFinishHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to register passkey")
+		return
+	}
+	s.recordUserEvent(r, user.ID, database.EventPasskeyRegister, database.OutcomeSuccess, map[string]interface{}{"name": name})
+
+	respondWithJSON(w, http.StatusCreated, map[string]string{
+		"message": "Passkey registered successfully",
+	})
+}
+
+func (s *Server) webAuthnLoginBeginHandler(w http.ResponseWriter, r *http.Request) {
+	options, session, err := s.webAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		log.Printf("webAuthnLoginBeginHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start passkey login")
+		return
+	}
+
+	if err := s.startWebAuthnCeremony(w, 0, ceremonyLogin, session); err != nil {
+		log.Printf("webAuthnLoginBeginHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start passkey login")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, options)
+}
+
+func (s *Server) webAuthnLoginFinishHandler(w http.ResponseWriter, r *http.Request) {
+	_, session, err := s.finishWebAuthnCeremony(w, r, ceremonyLogin)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "No passkey login in progress")
+		return
+	}
+
+	// Retrouve l’utilisateur à partir du user handle renvoyé par l’authentificateur
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		if len(userHandle) != 8 {
+			return nil, errors.New("invalid user handle")
+		}
+		user, err := s.db.GetUserByID(int(binary.BigEndian.Uint64(userHandle)))
+		if err != nil {
+			return nil, err
+		}
+		return s.loadWebAuthnUser(user)
+	}
+
+	waUser, credential, err := s.webAuthn.FinishPasskeyLogin(handler, *session, r)
+	if err != nil {
+		log.Printf("webAuthnLoginFinishHandler: verification failed: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Passkey verification failed")
+		return
+	}
+
+	if credential.Authenticator.CloneWarning {
+		log.Printf("webAuthnLoginFinishHandler: clone warning for credential of user %d", waUser.(*webAuthnUser).user.ID)
+	}
+
+	// Sauvegarde le nouveau compteur de signatures
+	if data, err := json.Marshal(credential); err == nil {
+		if err := s.db.UpdateWebAuthnCredential(credential.ID, data); err != nil {
+			log.Printf("webAuthnLoginFinishHandler: failed to update credential: %v", err)
+		}
+	}
+
+	s.respondWithNewSession(w, r, waUser.(*webAuthnUser).user, "passkey", r.URL.Query().Get("remember_me") == "true")
+}
+
+func (s *Server) listWebAuthnCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	stored, err := s.db.ListWebAuthnCredentials(int(user.ID))
+	if err != nil {
+		log.Printf("listWebAuthnCredentialsHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to list passkeys")
+		return
+	}
+
+	credentials := make([]map[string]interface{}, 0, len(stored))
+	for _, c := range stored {
+		item := map[string]interface{}{
+			"id":           c.ID,
+			"name":         c.Name,
+			"created_at":   c.CreatedAt,
+			"last_used_at": nil,
+		}
+		if c.LastUsedAt.Valid {
+			item["last_used_at"] = c.LastUsedAt.Time
+		}
+		credentials = append(credentials, item)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"credentials": credentials,
+	})
+}
+
+func (s *Server) deleteWebAuthnCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, err := s.currentSession(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid passkey ID")
+		return
+	}
+
+	if !s.checkRemainingLoginMethod(w, int(user.ID)) {
+		return
+	}
+
+	deleted, err := s.db.DeleteWebAuthnCredential(int(user.ID), id)
+	if err != nil {
+		log.Printf("deleteWebAuthnCredentialHandler error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete passkey")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Passkey not found")
+		return
+	}
+	s.recordUserEvent(r, user.ID, database.EventPasskeyDelete, database.OutcomeSuccess, map[string]interface{}{"credential_id": id})
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Passkey deleted",
+	})
+}
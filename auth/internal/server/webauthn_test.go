@@ -0,0 +1,329 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+
+	"auth/internal/auth"
+)
+
+// Authentificateur logiciel (ES256, attestation « none ») qui répond aux cérémonies du serveur de test.
+type testAuthenticator struct {
+	t            *testing.T
+	origin       string
+	rpID         string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newTestAuthenticator(api *testAPI) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+
+	return &testAuthenticator{
+		t:            api.t,
+		origin:       api.server.config.Server.FrontendURL,
+		rpID:         api.server.config.WebAuthn.RPID,
+		key:          key,
+		credentialID: credentialID,
+	}
+}
+
+var b64 = base64.RawURLEncoding
+
+// Challenge (et user handle) des options retournées par */begin.
+func ceremonyOptions(t *testing.T, options map[string]interface{}) (challenge string, userHandle []byte) {
+	t.Helper()
+
+	publicKey, _ := options["publicKey"].(map[string]interface{})
+	challenge, _ = publicKey["challenge"].(string)
+	if challenge == "" {
+		t.Fatalf("options without challenge: %v", options)
+	}
+	if user, ok := publicKey["user"].(map[string]interface{}); ok {
+		handle, err := b64.DecodeString(user["id"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		userHandle = handle
+	}
+	return challenge, userHandle
+}
+
+func (a *testAuthenticator) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": a.origin})
+	return data
+}
+
+func (a *testAuthenticator) authenticatorData(attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	flags := byte(0x01 | 0x04) // UP, UV
+	if attested != nil {
+		flags |= 0x40 // AT
+	}
+	a.signCount++
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+// Réponse à navigator.credentials.create pour les options de /auth/webauthn/register/begin.
+func (a *testAuthenticator) attestation(options map[string]interface{}) map[string]interface{} {
+	a.t.Helper()
+
+	challenge, userHandle := ceremonyOptions(a.t, options)
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(attested),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return map[string]interface{}{
+		"id":    b64.EncodeToString(a.credentialID),
+		"rawId": b64.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64.EncodeToString(a.clientData("webauthn.create", challenge)),
+			"attestationObject": b64.EncodeToString(attestationObject),
+		},
+	}
+}
+
+// Réponse à navigator.credentials.get pour les options de /auth/webauthn/login/begin.
+func (a *testAuthenticator) assertion(options map[string]interface{}) map[string]interface{} {
+	a.t.Helper()
+
+	challenge, _ := ceremonyOptions(a.t, options)
+	clientData := a.clientData("webauthn.get", challenge)
+	authData := a.authenticatorData(nil)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return map[string]interface{}{
+		"id":    b64.EncodeToString(a.credentialID),
+		"rawId": b64.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64.EncodeToString(clientData),
+			"authenticatorData": b64.EncodeToString(authData),
+			"signature":         b64.EncodeToString(signature),
+			"userHandle":        b64.EncodeToString(a.userHandle),
+		},
+	}
+}
+
+// Retourne le cookie de cérémonie posé par la réponse.
+func ceremonyCookie(t *testing.T, resp *http.Response) *http.Cookie {
+	t.Helper()
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == webAuthnCeremonyCookie {
+			return cookie
+		}
+	}
+	t.Fatalf("no %s cookie in the response", webAuthnCeremonyCookie)
+	return nil
+}
+
+func sessionCookie(token string) *http.Cookie {
+	return &http.Cookie{Name: "session_token", Value: token}
+}
+
+// Enregistre une passkey pour la session token.
+func (api *testAPI) registerPasskey(token string, authenticator *testAuthenticator) {
+	api.t.Helper()
+
+	resp, options := api.do("POST", "/auth/webauthn/register/begin", token, nil)
+	if resp.StatusCode != http.StatusOK {
+		api.t.Fatalf("register/begin: expected status 200, got %d (%v)", resp.StatusCode, options)
+	}
+	ceremony := ceremonyCookie(api.t, resp)
+
+	resp, payload := api.send("POST", "/auth/webauthn/register/finish?name=Laptop", authenticator.attestation(options), sessionCookie(token), ceremony)
+	if resp.StatusCode != http.StatusCreated {
+		api.t.Fatalf("register/finish: expected status 201, got %d (%v)", resp.StatusCode, payload)
+	}
+}
+
+// Connexion par passkey ; retourne la réponse de /auth/webauthn/login/finish.
+func (api *testAPI) passkeyLogin(authenticator *testAuthenticator) (*http.Response, map[string]interface{}) {
+	api.t.Helper()
+
+	resp, options := api.do("POST", "/auth/webauthn/login/begin", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		api.t.Fatalf("login/begin: expected status 200, got %d (%v)", resp.StatusCode, options)
+	}
+	return api.send("POST", "/auth/webauthn/login/finish", authenticator.assertion(options), ceremonyCookie(api.t, resp))
+}
+
+// Crée un compte relié uniquement à un fournisseur OAuth et ouvre une session.
+func (api *testAPI) providerUser(provider, email string) (int, string) {
+	api.t.Helper()
+
+	userID, err := api.store.CreateProviderUser(provider, "id-"+email, email, "Provider User", "", true)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	token, err := auth.NewSessionToken()
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	if _, err := api.store.CreateSession(userID, token, false, "test", "127.0.0.1"); err != nil {
+		api.t.Fatal(err)
+	}
+	return userID, token
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("ivan@example.com", "password1")
+	authenticator := newTestAuthenticator(api)
+
+	api.registerPasskey(token, authenticator)
+
+	list := api.expect("GET", "/api/webauthn/credentials", token, nil, http.StatusOK)
+	credentials, _ := list["credentials"].([]interface{})
+	if len(credentials) != 1 || credentials[0].(map[string]interface{})["name"] != "Laptop" {
+		t.Fatalf("expected the registered passkey, got %v", list)
+	}
+
+	resp, login := api.passkeyLogin(authenticator)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("passkey login: expected status 200, got %d (%v)", resp.StatusCode, login)
+	}
+	me := api.expect("GET", "/api/me", login["token"].(string), nil, http.StatusOK)
+	if me["email"] != "ivan@example.com" {
+		t.Fatalf("passkey login opened a session for %v", me["email"])
+	}
+}
+
+func TestPasskeyCeremonyCookie(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("judy@example.com", "password1")
+	otherToken := api.signUp("mallory@example.com", "password1")
+	authenticator := newTestAuthenticator(api)
+
+	resp, options := api.do("POST", "/auth/webauthn/register/begin", token, nil)
+	ceremony := ceremonyCookie(t, resp)
+	if !ceremony.HttpOnly || ceremony.SameSite != http.SameSiteStrictMode || ceremony.Path != "/auth/webauthn" {
+		t.Errorf("unexpected ceremony cookie attributes: %+v", ceremony)
+	}
+	attestation := authenticator.attestation(options)
+
+	// Sans cookie, ou avec la cérémonie d’un autre compte
+	api.expect("POST", "/auth/webauthn/register/finish", token, attestation, http.StatusBadRequest)
+	resp, _ = api.send("POST", "/auth/webauthn/register/finish", attestation, sessionCookie(otherToken), ceremony)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("another account should not finish the ceremony, got %d", resp.StatusCode)
+	}
+
+	// La cérémonie a été consommée par la tentative précédente
+	resp, _ = api.send("POST", "/auth/webauthn/register/finish", attestation, sessionCookie(token), ceremony)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("a ceremony should be usable only once, got %d", resp.StatusCode)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == webAuthnCeremonyCookie && cookie.MaxAge >= 0 {
+			t.Errorf("finish should clear the ceremony cookie, got %+v", cookie)
+		}
+	}
+
+	// Un cookie d’enregistrement ne sert pas à la connexion
+	api.registerPasskey(token, authenticator)
+	resp, options = api.do("POST", "/auth/webauthn/register/begin", token, nil)
+	registration := ceremonyCookie(t, resp)
+	assertion := authenticator.assertion(options)
+	resp, _ = api.send("POST", "/auth/webauthn/login/finish", assertion, registration)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("a registration ceremony should not finish a login, got %d", resp.StatusCode)
+	}
+
+	// Rejeu d’une assertion valide
+	resp, options = api.do("POST", "/auth/webauthn/login/begin", "", nil)
+	login := ceremonyCookie(t, resp)
+	assertion = authenticator.assertion(options)
+	if resp, payload := api.send("POST", "/auth/webauthn/login/finish", assertion, login); resp.StatusCode != http.StatusOK {
+		t.Fatalf("passkey login: expected status 200, got %d (%v)", resp.StatusCode, payload)
+	}
+	if resp, _ := api.send("POST", "/auth/webauthn/login/finish", assertion, login); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("a replayed assertion should be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeletePasskeyKeepsALoginMethod(t *testing.T) {
+	api := newTestAPI(t)
+	userID, token := api.providerUser("github", "ken@example.com")
+	authenticator := newTestAuthenticator(api)
+	api.registerPasskey(token, authenticator)
+
+	// La passkey reste : l’identité GitHub peut être déliée
+	identities := api.expect("GET", "/api/identities", token, nil, http.StatusOK)["identities"].([]interface{})
+	identityID := int(identities[0].(map[string]interface{})["id"].(float64))
+	api.expect("DELETE", fmt.Sprintf("/api/identities/%d", identityID), token, nil, http.StatusOK)
+
+	credentials, err := api.store.ListWebAuthnCredentials(userID)
+	if err != nil || len(credentials) != 1 {
+		t.Fatalf("expected one passkey, got %v, %v", credentials, err)
+	}
+	path := fmt.Sprintf("/api/webauthn/credentials/%d", credentials[0].ID)
+
+	// Dernier moyen de connexion
+	api.expect("DELETE", path, token, nil, http.StatusConflict)
+	if resp, login := api.passkeyLogin(authenticator); resp.StatusCode != http.StatusOK {
+		t.Fatalf("the passkey should still work, got %d (%v)", resp.StatusCode, login)
+	}
+
+	// Un mot de passe permet ensuite de la supprimer
+	api.expect("POST", "/api/me/password", token, SetPasswordRequest{NewPassword: "password1"}, http.StatusOK)
+	api.expect("DELETE", path, token, nil, http.StatusOK)
+	api.expect("DELETE", path, token, nil, http.StatusConflict)
+	if resp, _ := api.passkeyLogin(authenticator); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("a deleted passkey should not log in, got %d", resp.StatusCode)
+	}
+}
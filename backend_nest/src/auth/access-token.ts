@@ -0,0 +1,134 @@
+import { createPublicKey, verify, KeyObject } from 'crypto';
+import express from 'express';
+
+/**
+ * Local verification of the access tokens minted by the auth service.
+ *
+ * The gateway forwards `Authorization: Bearer <jwt>` (EdDSA / Ed25519). The
+ * public keys come from the auth service JWKS and are cached; an unknown `kid`
+ * (key rotation) triggers a reload, at most every 30 seconds.
+ */
+
+export interface AccessTokenClaims {
+  iss: string;
+  sub: string;
+  iat: number;
+  exp: number;
+  email: string;
+  email_verified: boolean;
+  roles?: string[];
+  // Only present on tokens issued to OpenID Connect clients
+  aud?: string;
+}
+
+const JWKS_REFRESH_INTERVAL_MS = 30_000;
+
+let keys = new Map<string, KeyObject>();
+let fetchedAt = 0;
+
+function authServiceUrl(): string {
+  return (
+    process.env.AUTH_SERVICE_URL ||
+    (process.env.NODE_ENV === 'production'
+      ? 'http://auth-service:3060'
+      : 'http://localhost:3060')
+  );
+}
+
+function issuer(): string {
+  return process.env.ACCESS_TOKEN_ISSUER || 'http://localhost:8000';
+}
+
+async function publicKey(kid: string): Promise<KeyObject | undefined> {
+  const cached = keys.get(kid);
+  if (cached || Date.now() - fetchedAt < JWKS_REFRESH_INTERVAL_MS) {
+    return cached;
+  }
+
+  fetchedAt = Date.now();
+  const response = await fetch(`${authServiceUrl()}/.well-known/jwks.json`);
+  if (!response.ok) {
+    return undefined;
+  }
+
+  const jwks = (await response.json()) as { keys?: any[] };
+  const loaded = new Map<string, KeyObject>();
+  for (const jwk of jwks.keys ?? []) {
+    if (jwk.kty !== 'OKP' || jwk.crv !== 'Ed25519') {
+      continue;
+    }
+    loaded.set(jwk.kid, createPublicKey({ key: jwk, format: 'jwk' }));
+  }
+  keys = loaded;
+
+  return keys.get(kid);
+}
+
+export async function verifyAccessToken(
+  token: string,
+): Promise<AccessTokenClaims | null> {
+  const parts = token.split('.');
+  if (parts.length !== 3) {
+    return null;
+  }
+
+  try {
+    const header = JSON.parse(Buffer.from(parts[0], 'base64url').toString());
+    if (header.alg !== 'EdDSA') {
+      return null;
+    }
+
+    const key = await publicKey(header.kid);
+    if (!key) {
+      return null;
+    }
+
+    const valid = verify(
+      null,
+      Buffer.from(`${parts[0]}.${parts[1]}`),
+      key,
+      Buffer.from(parts[2], 'base64url'),
+    );
+    if (!valid) {
+      return null;
+    }
+
+    const claims = JSON.parse(
+      Buffer.from(parts[1], 'base64url').toString(),
+    ) as AccessTokenClaims;
+    if (
+      claims.iss !== issuer() ||
+      !claims.sub ||
+      claims.aud !== undefined ||
+      Date.now() / 1000 >= claims.exp
+    ) {
+      return null;
+    }
+
+    return claims;
+  } catch (error) {
+    console.error('verifyAccessToken - Failed to verify token:', error);
+    return null;
+  }
+}
+
+/**
+ * Returns the user id carried by the bearer access token of the request,
+ * or null when the request has no valid access token.
+ */
+export async function getUserIdFromAccessToken(
+  req: express.Request,
+): Promise<number | null> {
+  const authorization = req.headers.authorization;
+  if (!authorization?.startsWith('Bearer ')) {
+    return null;
+  }
+
+  const claims = await verifyAccessToken(authorization.slice('Bearer '.length));
+  if (!claims) {
+    return null;
+  }
+
+  const userId = parseInt(claims.sub, 10);
+  return isNaN(userId) ? null : userId;
+}
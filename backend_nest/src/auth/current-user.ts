@@ -0,0 +1,96 @@
+import { ForbiddenException, UnauthorizedException } from '@nestjs/common';
+import express from 'express';
+import { getUserIdFromAccessToken } from './access-token';
+
+/**
+ * Identity of the caller, resolved only from credentials the auth service
+ * vouches for: the signed access token forwarded by the gateway (verified
+ * locally), or else the session cookie looked up through `/api/me`.
+ *
+ * A `userId` sent in the body or query string is never trusted; when present
+ * it must match the authenticated user.
+ */
+
+function sessionTokenFromRequest(req: express.Request): string | undefined {
+  return (
+    req.cookies?.session_token ||
+    req.headers.cookie?.split('session_token=')[1]?.split(';')[0]
+  );
+}
+
+async function getUserIdFromSession(
+  req: express.Request,
+): Promise<number | null> {
+  const sessionToken = sessionTokenFromRequest(req);
+  if (!sessionToken) {
+    return null;
+  }
+
+  // Gateway first (Docker), then the auth service directly
+  const gatewayUrl = process.env.GATEWAY_URL || 'http://localhost:8000';
+  const authServiceUrl =
+    process.env.AUTH_SERVICE_URL ||
+    (process.env.NODE_ENV === 'production'
+      ? 'http://auth-service:3060'
+      : 'http://localhost:3060');
+  const headers = { Cookie: `session_token=${sessionToken}` };
+
+  try {
+    let response;
+    try {
+      response = await fetch(`${gatewayUrl}/api/me`, { headers });
+    } catch {
+      response = await fetch(`${authServiceUrl}/api/me`, { headers });
+    }
+    if (!response.ok) {
+      return null;
+    }
+
+    const user = (await response.json()) as { id?: number };
+    return typeof user.id === 'number' && user.id > 0 ? user.id : null;
+  } catch (error) {
+    console.error(
+      'getUserIdFromSession - Failed to reach the auth service:',
+      error,
+    );
+    return null;
+  }
+}
+
+/**
+ * Returns the authenticated user id, or null when the request carries neither
+ * a valid access token nor a live session.
+ */
+export async function getAuthenticatedUserId(
+  req: express.Request,
+): Promise<number | null> {
+  return (
+    (await getUserIdFromAccessToken(req)) ?? (await getUserIdFromSession(req))
+  );
+}
+
+/**
+ * Returns the authenticated user id. Throws 401 without valid credentials and
+ * 403 when a client-supplied user id names another user.
+ */
+export async function requireUserId(
+  req: express.Request,
+  claimedUserId?: unknown,
+): Promise<number> {
+  const userId = await getAuthenticatedUserId(req);
+  if (!userId) {
+    throw new UnauthorizedException('Please log in to continue');
+  }
+
+  const claims = [claimedUserId, req.body?.userId, req.query?.userId];
+  for (const claim of claims) {
+    if (claim === undefined || claim === null || claim === '') {
+      continue;
+    }
+    if (Number(claim) !== userId) {
+      throw new ForbiddenException('You can only act on your own account');
+    }
+  }
+
+  return userId;
+}
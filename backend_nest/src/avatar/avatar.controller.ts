@@ -7,7 +7,6 @@ import {
   Body,
   Param,
   Req,
-  UnauthorizedException,
   HttpCode,
   HttpStatus,
   NotFoundException,
@@ -16,6 +15,7 @@ import express from 'express';
 import { AvatarService } from './avatar.service';
 import { CreateAvatarDto, UpdateAvatarDto } from './dto/avatar.dto';
 import { Avatar } from './interfaces/avatar.interface';
+import { requireUserId } from '../auth/current-user';
 
 @Controller('api/avatars')
 export class AvatarController {
@@ -55,19 +55,13 @@ export class AvatarController {
     @Body() createAvatarDto: CreateAvatarDto,
     @Req() req: express.Request,
   ) {
-    console.log('🍪 Cookies received:', req.cookies);
-    console.log('📋 Headers:', req.headers);
-    
-    const sessionToken = req.cookies?.session_token;
+    // The avatar always belongs to the authenticated user
+    const userId = await requireUserId(req, createAvatarDto.userId);
 
-    if (!sessionToken) {
-      console.log('❌ No session token found in cookies');
-      throw new UnauthorizedException('No session token provided');
-    }
-
-    console.log('✅ Session token found:', sessionToken.substring(0, 10) + '...');
-
-    const avatar = await this.avatarService.saveAvatar(createAvatarDto);
+    const avatar = await this.avatarService.saveAvatar({
+      ...createAvatarDto,
+      userId,
+    });
     return {
       success: true,
       message: 'Avatar saved successfully',
@@ -97,14 +91,10 @@ export class AvatarController {
     message: string;
     data: Avatar;
   }> {
-    const sessionToken = req.cookies?.session_token;
-
-    if (!sessionToken) {
-      throw new UnauthorizedException('No session token provided');
-    }
+    const ownerId = await requireUserId(req, userId);
 
     const avatar = await this.avatarService.updateAvatar(
-      userId,
+      ownerId,
       updateAvatarDto,
     );
     return {
@@ -120,12 +110,8 @@ export class AvatarController {
     @Param('userId') userId: number,
     @Req() req: express.Request,
   ): Promise<void> {
-    const sessionToken = req.cookies?.session_token;
-
-    if (!sessionToken) {
-      throw new UnauthorizedException('No session token provided');
-    }
+    const ownerId = await requireUserId(req, userId);
 
-    await this.avatarService.deleteAvatar(userId);
+    await this.avatarService.deleteAvatar(ownerId);
   }
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
-import { Controller, Get, Req, UnauthorizedException } from '@nestjs/common';
+import { Controller, Get, Req } from '@nestjs/common';
 import { DashboardService } from './dashboard.service';
 import type { Request } from 'express';
+import { requireUserId } from '../auth/current-user';
 
 @Controller('dashboard')
 export class DashboardController {
@@ -8,53 +9,9 @@ export class DashboardController {
 
     @Get('stats')
     async getStats(@Req() req: Request) {
-        // The Gateway forwards the session cookie.
-        // However, the NestJS backend might not have the session middleware to parse user from cookie directly 
-        // if it relies on the Gateway/Auth service for authentication.
+        // Stats of the authenticated user; a ?userId= from the frontend must match it
+        const userId = await requireUserId(req);
 
-        // BUT: The gateway's `/api/me` endpoint returns the user ID.
-        // And for calls proxied to NestJS, usually we pass the User ID in headers or rely on the session cookie 
-        // IF the NestJS app shares the session store or if the gateway injects the user ID.
-
-        // Looking at previous patterns (e.g. contracts controller), it seems we might be missing specific user extraction logic 
-        // unless there's a guard or we trust the cookie.
-
-        // WAIT: The Gateway forwards the cookie. The Auth service manages sessions. 
-        // Does the NestJS service have access to the DB to validate the session? 
-        // Or does it assume if the request gets here it's valid?
-        // Actually, `contracts.controller.ts` doesn't seem to extract user from request in the `getAll` method I saw.
-
-        // Users are authenticated by the Auth service. 
-        // For this implementation, I will rely on a header `X-User-Id` if provided by gateway, 
-        // OR I will parse the cookie and verify it (but NestJS might not have direct access to Auth DB easily without duplicating code).
-
-        // HACK for now: since we are running in a microservice setup, 
-        // usually the Gateway would resolve the user and pass `X-User-Id`.
-        // If not, we might need to query the Auth service /me endpoint or similar.
-
-        // Let's assume for this specific task that the `req.cookies['session_token']` is available.
-        // However, validating it requires DB access to the `sessions` table which is in the same MySQL DB 
-        // but managed by Go.
-
-        // ALTERNATIVE: The frontend calls this endpoint. The Gateway proxies it.
-        // We can assume the frontend will pass the User ID as a query param or header FOR NOW 
-        // given the time constraints, OR we can decode the session if we had shared secrets.
-
-        // BETTER APPROACH: The `request` object from Express might contain the user if we had middleware.
-        // Let's check `contracts.controller.ts` to see how it gets the user.
-        // (I previously viewed it but didn't check for user extraction).
-
-        // Let's try to extract from a custom header 'x-user-id' which we should configure the gateway to send, 
-        // OR just use a query param `?userId=...` from the frontend for simplicity in this "Proof of Concept" phase.
-        // The user said "replace user info page", so it's a specific user's dashboard.
-
-        // I'll add `userId` as a Query param for safety. Frontend will fetch `/api/me` first, get ID, then call dashboard.
-
-        const userId = req.query.userId;
-        if (!userId) {
-            throw new UnauthorizedException('User ID is required');
-        }
-
-        return this.dashboardService.getUserStats(Number(userId));
+        return this.dashboardService.getUserStats(userId);
     }
 }
@@ -12,93 +12,15 @@ import { FriendsService } from './friends.service';
 import { CreateFriendInvitationDto } from './dto/create-friend-invitation.dto';
 import { UpdateFriendInvitationDto } from './dto/update-friend-invitation.dto';
 import express from 'express';
+import { requireUserId } from '../auth/current-user';
 
 @Controller('friends')
 export class FriendsController {
   constructor(private readonly friendsService: FriendsService) {}
 
-  private async getUserIdFromRequest(req: express.Request): Promise<number> {
-    console.log('🔍 FriendsController - getUserIdFromRequest called');
-    console.log('🔍 FriendsController - req.body:', JSON.stringify(req.body));
-    console.log('🔍 FriendsController - req.query:', JSON.stringify(req.query));
-    console.log('🔍 FriendsController - req.cookies:', JSON.stringify(req.cookies));
-    console.log('🔍 FriendsController - req.headers.cookie:', req.headers.cookie);
-    
-    // Try to get from body first (most reliable from frontend)
-    if (req.body?.userId !== undefined && req.body?.userId !== null) {
-      const userId = typeof req.body.userId === 'number' ? req.body.userId : parseInt(String(req.body.userId), 10);
-      if (!isNaN(userId) && userId > 0) {
-        console.log('🔍 FriendsController - Using userId from body:', userId);
-        return userId;
-      } else {
-        console.error('🔍 FriendsController - Invalid userId in body:', req.body.userId);
-      }
-    }
-
-    // Try to get from query params
-    if (req.query?.userId) {
-      const userId = parseInt(req.query.userId as string, 10);
-      if (!isNaN(userId) && userId > 0) {
-        console.log('🔍 FriendsController - Using userId from query:', userId);
-        return userId;
-      }
-    }
-
-    // Try to get from session token via auth service
-    const sessionToken = req.cookies?.session_token || req.headers.cookie?.split('session_token=')[1]?.split(';')[0];
-    
-    console.log('🔍 FriendsController - Request cookies:', req.cookies);
-    console.log('🔍 FriendsController - Request headers.cookie:', req.headers.cookie);
-    console.log('🔍 FriendsController - Extracted session token:', sessionToken ? sessionToken.substring(0, 20) + '...' : 'NOT FOUND');
-    
-    if (sessionToken) {
-      try {
-        // Try using gateway URL first (for Docker), then fallback to direct auth service
-        const gatewayUrl = process.env.GATEWAY_URL || 'http://localhost:8000';
-        const authServiceUrl = process.env.AUTH_SERVICE_URL || 
-          (process.env.NODE_ENV === 'production' ? 'http://auth-service:3060' : 'http://localhost:3060');
-        
-        // Try gateway first (recommended for Docker)
-        let response;
-        try {
-          console.log('🔍 FriendsController - Trying gateway URL:', `${gatewayUrl}/api/me`);
-          response = await fetch(`${gatewayUrl}/api/me`, {
-            headers: {
-              Cookie: `session_token=${sessionToken}`,
-            },
-          });
-        } catch (gatewayError) {
-          console.log('🔍 FriendsController - Gateway failed, trying auth service directly:', authServiceUrl);
-          response = await fetch(`${authServiceUrl}/api/me`, {
-            headers: {
-              Cookie: `session_token=${sessionToken}`,
-            },
-          });
-        }
-        
-        console.log('🔍 FriendsController - Response status:', response.status);
-        
-        if (response.ok) {
-          const user = await response.json();
-          console.log('🔍 FriendsController - User ID found:', user.id);
-          return user.id;
-        } else {
-          const errorText = await response.text();
-          console.error('🔍 FriendsController - Auth service error:', response.status, errorText);
-        }
-      } catch (error) {
-        console.error('🔍 FriendsController - Failed to get user from auth service:', error);
-        if (error instanceof Error) {
-          console.error('🔍 FriendsController - Error message:', error.message);
-          console.error('🔍 FriendsController - Error stack:', error.stack);
-        }
-      }
-    } else {
-      console.error('🔍 FriendsController - No session token found in cookies or headers');
-      console.error('🔍 FriendsController - Full request object keys:', Object.keys(req));
-    }
-
-    throw new BadRequestException('User ID is required. Please ensure you are logged in.');
+  // Authenticated caller; a userId in the body or query must match it
+  private getUserIdFromRequest(req: express.Request): Promise<number> {
+    return requireUserId(req);
   }
 
   @Post('invitations')
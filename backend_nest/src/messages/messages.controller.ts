@@ -11,61 +11,15 @@ import {
 import { MessagesService } from './messages.service';
 import { CreateMessageDto } from './dto/create-message.dto';
 import express from 'express';
+import { requireUserId } from '../auth/current-user';
 
 @Controller('messages')
 export class MessagesController {
   constructor(private readonly messagesService: MessagesService) {}
 
-  private async getUserIdFromRequest(req: express.Request): Promise<number> {
-    // Try to get from query params
-    if (req.query?.userId) {
-      return parseInt(req.query.userId as string, 10);
-    }
-
-    // Try to get from body
-    if (req.body?.userId) {
-      return parseInt(req.body.userId, 10);
-    }
-
-    // Try to get from session token via auth service
-    const sessionToken = req.cookies?.session_token || req.headers.cookie?.split('session_token=')[1]?.split(';')[0];
-    if (sessionToken) {
-      try {
-        // Try using gateway URL first (for Docker), then fallback to direct auth service
-        const gatewayUrl = process.env.GATEWAY_URL || 'http://localhost:8000';
-        const authServiceUrl = process.env.AUTH_SERVICE_URL || 
-          (process.env.NODE_ENV === 'production' ? 'http://auth-service:3060' : 'http://localhost:3060');
-        
-        // Try gateway first (recommended for Docker)
-        let response;
-        try {
-          response = await fetch(`${gatewayUrl}/api/me`, {
-            headers: {
-              Cookie: `session_token=${sessionToken}`,
-            },
-          });
-        } catch (gatewayError) {
-          response = await fetch(`${authServiceUrl}/api/me`, {
-            headers: {
-              Cookie: `session_token=${sessionToken}`,
-            },
-          });
-        }
-        
-        if (response.ok) {
-          const user = await response.json();
-          return user.id;
-        } else {
-          console.error('MessagesController - Auth service error:', response.status);
-        }
-      } catch (error) {
-        console.error('MessagesController - Failed to get user from auth service:', error);
-      }
-    } else {
-      console.error('MessagesController - No session token found');
-    }
-
-    throw new BadRequestException('User ID is required');
+  // Authenticated caller; a userId in the body or query must match it
+  private getUserIdFromRequest(req: express.Request): Promise<number> {
+    return requireUserId(req);
   }
 
   @Post()
@@ -13,6 +13,11 @@ export default function Login(){
   const [verificationCode, setVerificationCode] = useState("");
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState("");
+
+  // Two-factor step: challenge returned by /auth/login or passed by the gateway after an OAuth login
+  const [challengeToken, setChallengeToken] = useState("");
+  const [twoFactorCode, setTwoFactorCode] = useState("");
+  const [recoveryMode, setRecoveryMode] = useState(false);
   
   // Form data
   const [formData, setFormData] = useState({
@@ -39,12 +44,42 @@ export default function Login(){
     setError("");
   };
 
-  const handleGoogleLogin = () => {
-    window.location.href = "http://localhost:8000/auth/google";
+  // OAuth providers enabled on the auth service (Google, GitHub, Microsoft, GitLab, OIDC...)
+  const [providers, setProviders] = useState<{ name: string; display_name: string }[]>([]);
+
+  useEffect(() => {
+    fetch("/auth/providers")
+      .then((response) => (response.ok ? response.json() : { providers: [] }))
+      .then((data) => setProviders(data.providers ?? []))
+      .catch(() => setProviders([]));
+  }, []);
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.search);
+    const challenge = params.get("challenge_token");
+    if (challenge) {
+      setIsSignUp(false);
+      setChallengeToken(challenge);
+      // Keep the token out of the history and of later navigations
+      params.delete("challenge_token");
+      const query = params.toString();
+      window.history.replaceState(null, "", window.location.pathname + (query ? `?${query}` : ""));
+    }
+  }, []);
+
+  const handleProviderLogin = (provider: string) => {
+    window.location.href = `http://localhost:8000/auth/${provider}`;
   };
 
   // Check if user has avatar and redirect accordingly
   const checkAvatarAndRedirect = async (userId: number) => {
+    // "Log in with SmartEther": resume the pending authorization request on the gateway
+    const returnTo = new URLSearchParams(window.location.search).get("return_to");
+    if (returnTo && returnTo.startsWith("/oauth/authorize?")) {
+      window.location.href = "http://localhost:8000" + returnTo;
+      return;
+    }
+
     try {
       const response = await fetch(`/api/avatars/check/${userId}`, {
         credentials: 'include',
@@ -91,7 +126,7 @@ export default function Login(){
 
       if (response.ok) {
         setShowVerification(true);
-        setCountdown(10);
+        setCountdown(60);
         setCanResend(false);
       } else {
         setError(data.error || "Signup failed");
@@ -128,22 +163,12 @@ export default function Login(){
 
       const data = await response.json();
 
-      if (response.ok) {
+      if (response.ok && data.two_factor_required) {
+        // Password accepted, no session yet: ask for the authenticator code
+        setChallengeToken(data.challenge_token);
+      } else if (response.ok) {
         console.log('Login successful, checking avatar status...');
-        
-        // Get user info first
-        const userResponse = await fetch('/api/me', {
-          credentials: 'include',
-        });
-
-        if (userResponse.ok) {
-          const userData = await userResponse.json();
-          // Check avatar and redirect
-          await checkAvatarAndRedirect(userData.id);
-        } else {
-          // Fallback to avatar page
-          router.push('/avatar');
-        }
+        await redirectAfterLogin();
       } else {
         setError(data.error || "Login failed");
       }
@@ -155,6 +180,74 @@ export default function Login(){
     }
   };
 
+  const redirectAfterLogin = async () => {
+    // Get user info first
+    const userResponse = await fetch('/api/me', {
+      credentials: 'include',
+    });
+
+    if (userResponse.ok) {
+      const userData = await userResponse.json();
+      // Check avatar and redirect
+      await checkAvatarAndRedirect(userData.id);
+    } else {
+      // Fallback to avatar page
+      router.push('/avatar');
+    }
+  };
+
+  const closeTwoFactor = () => {
+    setChallengeToken("");
+    setTwoFactorCode("");
+    setRecoveryMode(false);
+    setError("");
+  };
+
+  const handleTwoFactor = async () => {
+    const code = twoFactorCode.trim();
+    if (!code || (!recoveryMode && code.length !== 6)) {
+      setError(recoveryMode ? "Please enter a recovery code" : "Please enter a valid 6-digit code");
+      return;
+    }
+
+    setLoading(true);
+    setError("");
+
+    try {
+      const response = await fetch("/auth/login/2fa", {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+        },
+        credentials: "include",
+        body: JSON.stringify(
+          recoveryMode
+            ? { challenge_token: challengeToken, recovery_code: code }
+            : { challenge_token: challengeToken, code }
+        ),
+      });
+
+      const data = await response.json();
+
+      if (response.ok) {
+        closeTwoFactor();
+        await redirectAfterLogin();
+      } else if (response.status === 401 && data.error === "Invalid or expired login challenge") {
+        // Challenge expired or used up: start the login again
+        closeTwoFactor();
+        setError("Your sign-in attempt has expired. Please log in again.");
+      } else {
+        setTwoFactorCode("");
+        setError(data.error || "Invalid code");
+      }
+    } catch (err) {
+      console.error("Two-factor error:", err);
+      setError("Network error. Please try again.");
+    } finally {
+      setLoading(false);
+    }
+  };
+
   const handleVerifyCode = async () => {
     if (!verificationCode || verificationCode.length !== 6) {
       setError("Please enter a valid 6-digit code");
@@ -213,11 +306,15 @@ export default function Login(){
       const data = await response.json();
 
       if (response.ok) {
-        setCountdown(10);
+        setCountdown(60);
         setCanResend(false);
         setVerificationCode("");
         alert("Verification code resent!");
       } else {
+        if (data.retry_after) {
+          setCountdown(data.retry_after);
+          setCanResend(false);
+        }
         setError(data.error || "Failed to resend code");
       }
     } catch (err) {
@@ -238,6 +335,12 @@ export default function Login(){
     }
   };
 
+  const handleTwoFactorSubmit = (e: React.KeyboardEvent) => {
+    if (e.key === 'Enter') {
+      handleTwoFactor();
+    }
+  };
+
   const handleVerifySubmit = (e: React.KeyboardEvent) => {
     if (e.key === 'Enter' && countdown === 0) {
       handleVerifyCode();
@@ -358,6 +461,7 @@ export default function Login(){
                   </div>
                 </div>
 
+                {providers.length > 0 && (
                 <div className="mt-6">
                   <div className="relative mb-4">
                     <div className="absolute inset-0 flex items-center">
@@ -367,25 +471,109 @@ export default function Login(){
                       <span className="px-2 bg-indigo-900/50 text-purple-300">Or continue with</span>
                     </div>
                   </div>
-                  <button
-                    onClick={handleGoogleLogin}
-                    className="w-full flex items-center justify-center gap-3 bg-white/10 hover:bg-white/20 border border-purple-500/30 text-white py-3 rounded-lg font-semibold transition-all backdrop-blur-sm"
-                  >
-                    <Image
-                      src="https://www.svgrepo.com/show/353817/google-icon.svg"
-                      alt="Google logo"
-                      width={24}
-                      height={24}
-                    />
-                    Login with Google
-                  </button>
+                  <div className="space-y-3">
+                    {providers.map((provider) => (
+                      <button
+                        key={provider.name}
+                        onClick={() => handleProviderLogin(provider.name)}
+                        className="w-full flex items-center justify-center gap-3 bg-white/10 hover:bg-white/20 border border-purple-500/30 text-white py-3 rounded-lg font-semibold transition-all backdrop-blur-sm"
+                      >
+                        {provider.name === "google" && (
+                          <Image
+                            src="https://www.svgrepo.com/show/353817/google-icon.svg"
+                            alt="Google logo"
+                            width={24}
+                            height={24}
+                          />
+                        )}
+                        Login with {provider.display_name}
+                      </button>
+                    ))}
+                  </div>
                 </div>
+                )}
               </div>
             </div>
           </div>
         </div>
       </div>
 
+      {/* Two-Factor Modal */}
+      {challengeToken && (
+        <div className="fixed inset-0 bg-black/70 backdrop-blur-sm flex items-center justify-center z-50 p-4">
+          <div className="bg-gradient-to-br from-indigo-900 to-purple-900 rounded-2xl p-8 max-w-md w-full border border-purple-500/30 shadow-2xl">
+            <div className="flex items-center justify-between mb-4">
+              <h2 className="text-2xl font-bold text-white">
+                Two-Factor Authentication
+              </h2>
+              <button
+                onClick={closeTwoFactor}
+                className="text-purple-300 hover:text-white transition-colors"
+              >
+                <svg className="w-6 h-6" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                  <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M6 18L18 6M6 6l12 12" />
+                </svg>
+              </button>
+            </div>
+            <p className="text-purple-200 mb-6">
+              {recoveryMode
+                ? "Enter one of the recovery codes you saved when enabling two-factor authentication."
+                : "Enter the 6-digit code from your authenticator app."}
+            </p>
+
+            {error && (
+              <div className="mb-4 p-3 bg-red-900/50 border border-red-500/50 text-red-200 rounded-lg text-sm">
+                {error}
+              </div>
+            )}
+
+            <div className="space-y-4">
+              <div>
+                <label className="block text-sm font-medium text-purple-200 mb-2">
+                  {recoveryMode ? "Recovery Code" : "Authentication Code"}
+                </label>
+                <input
+                  type="text"
+                  autoComplete="one-time-code"
+                  autoFocus
+                  value={twoFactorCode}
+                  onChange={(e) => {
+                    const value = recoveryMode ? e.target.value : e.target.value.replace(/\D/g, '');
+                    setTwoFactorCode(value);
+                    setError("");
+                  }}
+                  onKeyPress={handleTwoFactorSubmit}
+                  placeholder={recoveryMode ? "xxxxx-xxxxx" : "Enter 6-digit code"}
+                  maxLength={recoveryMode ? 32 : 6}
+                  className="w-full px-4 py-3 bg-indigo-800/50 border border-purple-500/30 rounded-lg focus:outline-none focus:ring-2 focus:ring-purple-500 text-white text-center text-2xl tracking-widest"
+                />
+              </div>
+
+              <button
+                onClick={handleTwoFactor}
+                disabled={loading}
+                className="w-full bg-gradient-to-r from-purple-600 to-indigo-600 hover:from-purple-700 hover:to-indigo-700 text-white py-3 rounded-lg font-semibold transition-all disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                {loading ? "Verifying..." : "Verify"}
+              </button>
+            </div>
+
+            <div className="mt-4 text-center">
+              <button
+                onClick={() => {
+                  setRecoveryMode(!recoveryMode);
+                  setTwoFactorCode("");
+                  setError("");
+                }}
+                className="text-purple-300 hover:text-white text-sm font-medium transition-colors"
+              >
+                {recoveryMode ? "Use your authenticator app instead" : "Lost your device? Use a recovery code"}
+              </button>
+            </div>
+          </div>
+        </div>
+      )}
+
       {/* Verification Modal */}
       {showVerification && (
         <div className="fixed inset-0 bg-black/70 backdrop-blur-sm flex items-center justify-center z-50 p-4">
@@ -29,7 +29,8 @@ export default function ReportContract() {
             });
 
             if (!response.ok) {
-                throw new Error('Failed to submit report');
+                const data = await response.json().catch(() => ({}));
+                throw new Error(data.error || 'Failed to submit report. Please try again.');
             }
 
             setMessage({ type: 'success', text: 'Report submitted successfully. We will review it shortly.' });
@@ -37,7 +38,7 @@ export default function ReportContract() {
             setDescription('');
             setTimeout(() => router.back(), 3000);
         } catch (error) {
-            setMessage({ type: 'error', text: 'Failed to submit report. Please try again.' });
+            setMessage({ type: 'error', text: error instanceof Error ? error.message : 'Failed to submit report. Please try again.' });
         } finally {
             setIsSubmitting(false);
         }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimum delay between two JWKS downloads triggered by an unknown key id
+const jwksRefreshInterval = 30 * time.Second
+
+var (
+	errInvalidAccessToken = errors.New("invalid access token")
+	errUnknownSigningKey  = errors.New("unknown signing key")
+)
+
+// Claims carried by the access tokens minted by the auth service.
+type accessTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	IssuedAt      int64    `json:"iat"`
+	ExpiresAt     int64    `json:"exp"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles,omitempty"`
+	// Session the token was issued to, see sessionID
+	SessionID string `json:"sid,omitempty"`
+	// Set only on tokens issued to OpenID Connect clients, which must not reach the backends
+	Audience string `json:"aud,omitempty"`
+}
+
+// Verifies access tokens locally with the public keys published by the auth service.
+// Keys are cached and reloaded when a token references an unknown key id (key rotation).
+type accessTokenVerifier struct {
+	jwksURL string
+	issuer  string
+
+	mu        sync.Mutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+func newAccessTokenVerifier(authServiceURL, issuer string) *accessTokenVerifier {
+	return &accessTokenVerifier{
+		jwksURL: authServiceURL + "/.well-known/jwks.json",
+		issuer:  issuer,
+		keys:    map[string]ed25519.PublicKey{},
+	}
+}
+
+func (v *accessTokenVerifier) publicKey(kid string) (ed25519.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(v.fetchedAt) < jwksRefreshInterval {
+		return nil, errUnknownSigningKey
+	}
+
+	v.fetchedAt = time.Now()
+	resp, err := http.Get(v.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			Kid string `json:"kid"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]ed25519.PublicKey{}
+	for _, k := range jwks.Keys {
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Kty != "OKP" || k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[k.Kid] = ed25519.PublicKey(x)
+	}
+	v.keys = keys
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errUnknownSigningKey
+}
+
+// Checks the EdDSA signature, issuer and expiry of a first-party access token.
+func (v *accessTokenVerifier) Verify(token string) (*accessTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidAccessToken
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errInvalidAccessToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil || header.Alg != "EdDSA" {
+		return nil, errInvalidAccessToken
+	}
+
+	key, err := v.publicKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, errInvalidAccessToken
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidAccessToken
+	}
+	var claims accessTokenClaims
+	if err := json.Unmarshal(rawPayload, &claims); err != nil {
+		return nil, errInvalidAccessToken
+	}
+	if claims.Issuer != v.issuer || claims.Subject == "" || claims.Audience != "" || time.Now().Unix() >= claims.ExpiresAt {
+		return nil, errInvalidAccessToken
+	}
+
+	return &claims, nil
+}
+
+// sid claim of the tokens issued to a session: SHA-256 of the session token with a "sid:"
+// prefix (auth.SessionID in the auth service), so the value is not the sessions table key.
+func sessionID(sessionToken string) string {
+	sum := sha256.Sum256([]byte("sid:" + sessionToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Returns a valid access token for the request: the access_token cookie if it still verifies
+// and was issued to the current session cookie, otherwise a fresh token obtained from the auth
+// service with the session cookie (refresh). A token left over from another session (logout,
+// login as someone else in the same browser) is never reused.
+// Without a session cookie (logged out) no token is forwarded, even if one is still valid.
+func (v *accessTokenVerifier) tokenForRequest(w http.ResponseWriter, r *http.Request, authServiceURL string) string {
+	session, err := r.Cookie("session_token")
+	if err != nil {
+		return ""
+	}
+	sid := sessionID(session.Value)
+
+	if cookie, err := r.Cookie("access_token"); err == nil {
+		if claims, err := v.Verify(cookie.Value); err == nil && claims.SessionID == sid {
+			return cookie.Value
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, authServiceURL+"/auth/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.AddCookie(session)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to refresh access token: %v", err)
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return ""
+	}
+	claims, err := v.Verify(result.AccessToken)
+	if err != nil {
+		log.Printf("Auth service returned an invalid access token: %v", err)
+		return ""
+	}
+	if claims.SessionID != sid {
+		log.Printf("Auth service returned an access token for another session")
+		return ""
+	}
+
+	http.SetCookie(w, accessTokenCookie(result.AccessToken, result.ExpiresIn))
+	return result.AccessToken
+}
+
+// Short-lived cookie caching the access token between two backend calls (maxAge -1 deletes it).
+func accessTokenCookie(token string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     "access_token",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Forwards the caller identity to the backends as "Authorization: Bearer <jwt>",
+// which they verify locally with the JWKS. Any Authorization header sent by the client is dropped.
+func withAccessToken(verifier *accessTokenVerifier, authServiceURL string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Authorization")
+		if token := verifier.tokenForRequest(w, r, authServiceURL); token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIssuer = "http://localhost:8000"
+
+// Minimal auth service: JWKS, /auth/token for known sessions and /auth/login.
+type fakeAuthService struct {
+	t         *testing.T
+	key       ed25519.PrivateKey
+	sessions  map[string]string // session token -> user id
+	refreshes int
+	server    *httptest.Server
+}
+
+func newFakeAuthService(t *testing.T, sessions map[string]string) *fakeAuthService {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &fakeAuthService{t: t, key: key, sessions: sessions}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": "test-key",
+				"x":   base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			}},
+		})
+	})
+	mux.HandleFunc("/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		a.refreshes++
+		cookie, err := r.Cookie("session_token")
+		if err != nil || a.sessions[cookie.Value] == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": a.sign(accessTokenClaims{Subject: a.sessions[cookie.Value], SessionID: sessionID(cookie.Value)}),
+			"expires_in":   600,
+		})
+	})
+	mux.HandleFunc("/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      "se_sess_new",
+			"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+		})
+	})
+
+	a.server = httptest.NewServer(mux)
+	t.Cleanup(a.server.Close)
+	return a
+}
+
+func (a *fakeAuthService) sign(claims accessTokenClaims) string {
+	claims.Issuer = testIssuer
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = time.Now().Add(10 * time.Minute).Unix()
+
+	header, _ := json.Marshal(map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": "test-key"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(a.key, []byte(signingInput)))
+}
+
+// Calls a backend route through withAccessToken and returns the subject it received.
+func backendCall(t *testing.T, verifier *accessTokenVerifier, authURL string, cookies ...*http.Cookie) (string, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	var subject string
+	handler := withAccessToken(verifier, authURL, func(w http.ResponseWriter, r *http.Request) {
+		bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if claims, err := verifier.Verify(bearer); err == nil {
+			subject = claims.Subject
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/contracts", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return subject, rec
+}
+
+func responseCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestAccessTokenIsBoundToSession(t *testing.T) {
+	alice := &http.Cookie{Name: "session_token", Value: "se_sess_alice"}
+	bob := &http.Cookie{Name: "session_token", Value: "se_sess_bob"}
+	auth := newFakeAuthService(t, map[string]string{alice.Value: "1", bob.Value: "2"})
+	verifier := newAccessTokenVerifier(auth.server.URL, testIssuer)
+
+	subject, rec := backendCall(t, verifier, auth.server.URL, alice)
+	cached := responseCookie(rec, "access_token")
+	if subject != "1" || cached == nil || auth.refreshes != 1 {
+		t.Fatalf("expected a fresh token for alice, got subject %q, cookie %v, %d refreshes", subject, cached, auth.refreshes)
+	}
+
+	// Same session: the cached token is reused
+	if subject, _ := backendCall(t, verifier, auth.server.URL, alice, cached); subject != "1" || auth.refreshes != 1 {
+		t.Fatalf("expected the cached token to be reused, got subject %q, %d refreshes", subject, auth.refreshes)
+	}
+
+	// Another account logged in the same browser: alice's token must not be forwarded
+	subject, rec = backendCall(t, verifier, auth.server.URL, bob, cached)
+	if subject != "2" || auth.refreshes != 2 {
+		t.Fatalf("expected a fresh token for bob, got subject %q, %d refreshes", subject, auth.refreshes)
+	}
+	if cookie := responseCookie(rec, "access_token"); cookie == nil || cookie.Value == cached.Value {
+		t.Fatalf("the access_token cookie should be replaced, got %v", cookie)
+	}
+
+	// Tokens without sid are not reused either
+	unbound := &http.Cookie{Name: "access_token", Value: auth.sign(accessTokenClaims{Subject: "1"})}
+	if subject, _ := backendCall(t, verifier, auth.server.URL, bob, unbound); subject != "2" || auth.refreshes != 3 {
+		t.Fatalf("expected a fresh token for bob, got subject %q, %d refreshes", subject, auth.refreshes)
+	}
+}
+
+func TestLoginClearsCachedAccessToken(t *testing.T) {
+	auth := newFakeAuthService(t, nil)
+	handler := createLoginProxyHandler(auth.server.URL + "/auth/login")
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{}`))
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "previous-user-token"})
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if session := responseCookie(rec, "session_token"); session == nil || session.Value != "se_sess_new" {
+		t.Fatalf("expected the new session cookie, got %v", session)
+	}
+	if cookie := responseCookie(rec, "access_token"); cookie == nil || cookie.MaxAge >= 0 {
+		t.Fatalf("login should delete the access_token cookie, got %v", cookie)
+	}
+}
+
+// Same vector as auth.SessionID in the auth service: both must stay identical.
+func TestSessionID(t *testing.T) {
+	if got := sessionID("se_sess_test"); got != "JeCYtWuls21OWJ4zcRVCLIbW4Hc9e3A2GWhrdjD_fAw" {
+		t.Fatalf("unexpected session id %q", got)
+	}
+}
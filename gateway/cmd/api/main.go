@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"io"
 	"log"
 	"net/http"
@@ -10,6 +11,10 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"strconv"
+	"time"
+
+	"gateway/internal/config"
 )
 
 // Helper function for min
@@ -20,18 +25,30 @@ func min(a, b int) int {
 	return b
 }
 
-// Get environment variable with fallback
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Secure flag of the cookies set by the gateway (COOKIE_SECURE)
+var secureCookies bool
+
+// Session cookie whose expiry follows the session returned by the auth service.
+// If the auth service did not send an expiry, it falls back to a browser-session cookie.
+func sessionCookie(token string, expiresAt time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     "session_token",
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if !expiresAt.IsZero() {
+		cookie.Expires = expiresAt
+		cookie.MaxAge = int(time.Until(expiresAt).Seconds())
 	}
-	return fallback
+	return cookie
 }
 
 // CORS middleware for the official frontend
-func corsMiddleware(next http.Handler) http.Handler {
+func corsMiddleware(frontendURL string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		frontendURL := getEnv("FRONTEND_URL", "http://localhost:3000")
 		w.Header().Set("Access-Control-Allow-Origin", frontendURL)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Cookie, Stripe-Signature")
@@ -46,6 +63,80 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// Login proxy: forwards the credentials to the auth service and turns the returned
+// session token into the session_token cookie. Responses without a token (e.g. a
+// two-factor challenge) are passed through unchanged.
+func createLoginProxyHandler(loginURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		target := loginURL
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+
+		req, err := http.NewRequest(http.MethodPost, target, r.Body)
+		if err != nil {
+			http.Error(w, "Failed to create request", http.StatusInternalServerError)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		// Device info for the auth service session list
+		req.Header.Set("User-Agent", r.UserAgent())
+		req.Header.Set("X-Forwarded-For", r.RemoteAddr)
+		// Passkey login needs the ceremony cookie set by /auth/webauthn/login/begin
+		if cookies := r.Header.Get("Cookie"); cookies != "" {
+			req.Header.Set("Cookie", cookies)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			http.Error(w, "Failed to reach auth server", http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			http.Error(w, "Failed to read response", http.StatusInternalServerError)
+			return
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			var result map[string]interface{}
+			if err := json.Unmarshal(body, &result); err == nil {
+				if token, ok := result["token"].(string); ok {
+					var expiresAt time.Time
+					if expires, ok := result["expires_at"].(string); ok {
+						expiresAt, _ = time.Parse(time.RFC3339, expires)
+					}
+					http.SetCookie(w, sessionCookie(token, expiresAt))
+					// The cached access token belongs to the previous session, if any
+					http.SetCookie(w, accessTokenCookie("", -1))
+					delete(result, "token")
+					body, _ = json.Marshal(result)
+				}
+			}
+		}
+
+		// Cookies set by the auth service itself (e.g. clearing the passkey ceremony)
+		for _, cookie := range resp.Header.Values("Set-Cookie") {
+			w.Header().Add("Set-Cookie", cookie)
+		}
+		// Brute-force protection (429)
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		w.Write(body)
+	}
+}
+
 // Reverse-proxy helper (keeps body/headers intact; removes backend CORS headers)
 func createProxyHandler(proxy *httputil.ReverseProxy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -154,11 +245,27 @@ func createBackendProxyHandler(backendServiceURL string) http.HandlerFunc {
 }
 
 func main() {
+	configFile := flag.String("config", "", "YAML or TOML configuration file (default: CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted, then exit")
+	flag.Parse()
+
+	// The gateway does not start with an invalid configuration
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		cfg.Dump(os.Stdout)
+		return
+	}
+
 	// Service URLs
-	authServiceURL := getEnv("AUTH_SERVICE_URL", "http://localhost:3060")
-	backendServiceURL := getEnv("BACKEND_SERVICE_URL", "http://localhost:5000")
-	frontendURL := getEnv("FRONTEND_URL", "http://localhost:3000")
-	port := getEnv("PORT", "8000")
+	authServiceURL := cfg.AuthServiceURL
+	backendServiceURL := cfg.BackendServiceURL
+	frontendURL := cfg.FrontendURL
+	accessTokenIssuer := cfg.AccessTokenIssuer
+	port := strconv.Itoa(cfg.Port)
+	secureCookies = cfg.Cookies.Secure
 
 	log.Printf("🚀 Starting Gateway on port %s", port)
 	log.Printf("📡 Auth Service: %s", authServiceURL)
@@ -179,24 +286,35 @@ func main() {
 		return nil
 	}
 
+	// Access tokens are verified locally with the auth service JWKS
+	accessTokens := newAccessTokenVerifier(authServiceURL, accessTokenIssuer)
+	// Backend routes receive the caller identity as a signed access token
+	backend := func(next http.HandlerFunc) http.HandlerFunc {
+		return withAccessToken(accessTokens, authServiceURL, next)
+	}
+
 	mux := http.NewServeMux()
 
-	// --- GOOGLE AUTH ---
-	mux.HandleFunc("/auth/google", createProxyHandler(authProxy))
-	mux.HandleFunc("/auth/google/callback", createProxyHandler(authProxy))
+	// --- OAUTH PROVIDERS ---
+	// /auth/providers, /auth/{provider} and /auth/{provider}/callback: the provider list
+	// comes from the auth service configuration, more specific /auth/* routes below win
+	mux.HandleFunc("/auth/", createProxyHandler(authProxy))
 
 	// OAuth callback: set cookie and redirect to frontend
 	mux.HandleFunc("/auth/callback", func(w http.ResponseWriter, r *http.Request) {
+		// Two-factor enabled: no session yet, the frontend asks for the TOTP code
+		if challenge := r.URL.Query().Get("challenge_token"); challenge != "" {
+			http.Redirect(w, r, frontendURL+"/login?challenge_token="+url.QueryEscape(challenge), http.StatusFound)
+			return
+		}
+
 		token := r.URL.Query().Get("token")
-		http.SetCookie(w, &http.Cookie{
-			Name:     "session_token",
-			Value:    token,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   false,
-			SameSite: http.SameSiteLaxMode,
-			MaxAge:   86400 * 7,
-		})
+		var expiresAt time.Time
+		if expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64); err == nil {
+			expiresAt = time.Unix(expires, 0)
+		}
+		http.SetCookie(w, sessionCookie(token, expiresAt))
+		http.SetCookie(w, accessTokenCookie("", -1))
 		http.Redirect(w, r, frontendURL+"/avatar", http.StatusFound)
 	})
 
@@ -204,51 +322,37 @@ func main() {
 	mux.HandleFunc("/auth/register", createProxyHandler(authProxy))
 	mux.HandleFunc("/auth/verify", createProxyHandler(authProxy))
 	mux.HandleFunc("/auth/resend-code", createProxyHandler(authProxy))
-	mux.HandleFunc("/auth/logout", createProxyHandler(authProxy))
+	mux.HandleFunc("/auth/forgot-password", createProxyHandler(authProxy))
+	mux.HandleFunc("/auth/reset-password", createProxyHandler(authProxy))
+	logoutProxy := createProxyHandler(authProxy)
+	mux.HandleFunc("/auth/logout", func(w http.ResponseWriter, r *http.Request) {
+		// The cached access token must not outlive the session
+		http.SetCookie(w, accessTokenCookie("", -1))
+		logoutProxy(w, r)
+	})
 
 	// Login: proxy and set cookie on success
-	mux.HandleFunc("/auth/login", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+	mux.HandleFunc("/auth/login", createLoginProxyHandler(authServiceURL+"/auth/login"))
+	// Second login step when two-factor authentication is enabled
+	mux.HandleFunc("/auth/login/2fa", createLoginProxyHandler(authServiceURL+"/auth/login/2fa"))
 
-		resp, err := http.Post(authServiceURL+"/auth/login", "application/json", r.Body)
-		if err != nil {
-			http.Error(w, "Failed to reach auth server", http.StatusInternalServerError)
-			return
-		}
-		defer resp.Body.Close()
+	// --- PASSKEYS (WEBAUTHN) ---
+	mux.HandleFunc("/auth/webauthn/", createProxyHandler(authProxy))
+	// Passkey login creates a session exactly like /auth/login
+	mux.HandleFunc("/auth/webauthn/login/finish", createLoginProxyHandler(authServiceURL+"/auth/webauthn/login/finish"))
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			http.Error(w, "Failed to read response", http.StatusInternalServerError)
-			return
-		}
+	// --- SIGN-IN WITH ETHEREUM ---
+	mux.HandleFunc("/auth/siwe/nonce", createProxyHandler(authProxy))
+	// Logs in (sets the cookie) or links the wallet when a session cookie is present
+	mux.HandleFunc("/auth/siwe/verify", createLoginProxyHandler(authServiceURL+"/auth/siwe/verify"))
 
-		if resp.StatusCode == http.StatusOK {
-			var result map[string]interface{}
-			if err := json.Unmarshal(body, &result); err == nil {
-				if token, ok := result["token"].(string); ok {
-					http.SetCookie(w, &http.Cookie{
-						Name:     "session_token",
-						Value:    token,
-						Path:     "/",
-						HttpOnly: true,
-						Secure:   false,
-						SameSite: http.SameSiteLaxMode,
-						MaxAge:   86400 * 7,
-					})
-					delete(result, "token")
-					body, _ = json.Marshal(result)
-				}
-			}
-		}
+	// --- SIGNED ACCESS TOKENS (JWT) ---
+	mux.HandleFunc("/.well-known/jwks.json", createProxyHandler(authProxy))
+	mux.HandleFunc("/auth/token", createProxyHandler(authProxy))
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(resp.StatusCode)
-		w.Write(body)
-	})
+	// --- OPENID CONNECT PROVIDER ("Log in with SmartEther") ---
+	mux.HandleFunc("/.well-known/openid-configuration", createProxyHandler(authProxy))
+	mux.HandleFunc("/oauth/", createProxyHandler(authProxy))
 
 	// --- ME endpoint (reads session cookie, asks Auth) ---
 	mux.HandleFunc("/api/me", func(w http.ResponseWriter, r *http.Request) {
@@ -259,8 +363,6 @@ func main() {
 			return
 		}
 
-		log.Printf("Found session cookie: %s", cookie.Value[:min(10, len(cookie.Value))]+"...")
-
 		req, err := http.NewRequest("GET", authServiceURL+"/api/me", nil)
 		if err != nil {
 			http.Error(w, "Failed to create request", http.StatusInternalServerError)
@@ -296,8 +398,8 @@ func main() {
 	// --- SUBSCRIPTIONS SERVICE (NestJS) ---
 	// Important: register before generic /api/ so they are not captured by Auth
 	mux.HandleFunc("/api/subscriptions/webhook", createBackendProxyHandler(backendServiceURL))
-	mux.HandleFunc("/api/subscriptions/checkout", createBackendProxyHandler(backendServiceURL))
-	mux.HandleFunc("/api/subscriptions/", createBackendProxyHandler(backendServiceURL))
+	mux.HandleFunc("/api/subscriptions/checkout", backend(createBackendProxyHandler(backendServiceURL)))
+	mux.HandleFunc("/api/subscriptions/", backend(createBackendProxyHandler(backendServiceURL)))
 
 	// --- AVATAR SERVICE (NestJS) ---
 	avatarHandler := func(w http.ResponseWriter, r *http.Request) {
@@ -310,8 +412,6 @@ func main() {
 			return
 		}
 
-		log.Printf("Found session cookie in gateway: %s...", cookie.Value[:min(10, len(cookie.Value))])
-
 		backendURL := backendServiceURL + r.URL.Path
 		req, err := http.NewRequest(r.Method, backendURL, r.Body)
 		if err != nil {
@@ -352,8 +452,8 @@ func main() {
 		w.WriteHeader(resp.StatusCode)
 		w.Write(body)
 	}
-	mux.HandleFunc("/api/avatars", avatarHandler)
-	mux.HandleFunc("/api/avatars/", avatarHandler)
+	mux.HandleFunc("/api/avatars", backend(avatarHandler))
+	mux.HandleFunc("/api/avatars/", backend(avatarHandler))
 
 	// --- CONTRACTS SERVICE (NestJS) ---
 	// Handle /api/contracts/* and transform to /contracts/* for backend
@@ -447,27 +547,27 @@ func main() {
 		w.Write(body)
 	}
 	
-	mux.HandleFunc("/api/contracts", contractsHandler)
-	mux.HandleFunc("/api/contracts/", contractsHandler)
-	mux.HandleFunc("/contracts", createBackendProxyHandler(backendServiceURL))
-	mux.HandleFunc("/contracts/", createBackendProxyHandler(backendServiceURL))
+	mux.HandleFunc("/api/contracts", backend(contractsHandler))
+	mux.HandleFunc("/api/contracts/", backend(contractsHandler))
+	mux.HandleFunc("/contracts", backend(createBackendProxyHandler(backendServiceURL)))
+	mux.HandleFunc("/contracts/", backend(createBackendProxyHandler(backendServiceURL)))
 
 	// --- GENERIC /api/ to Auth (keep after specific service routes) ---
 	mux.HandleFunc("/api/", createProxyHandler(authProxy))
 
 	// --- FRIENDS SERVICE (NestJS) ---
-	mux.HandleFunc("/friends", createBackendProxyHandler(backendServiceURL))
-	mux.HandleFunc("/friends/", createBackendProxyHandler(backendServiceURL))
+	mux.HandleFunc("/friends", backend(createBackendProxyHandler(backendServiceURL)))
+	mux.HandleFunc("/friends/", backend(createBackendProxyHandler(backendServiceURL)))
 
 	// --- MESSAGES SERVICE (NestJS) ---
-	mux.HandleFunc("/messages", createBackendProxyHandler(backendServiceURL))
-	mux.HandleFunc("/messages/", createBackendProxyHandler(backendServiceURL))
+	mux.HandleFunc("/messages", backend(createBackendProxyHandler(backendServiceURL)))
+	mux.HandleFunc("/messages/", backend(createBackendProxyHandler(backendServiceURL)))
 
 	// --- DASHBOARD SERVICE (NestJS) ---
-	mux.HandleFunc("/api/dashboard", createBackendProxyHandler(backendServiceURL))
-	mux.HandleFunc("/api/dashboard/", createBackendProxyHandler(backendServiceURL))
+	mux.HandleFunc("/api/dashboard", backend(createBackendProxyHandler(backendServiceURL)))
+	mux.HandleFunc("/api/dashboard/", backend(createBackendProxyHandler(backendServiceURL)))
 
-	handler := corsMiddleware(mux)
+	handler := corsMiddleware(frontendURL, mux)
 
 	log.Printf("✅ Gateway running on :%s", port)
 	log.Printf("📋 Routes configured:")
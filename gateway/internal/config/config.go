@@ -0,0 +1,125 @@
+/*
+Package config holds the gateway configuration in a typed struct, loaded once at startup.
+
+Precedence (lowest to highest):
+
+	defaults (default tags)
+	optional YAML or TOML file (-config or CONFIG_FILE)
+	environment variables (and .env in development)
+	NAME_FILE: value read from a file (Docker/Kubernetes secrets)
+
+The configuration is validated before the gateway starts and every problem is reported at once.
+Dump prints the effective configuration with secrets redacted (-print-config).
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	// production enables the strict checks (Secure cookies)
+	Env  string `env:"APP_ENV" key:"env" default:"development"`
+	Port int    `env:"PORT" key:"port" default:"8000"`
+
+	// Official frontend: CORS origin and redirect target after OAuth login
+	FrontendURL       string `env:"FRONTEND_URL" key:"frontend_url" default:"http://localhost:3000"`
+	AuthServiceURL    string `env:"AUTH_SERVICE_URL" key:"auth_service_url" default:"http://localhost:3060"`
+	BackendServiceURL string `env:"BACKEND_SERVICE_URL" key:"backend_service_url" default:"http://localhost:5000"`
+	// Expected iss of the access tokens, must match the auth service
+	AccessTokenIssuer string `env:"ACCESS_TOKEN_ISSUER" key:"access_token_issuer" default:"http://localhost:8000"`
+
+	Cookies Cookies `env:"" key:"cookies"`
+}
+
+type Cookies struct {
+	// HTTPS-only session_token and access_token cookies; required in production
+	Secure bool `env:"COOKIE_SECURE" key:"secure"`
+}
+
+/*
+Load reads the configuration (see the package comment) and validates it.
+path may be empty, CONFIG_FILE is then used if set.
+*/
+func Load(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookup lookupFunc) (*Config, error) {
+	cfg := &Config{}
+	v := reflect.ValueOf(cfg).Elem()
+	setDefaults(v)
+
+	var errs []error
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config file: %v", err)
+		}
+		errs = append(errs, applyFile(v, values, "")...)
+	}
+	errs = append(errs, applyEnv(v, "", lookup)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	cfg.FrontendURL = strings.TrimSuffix(cfg.FrontendURL, "/")
+	cfg.AuthServiceURL = strings.TrimSuffix(cfg.AuthServiceURL, "/")
+	cfg.BackendServiceURL = strings.TrimSuffix(cfg.BackendServiceURL, "/")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) Production() bool {
+	return c.Env == "production"
+}
+
+// Validate checks the configuration; messages name the environment variables.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Port > 0 && c.Port < 65536, "PORT must be between 1 and 65535, got %d", c.Port)
+	check(isHTTPURL(c.FrontendURL), "FRONTEND_URL must be an absolute http(s) URL, got %q", c.FrontendURL)
+	check(isHTTPURL(c.AuthServiceURL), "AUTH_SERVICE_URL must be an absolute http(s) URL, got %q", c.AuthServiceURL)
+	check(isHTTPURL(c.BackendServiceURL), "BACKEND_SERVICE_URL must be an absolute http(s) URL, got %q", c.BackendServiceURL)
+	check(c.AccessTokenIssuer != "", "ACCESS_TOKEN_ISSUER must be set")
+	if c.Production() {
+		check(c.Cookies.Secure, "COOKIE_SECURE must be true when APP_ENV=production")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Dump writes the effective configuration as NAME=value lines, secrets redacted.
+func (c *Config) Dump(w io.Writer) {
+	dump(w, reflect.ValueOf(c).Elem(), "")
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
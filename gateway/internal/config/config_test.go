@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func env(values map[string]string) lookupFunc {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "gateway.toml")
+	content := "port = 9000\nauth_service_url = \"http://auth:3060/\"\n\n[cookies]\nsecure = true\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	issuerFile := filepath.Join(dir, "issuer")
+	if err := os.WriteFile(issuerFile, []byte("https://api.example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := load(file, env(map[string]string{
+		"PORT":                     "9100",
+		"ACCESS_TOKEN_ISSUER_FILE": issuerFile,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9100 || cfg.AuthServiceURL != "http://auth:3060" || !cfg.Cookies.Secure {
+		t.Errorf("unexpected configuration: %+v", cfg)
+	}
+	if cfg.AccessTokenIssuer != "https://api.example.com" {
+		t.Errorf("ACCESS_TOKEN_ISSUER_FILE should be used, got %q", cfg.AccessTokenIssuer)
+	}
+
+	var out bytes.Buffer
+	cfg.Dump(&out)
+	if !strings.Contains(out.String(), "COOKIE_SECURE=true\n") {
+		t.Errorf("unexpected dump:\n%s", out.String())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := load("", env(map[string]string{
+		"APP_ENV":          "production",
+		"AUTH_SERVICE_URL": "auth-service:3060",
+		"PORT":             "0",
+	}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"PORT must be between 1 and 65535",
+		"AUTH_SERVICE_URL must be an absolute http(s) URL",
+		"COOKIE_SECURE must be true when APP_ENV=production",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should mention %q, got:\n%v", want, err)
+		}
+	}
+}
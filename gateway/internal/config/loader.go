@@ -0,0 +1,258 @@
+/*
+Generic loader for a configuration struct, driven by field tags:
+
+	env:"NAME"        environment variable; on a nested struct, prefix of its fields
+	key:"name"        key in the YAML/TOML file
+	default:"value"   default value (same format as the variable)
+	secret:"true"     redacted by Dump
+
+Supported types: string, bool, int, time.Duration and []string (comma separated).
+
+Every variable can also be read from a file: NAME_FILE=/run/secrets/name
+(trailing whitespace removed), but not both at once.
+
+Same rules as auth/internal/config, without the named lists the gateway does not need.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Reads an environment variable; replaced in tests.
+type lookupFunc func(name string) (string, bool)
+
+func setDefaults(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		switch {
+		case isSection(f.Type):
+			setDefaults(fv)
+		case f.Tag.Get("default") != "":
+			if err := setValue(fv, f.Tag.Get("default")); err != nil {
+				panic(fmt.Sprintf("config: invalid default for %s: %v", f.Name, err))
+			}
+		}
+	}
+}
+
+// Reads a YAML (.yaml, .yml) or TOML (.toml) file as a table.
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q (expected .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return values, nil
+}
+
+// Applies the file values; an unknown key is an error (most likely a typo).
+func applyFile(v reflect.Value, values map[string]interface{}, path string) []error {
+	fields := map[string]int{}
+	for i := 0; i < v.NumField(); i++ {
+		if key := v.Type().Field(i).Tag.Get("key"); key != "" {
+			fields[key] = i
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		name := path + key
+		i, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file: unknown key %q", name))
+			continue
+		}
+		f, fv, value := v.Type().Field(i), v.Field(i), values[key]
+
+		if isSection(f.Type) {
+			table, ok := value.(map[string]interface{})
+			if !ok {
+				errs = append(errs, fmt.Errorf("config file: %s must be a table", name))
+				continue
+			}
+			errs = append(errs, applyFile(fv, table, name+".")...)
+			continue
+		}
+		if err := setFileValue(fv, value); err != nil {
+			errs = append(errs, fmt.Errorf("config file: %s: %v", name, err))
+		}
+	}
+	return errs
+}
+
+/*
+Applies the environment variables (and NAME_FILE), which take precedence over the file.
+An empty variable is ignored, as if it was not set.
+*/
+func applyEnv(v reflect.Value, prefix string, lookup lookupFunc) []error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		env, ok := f.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if isSection(f.Type) {
+			errs = append(errs, applyEnv(fv, prefix+env, lookup)...)
+			continue
+		}
+
+		value, err := envValue(prefix+env, lookup)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		if err := setValue(fv, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %v", prefix+env, value, err))
+		}
+	}
+	return errs
+}
+
+// Value of name, or content of the file named by name_FILE.
+func envValue(name string, lookup lookupFunc) (string, error) {
+	value, _ := lookup(name)
+	path, _ := lookup(name + "_FILE")
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("%s and %s_FILE are both set", name, name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %v", name, err)
+	}
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
+
+func setValue(fv reflect.Value, value string) error {
+	switch {
+	case fv.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+	case fv.Kind() == reflect.String:
+		fv.SetString(value)
+	case fv.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		fv.SetBool(b)
+	case fv.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		fv.SetInt(int64(n))
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		fv.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// File value: a YAML/TOML list, or a scalar in the environment variable format.
+func setFileValue(fv reflect.Value, value interface{}) error {
+	if items, ok := value.([]interface{}); ok && fv.Kind() == reflect.Slice {
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			list = append(list, fmt.Sprint(item))
+		}
+		fv.Set(reflect.ValueOf(list))
+		return nil
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}, []map[string]interface{}:
+		return fmt.Errorf("expected a single value")
+	}
+	return setValue(fv, fmt.Sprint(value))
+}
+
+// Writes the effective configuration as NAME=value lines, secrets redacted.
+func dump(w io.Writer, v reflect.Value, prefix string) {
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		env, ok := f.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if isSection(f.Type) {
+			dump(w, fv, prefix+env)
+			continue
+		}
+
+		value := formatValue(fv)
+		if f.Tag.Get("secret") == "true" && value != "" {
+			value = redacted
+		}
+		fmt.Fprintf(w, "%s=%s\n", prefix+env, value)
+	}
+}
+
+func formatValue(fv reflect.Value) string {
+	switch {
+	case fv.Type() == durationType:
+		return time.Duration(fv.Int()).String()
+	case fv.Kind() == reflect.Slice:
+		return strings.Join(fv.Interface().([]string), ",")
+	}
+	return fmt.Sprint(fv.Interface())
+}
+
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}